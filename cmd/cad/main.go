@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/ca"
+	pgrpc "github.com/loshz/platform/internal/grpc"
+	"github.com/loshz/platform/internal/service"
+)
+
+// caConfig is the config required to run the CA. Defaults and validation are
+// provided by the registered config schema.
+type caConfig struct {
+	Cert            string            `plat:"ca.cert"`
+	Key             string            `plat:"ca.key"`
+	CertTTL         time.Duration     `plat:"ca.cert.ttl"`
+	TrustDomain     string            `plat:"ca.trust.domain"`
+	BootstrapTokens map[string]string `plat:"ca.bootstrap.tokens"`
+}
+
+func main() {
+	s := service.New("cad")
+
+	// Load the config file and flags before any other config.
+	s.LoadConfigSources(os.Args[1:])
+
+	// The CA serves a cert issued by itself, so only config is loaded before
+	// startup rather than credentials.
+	s.LoadGrpcServerConfig()
+	var conf caConfig
+	s.Config().MustBind(&conf)
+
+	// Run the service.
+	s.Run(func(ctx context.Context, s *service.Service) error {
+		return run(ctx, s, conf)
+	})
+}
+
+func run(ctx context.Context, s *service.Service, conf caConfig) error {
+	// Load the CA cert/key used to sign certs.
+	authority, err := ca.Load(conf.Cert, conf.Key, conf.TrustDomain, conf.CertTTL)
+	if err != nil {
+		return err
+	}
+
+	// Create a certificate server and renew its own cert in the background.
+	tokens := ca.NewTokens(conf.BootstrapTokens)
+	cs, err := NewCertificateServer(authority, tokens, s.ID())
+	if err != nil {
+		return fmt.Errorf("error issuing ca server certificate: %w", err)
+	}
+	s.Go("ca.renewal", cs.StartRenewal)
+
+	// Create a gRPC server with default options, replacing the default
+	// credentials, and register the service.
+	opts := append(s.GrpcServerOptions(), grpc.Creds(credentials.NewTLS(cs.TLSConfig())))
+	grpcSrv := pgrpc.NewServer(opts)
+	grpcSrv.RegisterService(&apiv1.CertificateService_ServiceDesc, cs)
+
+	// Serve gRPC once the service has run.
+	s.ServeGRPC(grpcSrv)
+
+	return nil
+}
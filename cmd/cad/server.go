@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/authz"
+	"github.com/loshz/platform/internal/ca"
+)
+
+// Errors returned when a certificate request can't be authenticated.
+var (
+	ErrInvalidToken     = status.Error(codes.PermissionDenied, "error: invalid bootstrap token")
+	ErrInstanceMismatch = status.Error(codes.PermissionDenied, "error: certificates can only be renewed by the instance they were issued to")
+)
+
+type CertificateServer struct {
+	apiv1.UnimplementedCertificateServiceServer
+
+	authority *ca.Authority
+	tokens    *ca.Tokens
+
+	// UUID of the CA instance and the cert it serves, which is issued by itself.
+	id   string
+	cert atomic.Pointer[tls.Certificate]
+}
+
+func NewCertificateServer(authority *ca.Authority, tokens *ca.Tokens, id string) (*CertificateServer, error) {
+	cs := &CertificateServer{
+		authority: authority,
+		tokens:    tokens,
+		id:        id,
+	}
+
+	if err := cs.renew(); err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+// TLSConfig returns a server TLS config that serves the CA's own cert. Client
+// certs are verified if given, but not required, so instances without a cert
+// can bootstrap using a token.
+func (cs *CertificateServer) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return cs.cert.Load(), nil
+		},
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  cs.authority.CertPool(),
+		MinVersion: tls.VersionTLS13,
+	}
+}
+
+// StartRenewal periodically renews the CA's own cert before it expires, until
+// the context is cancelled. Failed renewals are retried every minute, and an
+// error is returned if the cert expires without being renewed.
+func (cs *CertificateServer) StartRenewal(ctx context.Context) error {
+	for {
+		// Renew after 2/3 of the cert's lifetime, retrying failures every minute.
+		leaf := cs.cert.Load().Leaf
+		renewIn := time.Until(leaf.NotBefore.Add(leaf.NotAfter.Sub(leaf.NotBefore) * 2 / 3))
+		if renewIn < time.Minute {
+			renewIn = time.Minute
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(renewIn):
+			if err := cs.renew(); err != nil {
+				if time.Now().After(leaf.NotAfter) {
+					return fmt.Errorf("error renewing expired ca server certificate: %w", err)
+				}
+				log.Error().Err(err).Msg("error renewing ca server certificate")
+				continue
+			}
+			log.Info().Time("expiry", cs.cert.Load().Leaf.NotAfter).Msg("ca server certificate renewed")
+		}
+	}
+}
+
+// renew issues a new cert for the CA instance.
+func (cs *CertificateServer) renew() error {
+	key, data, err := ca.NewCSR(cs.id)
+	if err != nil {
+		return err
+	}
+
+	csr, err := ca.ParseCSR(data)
+	if err != nil {
+		return err
+	}
+
+	cert, err := cs.authority.Issue(csr)
+	if err != nil {
+		return err
+	}
+
+	cs.cert.Store(&tls.Certificate{
+		Certificate: [][]byte{cert.Raw},
+		PrivateKey:  key,
+		Leaf:        cert,
+	})
+
+	return nil
+}
+
+// IssueCertificate signs a certificate for the service instance named by the
+// CSR. Callers must either present a valid bootstrap token for the instance's
+// service, or a platform issued cert for the same instance.
+func (cs *CertificateServer) IssueCertificate(ctx context.Context, req *apiv1.IssueCertificateRequest) (*apiv1.IssueCertificateResponse, error) {
+	csr, err := ca.ParseCSR(req.GetCsr())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	id, err := ca.CSRIdentity(csr)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := cs.authenticate(ctx, id, req.GetToken()); err != nil {
+		log.Warn().Ctx(ctx).Err(err).Bool("audit", true).Str("identity", id.String()).Msg("certificate request denied")
+		return nil, err
+	}
+
+	cert, err := cs.authority.Issue(csr)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("identity", id.String()).Msg("error issuing certificate")
+		return nil, status.Error(codes.Internal, "error: failed to issue certificate")
+	}
+
+	log.Info().Ctx(ctx).
+		Bool("audit", true).
+		Str("identity", id.String()).
+		Str("serial", cert.SerialNumber.Text(16)).
+		Time("expiry", cert.NotAfter).
+		Msg("certificate issued")
+
+	return &apiv1.IssueCertificateResponse{
+		Certificate: ca.EncodeCert(cert.Raw),
+		Ca:          cs.authority.CertPEM(),
+		Expiry:      cert.NotAfter.Unix(),
+	}, nil
+}
+
+// authenticate checks that the caller may request a cert for an identity.
+func (cs *CertificateServer) authenticate(ctx context.Context, id authz.Identity, token string) error {
+	if token != "" {
+		if !cs.tokens.Redeem(token, id.Service) {
+			return ErrInvalidToken
+		}
+		return nil
+	}
+
+	// Without a token, the caller must be renewing its own cert.
+	if peer := authz.IdentityFromContext(ctx); peer.Instance == "" || peer.Instance != id.Instance {
+		return ErrInstanceMismatch
+	}
+
+	return nil
+}
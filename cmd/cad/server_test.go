@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/ca"
+	"github.com/loshz/platform/internal/uuid"
+)
+
+// testServer creates a certificate server with a new CA and bootstrap tokens.
+func testServer(t *testing.T, tokens map[string]string) *CertificateServer {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "ca.crt.pem"), filepath.Join(dir, "ca.key.pem")
+	require.NoError(t, os.WriteFile(certPath, ca.EncodeCert(der), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	authority, err := ca.Load(certPath, keyPath, ca.DefaultTrustDomain, time.Hour)
+	require.NoError(t, err)
+
+	cs, err := NewCertificateServer(authority, ca.NewTokens(tokens), uuid.New("cad").String())
+	require.NoError(t, err)
+
+	return cs
+}
+
+// peerContext returns a context with a peer that presented a verified cert.
+func peerContext(cert *x509.Certificate) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert}},
+		}},
+	})
+}
+
+// newRequest creates a certificate request for an instance.
+func newRequest(t *testing.T, instance, token string) *apiv1.IssueCertificateRequest {
+	t.Helper()
+
+	_, csr, err := ca.NewCSR(instance)
+	require.NoError(t, err)
+
+	return &apiv1.IssueCertificateRequest{Csr: csr, Token: token}
+}
+
+func TestIssueCertificate(t *testing.T) {
+	server := testServer(t, map[string]string{"token": "eventd"})
+	instance := uuid.New("eventd").String()
+
+	var issued *x509.Certificate
+
+	t.Run("TestInvalidCSR", func(t *testing.T) {
+		_, err := server.IssueCertificate(context.Background(), &apiv1.IssueCertificateRequest{Csr: []byte("invalid"), Token: "token"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("TestNoToken", func(t *testing.T) {
+		_, err := server.IssueCertificate(context.Background(), newRequest(t, instance, ""))
+		assert.Equal(t, ErrInstanceMismatch, err)
+	})
+
+	t.Run("TestToken", func(t *testing.T) {
+		res, err := server.IssueCertificate(context.Background(), newRequest(t, instance, "token"))
+		require.NoError(t, err)
+
+		block, _ := pem.Decode(res.GetCertificate())
+		issued, err = x509.ParseCertificate(block.Bytes)
+		require.NoError(t, err)
+		assert.Equal(t, instance, issued.Subject.CommonName)
+		assert.Equal(t, issued.NotAfter.Unix(), res.GetExpiry())
+		assert.Equal(t, server.authority.CertPEM(), res.GetCa())
+	})
+
+	t.Run("TestTokenReused", func(t *testing.T) {
+		// Assert restarted instances can reuse their service's token.
+		_, err := server.IssueCertificate(context.Background(), newRequest(t, uuid.New("eventd").String(), "token"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("TestRenew", func(t *testing.T) {
+		// Assert instances can renew their own cert.
+		_, err := server.IssueCertificate(peerContext(issued), newRequest(t, instance, ""))
+		assert.NoError(t, err)
+	})
+
+	t.Run("TestRenewOtherInstance", func(t *testing.T) {
+		_, err := server.IssueCertificate(peerContext(issued), newRequest(t, uuid.New("eventd").String(), ""))
+		assert.Equal(t, ErrInstanceMismatch, err)
+	})
+
+	t.Run("TestTokenOtherService", func(t *testing.T) {
+		_, err := server.IssueCertificate(context.Background(), newRequest(t, uuid.New("trafficd").String(), "token"))
+		assert.Equal(t, ErrInvalidToken, err)
+
+		// Assert the token is revoked once presented by another service.
+		_, err = server.IssueCertificate(context.Background(), newRequest(t, uuid.New("eventd").String(), "token"))
+		assert.Equal(t, ErrInvalidToken, err)
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	pgrpc "github.com/loshz/platform/internal/grpc"
+)
+
+// event represents the output of a single sent event.
+type event struct {
+	Addr     string `json:"addr"`
+	Hostname string `json:"hostname"`
+	UUID     string `json:"uuid"`
+}
+
+func eventsSend(ctx context.Context, cli *CLI, args []string) error {
+	hostname, _ := os.Hostname()
+
+	fs := flag.NewFlagSet("events send", flag.ContinueOnError)
+	addr := fs.String("addr", "", "eventd grpc address (default: first instance found via discovery)")
+	fs.StringVar(&hostname, "hostname", hostname, "event hostname")
+	count := fs.Int("count", 1, "number of events to send")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	// Find an eventd instance if an address wasn't given.
+	if *addr == "" {
+		ds, err := cli.discovery(ctx)
+		if err != nil {
+			return err
+		}
+
+		svcs, err := cli.lookup(ctx, ds, "eventd")
+		if err != nil {
+			return err
+		}
+		if len(svcs) == 0 {
+			return errors.New("no eventd instances found via discovery")
+		}
+
+		*addr = fmt.Sprintf("%s:%d", svcs[0].Address, svcs[0].GRPCPort)
+	} else if err := cli.loadCredentials(); err != nil {
+		return err
+	}
+
+	creds, err := cli.clientCreds(*addr)
+	if err != nil {
+		return err
+	}
+
+	conn, err := pgrpc.Dial(ctx, *addr, creds, cli.policy(), cli.dialOptions()...)
+	if err != nil {
+		return fmt.Errorf("error dialing eventd: %w", err)
+	}
+	defer conn.Close()
+	client := apiv1.NewEventServiceClient(conn)
+
+	var events []event
+	t := table{header: []string{"ADDR", "HOSTNAME", "UUID"}}
+	for i := 0; i < *count; i++ {
+		res, err := client.Event(ctx, &apiv1.EventRequest{Hostname: hostname})
+		if err != nil {
+			return fmt.Errorf("error sending event: %w", err)
+		}
+
+		e := event{*addr, hostname, res.GetUuid()}
+		events = append(events, e)
+		t.rows = append(t.rows, []string{e.Addr, e.Hostname, e.UUID})
+	}
+
+	return cli.print(events, t)
+}
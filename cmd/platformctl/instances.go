@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// metricsPrefix is the prefix of all platform metrics.
+const metricsPrefix = "platform_"
+
+// instance represents flags used to target an individual service instance.
+type instance struct {
+	addr string
+	uuid string
+	tls  bool
+}
+
+func instanceFlags(fs *flag.FlagSet) *instance {
+	i := new(instance)
+	fs.StringVar(&i.addr, "addr", "", "instance http address, e.g. localhost:8001")
+	fs.StringVar(&i.uuid, "uuid", "", "instance uuid, resolved via discovery")
+	fs.BoolVar(&i.tls, "tls", false, "call instances over https, e.g. if served with http.server.tls=tls or mtls")
+
+	return i
+}
+
+// url returns the base URL of an instance's http server, looking up its address
+// via discovery if required. Addresses without a scheme use https if -tls is set.
+func (i *instance) url(ctx context.Context, cli *CLI) (string, error) {
+	addr := i.addr
+
+	switch {
+	case addr == "" && i.uuid == "":
+		return "", fmt.Errorf("one of -addr or -uuid is required: %w", errUsage)
+	case addr == "":
+		ds, err := cli.discovery(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		svcs, err := cli.lookup(ctx, ds, i.uuid)
+		if err != nil {
+			return "", err
+		}
+
+		for _, svc := range svcs {
+			if svc.UUID == i.uuid {
+				addr = fmt.Sprintf("%s:%d", svc.Address, svc.HTTPPort)
+			}
+		}
+
+		if addr == "" {
+			return "", fmt.Errorf("service '%s' not found via discovery", i.uuid)
+		}
+	}
+
+	if !strings.Contains(addr, "://") {
+		scheme := "http://"
+		if i.tls {
+			scheme = "https://"
+		}
+		addr = scheme + addr
+	}
+
+	return strings.TrimSuffix(addr, "/"), nil
+}
+
+// get makes a GET request to an instance, returning the response if the status
+// is OK.
+func (cli *CLI) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := cli.httpClient(url)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("unexpected response status from %s: %s", url, res.Status)
+	}
+
+	return res, nil
+}
+
+// httpClient returns the client used to call an instance URL. HTTPS servers are
+// called with the same mTLS client credentials used by platform services.
+func (cli *CLI) httpClient(url string) (*http.Client, error) {
+	if !strings.HasPrefix(url, "https://") {
+		return http.DefaultClient, nil
+	}
+
+	if err := cli.loadCredentials(); err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := cli.creds.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, ForceAttemptHTTP2: true},
+	}, nil
+}
+
+func health(ctx context.Context, cli *CLI, args []string) error {
+	fs := flag.NewFlagSet("health", flag.ContinueOnError)
+	target := instanceFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	url, err := target.url(ctx, cli)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cli.timeout)
+	defer cancel()
+
+	res, err := cli.get(ctx, url+"/health")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var out struct {
+		Service string `json:"service"`
+		Status  string `json:"status"`
+		Leader  bool   `json:"leader"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return fmt.Errorf("error decoding health response: %w", err)
+	}
+
+	return cli.print(out, table{
+		header: []string{"SERVICE", "STATUS", "LEADER"},
+		rows:   [][]string{{out.Service, out.Status, strconv.FormatBool(out.Leader)}},
+	})
+}
+
+// metric represents the output of a single summarized metric series.
+type metric struct {
+	Name   string            `json:"name"`
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+	Count  uint64            `json:"count,omitempty"`
+}
+
+func metricsSummary(ctx context.Context, cli *CLI, args []string) error {
+	fs := flag.NewFlagSet("metrics", flag.ContinueOnError)
+	target := instanceFlags(fs)
+	all := fs.Bool("all", false, "include non-platform metrics")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	url, err := target.url(ctx, cli)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cli.timeout)
+	defer cancel()
+
+	res, err := cli.get(ctx, url+"/metrics")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(res.Body)
+	if err != nil {
+		return fmt.Errorf("error parsing metrics: %w", err)
+	}
+
+	metrics := summarizeMetrics(families, *all)
+
+	t := table{header: []string{"NAME", "LABELS", "VALUE"}}
+	for _, m := range metrics {
+		labels := make([]string, 0, len(m.Labels))
+		for k, v := range m.Labels {
+			labels = append(labels, fmt.Sprintf("%s=%s", k, v))
+		}
+		sort.Strings(labels)
+
+		value := strconv.FormatFloat(m.Value, 'g', -1, 64)
+		if m.Type == dto.MetricType_HISTOGRAM.String() || m.Type == dto.MetricType_SUMMARY.String() {
+			value = fmt.Sprintf("count=%d sum=%s", m.Count, value)
+		}
+
+		t.rows = append(t.rows, []string{m.Name, strings.Join(labels, ","), value})
+	}
+
+	return cli.print(metrics, t)
+}
+
+// summarizeMetrics flattens metric families into individual series sorted by name.
+// Histograms and summaries are reported by their sample count and sum.
+func summarizeMetrics(families map[string]*dto.MetricFamily, all bool) []metric {
+	var metrics []metric
+	for name, mf := range families {
+		if !all && !strings.HasPrefix(name, metricsPrefix) {
+			continue
+		}
+
+		for _, m := range mf.GetMetric() {
+			out := metric{
+				Name:   name,
+				Type:   mf.GetType().String(),
+				Labels: make(map[string]string),
+			}
+			for _, lp := range m.GetLabel() {
+				out.Labels[lp.GetName()] = lp.GetValue()
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				out.Value = m.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				out.Value = m.GetGauge().GetValue()
+			case dto.MetricType_UNTYPED:
+				out.Value = m.GetUntyped().GetValue()
+			case dto.MetricType_HISTOGRAM:
+				out.Value = m.GetHistogram().GetSampleSum()
+				out.Count = m.GetHistogram().GetSampleCount()
+			case dto.MetricType_SUMMARY:
+				out.Value = m.GetSummary().GetSampleSum()
+				out.Count = m.GetSummary().GetSampleCount()
+			}
+
+			// JSON cannot encode NaN or Inf values.
+			if math.IsNaN(out.Value) || math.IsInf(out.Value, 0) {
+				out.Value = 0
+			}
+
+			metrics = append(metrics, out)
+		}
+	}
+
+	sort.SliceStable(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+
+	return metrics
+}
+
+func logs(ctx context.Context, cli *CLI, args []string) error {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	target := instanceFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	url, err := target.url(ctx, cli)
+	if err != nil {
+		return err
+	}
+
+	// Log streams are long lived, so only the context bounds the request.
+	res, err := cli.get(ctx, url+"/admin/logs")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		if err := cli.printLog(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil && ctx.Err() == nil && err != io.EOF {
+		return fmt.Errorf("error reading logs: %w", err)
+	}
+
+	return nil
+}
+
+// printLog writes a single JSON log line, formatting it as a table row unless
+// JSON output is requested.
+func (cli *CLI) printLog(line []byte) error {
+	if cli.format == formatJSON {
+		_, err := fmt.Fprintf(cli.out, "%s\n", line)
+		return err
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(line, &entry); err != nil {
+		_, err := fmt.Fprintf(cli.out, "%s\n", line)
+		return err
+	}
+
+	// Print well known fields first, followed by any others.
+	known := []string{"time", "level", "service", "message"}
+	cols := make([]string, 0, len(entry))
+	for _, k := range known {
+		cols = append(cols, fmt.Sprint(entry[k]))
+		delete(entry, k)
+	}
+
+	keys := make([]string, 0, len(entry))
+	for k := range entry {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cols = append(cols, fmt.Sprintf("%s=%v", k, entry[k]))
+	}
+
+	_, err := fmt.Fprintln(cli.out, strings.Join(cols, " "))
+	return err
+}
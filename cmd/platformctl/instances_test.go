@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMetrics = `# TYPE go_goroutines gauge
+go_goroutines 10
+# TYPE platform_grpc_requests_total counter
+platform_grpc_requests_total{code="OK",method="/test"} 3
+# TYPE platform_grpc_request_duration_seconds histogram
+platform_grpc_request_duration_seconds_bucket{code="OK",method="/test",le="+Inf"} 3
+platform_grpc_request_duration_seconds_sum{code="OK",method="/test"} 0.5
+platform_grpc_request_duration_seconds_count{code="OK",method="/test"} 3
+`
+
+func TestSummarizeMetrics(t *testing.T) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(testMetrics))
+	require.NoError(t, err)
+
+	// Assert only platform metrics are returned by default.
+	metrics := summarizeMetrics(families, false)
+	require.Len(t, metrics, 2)
+
+	assert.Equal(t, "platform_grpc_request_duration_seconds", metrics[0].Name)
+	assert.Equal(t, 0.5, metrics[0].Value)
+	assert.Equal(t, uint64(3), metrics[0].Count)
+
+	assert.Equal(t, "platform_grpc_requests_total", metrics[1].Name)
+	assert.Equal(t, 3.0, metrics[1].Value)
+	assert.Equal(t, map[string]string{"code": "OK", "method": "/test"}, metrics[1].Labels)
+
+	// Assert all metrics are returned if requested.
+	metrics = summarizeMetrics(families, true)
+	assert.Len(t, metrics, 3)
+}
+
+func TestInstanceURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance instance
+		exp      string
+	}{
+		{"TestHTTP", instance{addr: "localhost:8001"}, "http://localhost:8001"},
+		{"TestTLS", instance{addr: "localhost:8001", tls: true}, "https://localhost:8001"},
+		{"TestScheme", instance{addr: "http://localhost:8001/", tls: true}, "http://localhost:8001"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			url, err := tc.instance.url(context.Background(), nil)
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, url)
+		})
+	}
+
+	_, err := new(instance).url(context.Background(), nil)
+	assert.ErrorIs(t, err, errUsage)
+}
+
+func TestRun(t *testing.T) {
+	t.Run("TestUnknownCommand", func(t *testing.T) {
+		err := run(context.Background(), []string{"unknown"}, new(bytes.Buffer))
+		assert.ErrorIs(t, err, errUsage)
+	})
+
+	t.Run("TestUnknownFormat", func(t *testing.T) {
+		err := run(context.Background(), []string{"-o", "yaml", "version"}, new(bytes.Buffer))
+		assert.ErrorContains(t, err, "unknown output format")
+	})
+
+	t.Run("TestVersion", func(t *testing.T) {
+		out := new(bytes.Buffer)
+		err := run(context.Background(), []string{"version"}, out)
+		assert.NoError(t, err)
+		assert.Equal(t, "platformctl dev\n", out.String())
+	})
+}
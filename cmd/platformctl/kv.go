@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+)
+
+// keyValue represents the output of a single key.
+type keyValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Revision int64  `json:"revision"`
+}
+
+// keyEvent represents a change to a watched key.
+type keyEvent struct {
+	Event string   `json:"event"`
+	KV    keyValue `json:"kv"`
+}
+
+var keyHeader = []string{"KEY", "VALUE", "REVISION"}
+
+func newKeyValue(kv *apiv1.KeyValue) keyValue {
+	return keyValue{
+		Key:      kv.GetKey(),
+		Value:    kv.GetValue(),
+		Revision: kv.GetRevision(),
+	}
+}
+
+func (kv keyValue) row() []string {
+	return []string{kv.Key, kv.Value, strconv.FormatInt(kv.Revision, 10)}
+}
+
+// kvClient returns a client for the KV store provided by the discovery service.
+func (cli *CLI) kvClient(ctx context.Context) (apiv1.KVServiceClient, error) {
+	ds, err := cli.discovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ds.KV(), nil
+}
+
+func kvGet(ctx context.Context, cli *CLI, args []string) error {
+	fs := flag.NewFlagSet("kv get", flag.ContinueOnError)
+	key := fs.String("key", "", "key (required)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if *key == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	kv, err := cli.kvClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := kv.Get(ctx, &apiv1.GetKeyRequest{Key: *key})
+	if err != nil {
+		return fmt.Errorf("error getting key: %w", err)
+	}
+
+	out := newKeyValue(res.GetKv())
+	return cli.print(out, table{header: keyHeader, rows: [][]string{out.row()}})
+}
+
+func kvPut(ctx context.Context, cli *CLI, args []string) error {
+	fs := flag.NewFlagSet("kv put", flag.ContinueOnError)
+	key := fs.String("key", "", "key (required)")
+	value := fs.String("value", "", "value")
+	revision := fs.Int64("revision", -1, "only put if the key's revision matches, or 0 if it must not exist")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if *key == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	kv, err := cli.kvClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := &apiv1.PutKeyRequest{Key: *key, Value: *value}
+	if *revision >= 0 {
+		req.Compare, req.Revision = true, *revision
+	}
+
+	res, err := kv.Put(ctx, req)
+	if err != nil {
+		return fmt.Errorf("error putting key: %w", err)
+	}
+
+	out := newKeyValue(res.GetKv())
+	return cli.print(out, table{header: keyHeader, rows: [][]string{out.row()}})
+}
+
+func kvDelete(ctx context.Context, cli *CLI, args []string) error {
+	fs := flag.NewFlagSet("kv delete", flag.ContinueOnError)
+	key := fs.String("key", "", "key (required)")
+	revision := fs.Int64("revision", -1, "only delete if the key's revision matches")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if *key == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	kv, err := cli.kvClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := &apiv1.DeleteKeyRequest{Key: *key}
+	if *revision >= 0 {
+		req.Compare, req.Revision = true, *revision
+	}
+
+	res, err := kv.Delete(ctx, req)
+	if err != nil {
+		return fmt.Errorf("error deleting key: %w", err)
+	}
+
+	out := newKeyValue(res.GetKv())
+	return cli.print(out, table{header: keyHeader, rows: [][]string{out.row()}})
+}
+
+func kvList(ctx context.Context, cli *CLI, args []string) error {
+	fs := flag.NewFlagSet("kv list", flag.ContinueOnError)
+	prefix := fs.String("prefix", "", "key prefix")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	kv, err := cli.kvClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := kv.List(ctx, &apiv1.ListKeysRequest{Prefix: *prefix})
+	if err != nil {
+		return fmt.Errorf("error listing keys: %w", err)
+	}
+
+	out := make([]keyValue, 0, len(res.GetKvs()))
+	t := table{header: keyHeader}
+	for _, entry := range res.GetKvs() {
+		out = append(out, newKeyValue(entry))
+		t.rows = append(t.rows, out[len(out)-1].row())
+	}
+
+	return cli.print(out, t)
+}
+
+func kvWatch(ctx context.Context, cli *CLI, args []string) error {
+	fs := flag.NewFlagSet("kv watch", flag.ContinueOnError)
+	prefix := fs.String("prefix", "", "key prefix")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	kv, err := cli.kvClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	stream, err := kv.Watch(ctx, &apiv1.WatchKeysRequest{Prefix: *prefix})
+	if err != nil {
+		return fmt.Errorf("error watching keys: %w", err)
+	}
+
+	header := []string{"EVENT", "KEY", "VALUE", "REVISION"}
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error watching keys: %w", err)
+		}
+
+		for _, e := range res.GetEvents() {
+			event := keyEvent{Event: "PUT", KV: newKeyValue(e.GetKv())}
+			if e.GetDeleted() {
+				event.Event = "DELETE"
+			}
+
+			if err := cli.print(event, table{header: header, rows: [][]string{append([]string{event.Event}, event.KV.row()...)}}); err != nil {
+				return err
+			}
+			// Only print the table header once.
+			header = nil
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// platformctl is a command-line client for inspecting and operating platform
+// services.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	grpccreds "google.golang.org/grpc/credentials"
+
+	"github.com/loshz/platform/internal/config"
+	"github.com/loshz/platform/internal/credentials"
+	pgrpc "github.com/loshz/platform/internal/grpc"
+	"github.com/loshz/platform/internal/version"
+)
+
+const usage = `Usage: platformctl [flags] <command> [args]
+
+Commands:
+  services list        List services registered for discovery
+  services watch       Watch services being registered and deregistered
+  services register    Manually register a service for discovery
+  services deregister  Manually deregister a service from discovery
+  kv get               Get the value of a key from the discovery KV store
+  kv put               Set the value of a key in the discovery KV store
+  kv delete            Delete a key from the discovery KV store
+  kv list              List keys in the discovery KV store
+  kv watch             Watch changes to keys in the discovery KV store
+  events send          Send a test event to eventd
+  secrets encrypt      Encrypt a secret for use as a config value
+  health               Show the health of a service instance
+  metrics              Show a summary of a service instance's metrics
+  logs                 Tail the logs of a service instance
+  version              Show the platformctl version
+
+Flags:
+`
+
+// errUsage is returned when a command is called with invalid arguments.
+var errUsage = errors.New("invalid usage")
+
+// command is a platformctl subcommand.
+type command func(ctx context.Context, cli *CLI, args []string) error
+
+var commands = map[string]map[string]command{
+	"services": {
+		"list":       servicesList,
+		"watch":      servicesWatch,
+		"register":   servicesRegister,
+		"deregister": servicesDeregister,
+	},
+	"kv": {
+		"get":    kvGet,
+		"put":    kvPut,
+		"delete": kvDelete,
+		"list":   kvList,
+		"watch":  kvWatch,
+	},
+	"events": {
+		"send": eventsSend,
+	},
+	"secrets": {
+		"encrypt": secretsEncrypt,
+	},
+	"health":  {"": health},
+	"metrics": {"": metricsSummary},
+	"logs":    {"": logs},
+	"version": {"": printVersion},
+}
+
+// CLI stores global platformctl state shared by all commands.
+type CLI struct {
+	conf    *config.Config
+	creds   *credentials.Store
+	out     io.Writer
+	format  string
+	timeout time.Duration
+	token   bool
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		}
+		cancel()
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	cli := &CLI{
+		conf:  config.New(),
+		creds: new(credentials.Store),
+		out:   out,
+	}
+
+	fs := flag.NewFlagSet("platformctl", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+
+	discovery := fs.String("discovery", "", "discovery service address (default $PLAT_SERVICE_DISCOVERY_ADDR)")
+	ca := fs.String("ca", "", "path to the CA certificate (default $PLAT_GRPC_TLS_CA)")
+	cert := fs.String("cert", "", "path to the client certificate (default $PLAT_GRPC_CLIENT_CERT)")
+	key := fs.String("key", "", "path to the client key (default $PLAT_GRPC_CLIENT_KEY)")
+	identities := fs.String("server-identities", "", "comma separated target=identity pairs servers must present (default $PLAT_GRPC_CLIENT_SERVER_IDENTITIES)")
+	fs.StringVar(&cli.format, "o", formatTable, "output format, one of: table, json")
+	fs.DurationVar(&cli.timeout, "timeout", 10*time.Second, "timeout for individual requests")
+	fs.BoolVar(&cli.token, "token", false, "attach bearer tokens signed with the client certificate to requests")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return errUsage
+	}
+
+	if cli.format != formatTable && cli.format != formatJSON {
+		return fmt.Errorf("unknown output format '%s'", cli.format)
+	}
+
+	if err := config.ParseStringMap(*identities); err != nil {
+		return fmt.Errorf("invalid server identities: %w", err)
+	}
+
+	// Find the command and subcommand.
+	args = fs.Args()
+	if len(args) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	subcommands, ok := commands[args[0]]
+	if !ok {
+		fs.Usage()
+		return errUsage
+	}
+
+	cmd, ok := subcommands[""]
+	args = args[1:]
+	if !ok {
+		if len(args) == 0 {
+			fs.Usage()
+			return errUsage
+		}
+
+		if cmd, ok = subcommands[args[0]]; !ok {
+			fs.Usage()
+			return errUsage
+		}
+		args = args[1:]
+	}
+
+	// Load client config, preferring flags over env vars.
+	cli.loadConfig(map[string]string{
+		config.KeyServiceDiscoveryAddr: *discovery,
+		config.KeyGrpcTLSCA:            *ca,
+		config.KeyGrpcClientCert:       *cert,
+		config.KeyGrpcClientKey:        *key,
+		config.KeyGrpcClientIdentities: *identities,
+	})
+
+	return cmd(ctx, cli, args)
+}
+
+// loadConfig loads client config from env vars with defaults, overriding any
+// values explicitly set by flags.
+func (cli *CLI) loadConfig(flags map[string]string) {
+	cli.conf.MustLoadKeys(
+		config.KeyServiceDiscoveryAddr,
+		config.KeyGrpcTLSCA,
+		config.KeyGrpcClientCert,
+		config.KeyGrpcClientKey,
+		config.KeyGrpcClientIdentities,
+	)
+
+	for key, value := range flags {
+		if value != "" {
+			cli.conf.Set(key, value)
+		}
+	}
+}
+
+// policy returns the gRPC client call policy, using the request timeout as the
+// default deadline.
+func (cli *CLI) policy() pgrpc.ClientPolicy {
+	policy := pgrpc.DefaultClientPolicy()
+	policy.Timeout = cli.timeout
+
+	return policy
+}
+
+// clientCreds returns the client credentials used to dial a target, expecting
+// the server identity configured for the target, if any.
+func (cli *CLI) clientCreds(target string) (grpccreds.TransportCredentials, error) {
+	return cli.creds.GrpcClientFor(cli.conf.StringMap(config.KeyGrpcClientIdentities)[target])
+}
+
+// dialOptions returns the dial options used by all gRPC clients so that calls
+// can be correlated with server logs, including bearer tokens if enabled.
+func (cli *CLI) dialOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(pgrpc.UnaryClientRequestIDInterceptor()),
+		grpc.WithChainStreamInterceptor(pgrpc.StreamClientRequestIDInterceptor()),
+	}
+	if creds := cli.creds.GrpcToken(); creds != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(creds))
+	}
+
+	return opts
+}
+
+// loadCredentials loads the same mTLS client credentials used by platform
+// services, and token credentials if enabled.
+func (cli *CLI) loadCredentials() error {
+	if err := cli.creds.LoadGrpcClientCreds(cli.conf); err != nil {
+		return err
+	}
+
+	if !cli.token {
+		return nil
+	}
+
+	// Tokens are only needed for the lifetime of a command.
+	cli.conf.Set(config.KeyGrpcClientTokenTTL, cli.timeout)
+	return cli.creds.LoadGrpcTokenCreds(cli.conf)
+}
+
+func printVersion(_ context.Context, cli *CLI, _ []string) error {
+	fmt.Fprintf(cli.out, "platformctl %s\n", version.Build)
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// Supported output formats.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// table represents tabular command output.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// print writes command output in the configured format: v is encoded as JSON,
+// otherwise t is written as an aligned table.
+func (cli *CLI) print(v interface{}, t table) error {
+	if cli.format == formatJSON {
+		enc := json.NewEncoder(cli.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(cli.out, 0, 0, 2, ' ', 0)
+	if len(t.header) > 0 {
+		fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	}
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
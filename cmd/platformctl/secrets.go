@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/loshz/platform/internal/config"
+)
+
+// encryptedSecret represents the output of an encrypted secret.
+type encryptedSecret struct {
+	Value string `json:"value"`
+}
+
+func secretsEncrypt(_ context.Context, cli *CLI, args []string) error {
+	keyFile := os.Getenv("PLAT_CONFIG_SECRET_KEY_FILE")
+	if keyFile == "" {
+		keyFile = config.DefaultSecretKeyFile
+	}
+
+	fs := flag.NewFlagSet("secrets encrypt", flag.ContinueOnError)
+	fs.StringVar(&keyFile, "key-file", keyFile, "path to the base64 encoded secret key (default $PLAT_CONFIG_SECRET_KEY_FILE)")
+	value := fs.String("value", "", "secret to encrypt (default: read from stdin)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	// Prefer reading from stdin, so secrets aren't recorded in shell history.
+	secret := *value
+	if secret == "" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("error reading secret: %w", err)
+		}
+		secret = strings.TrimRight(string(data), "\r\n")
+	}
+
+	enc, err := config.EncryptSecret(keyFile, secret)
+	if err != nil {
+		return err
+	}
+
+	out := encryptedSecret{Value: enc}
+	return cli.print(out, table{header: []string{"VALUE"}, rows: [][]string{{out.Value}}})
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/config"
+	"github.com/loshz/platform/internal/discovery"
+)
+
+// delimiterAll represents the discovery delimiter for specifying all services.
+const delimiterAll = "*"
+
+// service represents the output of a single discovered service.
+type service struct {
+	UUID     string    `json:"uuid"`
+	Address  string    `json:"address"`
+	HTTPPort uint32    `json:"http_port"`
+	GRPCPort uint32    `json:"grpc_port"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// serviceEvent represents a change in discovered services.
+type serviceEvent struct {
+	Event   string  `json:"event"`
+	Service service `json:"service"`
+}
+
+var serviceHeader = []string{"UUID", "ADDRESS", "HTTP PORT", "GRPC PORT", "LAST SEEN"}
+
+func newService(svc *apiv1.Service) service {
+	return service{
+		UUID:     svc.GetUuid(),
+		Address:  svc.GetAddress(),
+		HTTPPort: svc.GetHttpPort(),
+		GRPCPort: svc.GetGrpcPort(),
+		LastSeen: time.Unix(svc.GetLastSeen(), 0).UTC(),
+	}
+}
+
+func (s service) row() []string {
+	return []string{
+		s.UUID,
+		s.Address,
+		strconv.Itoa(int(s.HTTPPort)),
+		strconv.Itoa(int(s.GRPCPort)),
+		s.LastSeen.Format(time.RFC3339),
+	}
+}
+
+// discovery starts a discovery client using the service mTLS credentials.
+func (cli *CLI) discovery(ctx context.Context) (*discovery.Service, error) {
+	if err := cli.loadCredentials(); err != nil {
+		return nil, err
+	}
+
+	addr := cli.conf.String(config.KeyServiceDiscoveryAddr)
+	creds, err := cli.clientCreds(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ds := new(discovery.Service)
+	if err := ds.Start(ctx, addr, creds, cli.policy(), cli.dialOptions()...); err != nil {
+		return nil, err
+	}
+
+	return ds, nil
+}
+
+// lookup returns all services with a given name prefix sorted by uuid.
+func (cli *CLI) lookup(ctx context.Context, ds *discovery.Service, name string) ([]service, error) {
+	svcs, err := ds.Lookup(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up services: %w", err)
+	}
+
+	out := make([]service, 0, len(svcs))
+	for _, svc := range svcs {
+		out = append(out, newService(svc))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UUID < out[j].UUID })
+
+	return out, nil
+}
+
+func servicesList(ctx context.Context, cli *CLI, args []string) error {
+	fs := flag.NewFlagSet("services list", flag.ContinueOnError)
+	name := fs.String("name", delimiterAll, "service name prefix")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	ds, err := cli.discovery(ctx)
+	if err != nil {
+		return err
+	}
+
+	svcs, err := cli.lookup(ctx, ds, *name)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: serviceHeader}
+	for _, svc := range svcs {
+		t.rows = append(t.rows, svc.row())
+	}
+
+	return cli.print(svcs, t)
+}
+
+func servicesWatch(ctx context.Context, cli *CLI, args []string) error {
+	fs := flag.NewFlagSet("services watch", flag.ContinueOnError)
+	name := fs.String("name", delimiterAll, "service name prefix")
+	interval := fs.Duration("interval", 5*time.Second, "interval between discovery lookups")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	ds, err := cli.discovery(ctx)
+	if err != nil {
+		return err
+	}
+
+	t := time.NewTicker(*interval)
+	defer t.Stop()
+
+	known := make(map[string]service)
+	header := []string{"EVENT", "UUID", "ADDRESS", "HTTP PORT", "GRPC PORT", "LAST SEEN"}
+	for {
+		svcs, err := cli.lookup(ctx, ds, *name)
+		if err != nil {
+			return err
+		}
+
+		// Compare the current services against those previously seen.
+		var events []serviceEvent
+		current := make(map[string]service, len(svcs))
+		for _, svc := range svcs {
+			current[svc.UUID] = svc
+			if _, ok := known[svc.UUID]; !ok {
+				events = append(events, serviceEvent{"ADDED", svc})
+			}
+		}
+		for uuid, svc := range known {
+			if _, ok := current[uuid]; !ok {
+				events = append(events, serviceEvent{"REMOVED", svc})
+			}
+		}
+		known = current
+
+		for _, event := range events {
+			if err := cli.print(event, table{header: header, rows: [][]string{append([]string{event.Event}, event.Service.row()...)}}); err != nil {
+				return err
+			}
+			// Only print the table header once.
+			header = nil
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func servicesRegister(ctx context.Context, cli *CLI, args []string) error {
+	fs := flag.NewFlagSet("services register", flag.ContinueOnError)
+	uuid := fs.String("uuid", "", "service uuid (required)")
+	address := fs.String("address", "", "service address (required)")
+	httpPort := fs.Uint("http-port", 0, "service http port")
+	grpcPort := fs.Uint("grpc-port", 0, "service grpc port")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if *uuid == "" || *address == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	ds, err := cli.discovery(ctx)
+	if err != nil {
+		return err
+	}
+
+	svc := &apiv1.Service{
+		Uuid:     *uuid,
+		Address:  *address,
+		HttpPort: uint32(*httpPort),
+		GrpcPort: uint32(*grpcPort),
+		LastSeen: time.Now().Unix(),
+	}
+
+	if err := ds.Register(ctx, svc); err != nil {
+		return fmt.Errorf("error registering service: %w", err)
+	}
+
+	out := newService(svc)
+	return cli.print(out, table{header: serviceHeader, rows: [][]string{out.row()}})
+}
+
+func servicesDeregister(ctx context.Context, cli *CLI, args []string) error {
+	fs := flag.NewFlagSet("services deregister", flag.ContinueOnError)
+	uuid := fs.String("uuid", "", "service uuid (required)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if *uuid == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	ds, err := cli.discovery(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := ds.Deregister(ctx, *uuid); err != nil {
+		return fmt.Errorf("error deregistering service: %w", err)
+	}
+
+	out := struct {
+		UUID string `json:"uuid"`
+	}{*uuid}
+	return cli.print(out, table{header: []string{"UUID"}, rows: [][]string{{*uuid}}})
+}
@@ -1,66 +1,21 @@
 package main
 
 import (
-	"context"
-	"fmt"
-	"time"
+	"os"
 
-	"github.com/rs/zerolog/log"
-	"google.golang.org/grpc"
-
-	apiv1 "github.com/loshz/platform/internal/api/v1"
-	"github.com/loshz/platform/internal/credentials"
 	"github.com/loshz/platform/internal/service"
+	"github.com/loshz/platform/internal/trafficd"
 )
 
 func main() {
-	s := service.New("trafficd")
+	s := service.New(trafficd.Name)
+
+	// Load the config file and flags before any other config.
+	s.LoadConfigSources(os.Args[1:])
 
 	// Load required service credentials and dependencies before startup.
-	s.LoadCredentials(credentials.GrpcClient)
+	trafficd.Configure(s)
 
 	// Run the service.
-	s.Run(run)
-}
-
-func run(ctx context.Context, s *service.Service) error {
-	go func() {
-		// TODO: refactor this whole function to use periodic refresh and retries.
-		time.Sleep(10 * time.Second)
-
-		// Get eventd address.
-		svcs, err := s.Discovery().Lookup(context.Background(), "eventd")
-		if err != nil {
-			s.Error(fmt.Errorf("error getting eventd service details from discovery: %w", err))
-			return
-		}
-
-		// TODO: perform sanity check on returned eventd services.
-		eventd := fmt.Sprintf("%s:%d", svcs[0].Address, svcs[0].GrpcPort)
-		conn, err := grpc.Dial(eventd, grpc.WithTransportCredentials(s.Creds().GrpcClient()))
-		if err != nil {
-			s.Error(fmt.Errorf("error dialing eventd: %w", err))
-			return
-		}
-		client := apiv1.NewEventServiceClient(conn)
-
-		t := time.NewTicker(10 * time.Second)
-		for {
-			select {
-			case <-t.C:
-				res, err := client.Event(context.Background(), &apiv1.EventRequest{Hostname: "blah"})
-				if err != nil {
-					log.Error().Err(err).Msg("error making request to eventd")
-					continue
-				}
-
-				log.Info().Msgf("eventd response: %s", res.Uuid)
-			case <-ctx.Done():
-				conn.Close()
-				return
-			}
-		}
-	}()
-
-	return nil
+	s.Run(trafficd.Run)
 }
@@ -0,0 +1,111 @@
+// Package authz provides peer identity and policy based authorization of
+// platform RPCs.
+package authz
+
+import (
+	"context"
+	"crypto/x509"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/loshz/platform/internal/uuid"
+)
+
+// SpiffeScheme is the URI scheme of SPIFFE style identities.
+// E.g., spiffe://platform/eventd/eventd-xxxx-xxxx
+const SpiffeScheme = "spiffe"
+
+// Identity represents the platform service identity of a peer.
+type Identity struct {
+	// Name of the service. E.g., eventd
+	Service string
+
+	// UUID of the individual service instance including name prefix, if known.
+	// E.g., eventd-xxxx-xxxx
+	Instance string
+}
+
+// IsAnonymous reports whether the identity is unknown, e.g. the peer didn't
+// present a client certificate.
+func (id Identity) IsAnonymous() bool { return id.Service == "" }
+
+func (id Identity) String() string {
+	switch {
+	case id.IsAnonymous():
+		return "anonymous"
+	case id.Instance == "":
+		return id.Service
+	}
+
+	return id.Instance
+}
+
+// IdentityFromCert maps a certificate to a platform service identity.
+//
+// SPIFFE URI SANs of the form spiffe://<trust-domain>/<service>[/<instance>] are
+// preferred, followed by the first DNS SAN and then the subject CN. DNS and CN
+// values that are service UUIDs identify an individual instance, otherwise the
+// value is treated as a service name.
+func IdentityFromCert(cert *x509.Certificate) Identity {
+	for _, uri := range cert.URIs {
+		if uri.Scheme != SpiffeScheme {
+			continue
+		}
+
+		segments := strings.Split(strings.Trim(uri.Path, "/"), "/")
+		id := Identity{Service: segments[0]}
+		if len(segments) > 1 {
+			id.Instance = segments[1]
+		}
+
+		return id
+	}
+
+	name := cert.Subject.CommonName
+	if len(cert.DNSNames) > 0 {
+		name = cert.DNSNames[0]
+	}
+
+	return identityFromName(name)
+}
+
+// identityKey is the context key of a verified caller identity.
+type identityKey struct{}
+
+// WithIdentity returns a context with a verified caller identity, e.g. from a
+// bearer token, that takes precedence over the peer's client certificate.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFromContext returns the identity of the caller associated with a
+// context. Identities stored with WithIdentity are preferred, followed by the
+// peer's verified client certificate. Otherwise callers are anonymous.
+func IdentityFromContext(ctx context.Context) Identity {
+	if id, ok := ctx.Value(identityKey{}).(Identity); ok {
+		return id
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return Identity{}
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.PeerCertificates) == 0 {
+		return Identity{}
+	}
+
+	return IdentityFromCert(info.State.PeerCertificates[0])
+}
+
+// identityFromName maps a DNS name or CN to an identity.
+func identityFromName(name string) Identity {
+	if u, err := uuid.Parse(name); err == nil {
+		return Identity{Service: u.Name(), Instance: u.String()}
+	}
+
+	return Identity{Service: strings.ToLower(name)}
+}
@@ -0,0 +1,75 @@
+package authz
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/loshz/platform/internal/uuid"
+)
+
+func TestIdentityFromCert(t *testing.T) {
+	instance := uuid.New("eventd").String()
+
+	t.Run("TestSpiffeURI", func(t *testing.T) {
+		cert := &x509.Certificate{
+			Subject:  pkix.Name{CommonName: "localhost"},
+			DNSNames: []string{"localhost"},
+			URIs:     []*url.URL{{Scheme: SpiffeScheme, Host: "platform", Path: "/eventd/" + instance}},
+		}
+
+		// Assert SPIFFE URIs are preferred.
+		assert.Equal(t, Identity{"eventd", instance}, IdentityFromCert(cert))
+	})
+
+	t.Run("TestDNSName", func(t *testing.T) {
+		cert := &x509.Certificate{
+			Subject:  pkix.Name{CommonName: "localhost"},
+			DNSNames: []string{instance},
+			URIs:     []*url.URL{{Scheme: "https", Host: "platform"}},
+		}
+
+		// Assert DNS names are preferred over the CN.
+		assert.Equal(t, Identity{"eventd", instance}, IdentityFromCert(cert))
+	})
+
+	t.Run("TestCommonName", func(t *testing.T) {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: "Platformctl"}}
+
+		// Assert non-uuid names identify a service.
+		assert.Equal(t, Identity{Service: "platformctl"}, IdentityFromCert(cert))
+	})
+}
+
+func TestIdentityFromContext(t *testing.T) {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "trafficd"}}
+
+	// Assert peers without a verified certificate are anonymous.
+	assert.True(t, IdentityFromContext(context.Background()).IsAnonymous())
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+	}})
+	assert.True(t, IdentityFromContext(ctx).IsAnonymous())
+
+	ctx = peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert}},
+		},
+	}})
+	assert.Equal(t, Identity{Service: "trafficd"}, IdentityFromContext(ctx))
+}
+
+func TestIdentityString(t *testing.T) {
+	assert.Equal(t, "anonymous", Identity{}.String())
+	assert.Equal(t, "eventd", Identity{Service: "eventd"}.String())
+	assert.Equal(t, "eventd-1234", Identity{"eventd", "eventd-1234"}.String())
+}
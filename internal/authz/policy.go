@@ -0,0 +1,192 @@
+package authz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Policy decisions.
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// Wildcard matches any method or authenticated service.
+const Wildcard = "*"
+
+// ErrDenied is returned when a call is not authorized by a policy.
+var ErrDenied = errors.New("permission denied")
+
+// Policy represents a set of rules used to authorize calls.
+type Policy struct {
+	// Decision for calls that don't match any rule, either Allow or Deny.
+	Default string `json:"default"`
+
+	// A call is authorized if any rule matching its method allows it. Calls
+	// that match rules, but aren't allowed by any, are denied.
+	Rules []Rule `json:"rules"`
+}
+
+// Rule authorizes calls to a set of methods.
+type Rule struct {
+	// Full method names, e.g. /proto.v1.DiscoveryService/DeregisterService.
+	// A method of "/<service>/*" matches all methods of a service, and "*"
+	// matches all methods.
+	Methods []string `json:"methods"`
+
+	// Names of services allowed to call the methods. "*" allows any service,
+	// but never anonymous peers.
+	Allow []string `json:"allow"`
+
+	// Optional path of a request field, e.g. service.uuid, that must equal the
+	// caller's instance UUID. This can be used to restrict instances to acting
+	// on themselves.
+	Self string `json:"self,omitempty"`
+}
+
+// LoadPolicy reads and validates a JSON policy file.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading policy file: %w", err)
+	}
+
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("error parsing policy file: %w", err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Validate ensures a policy is well formed.
+func (p *Policy) Validate() error {
+	switch p.Default {
+	case Allow, Deny:
+	default:
+		return fmt.Errorf("invalid policy default '%s': must be one of %s, %s", p.Default, Allow, Deny)
+	}
+
+	for i, r := range p.Rules {
+		if len(r.Methods) == 0 {
+			return fmt.Errorf("invalid policy rule %d: no methods", i)
+		}
+	}
+
+	return nil
+}
+
+// Authorize checks whether an identity may call a method with a given request.
+// An error wrapping ErrDenied and describing the reason is returned if the call
+// is not authorized.
+func (p *Policy) Authorize(id Identity, method string, req interface{}) error {
+	var denied error
+	for _, r := range p.Rules {
+		if !r.matchMethod(method) {
+			continue
+		}
+
+		if denied = r.authorize(id, method, req); denied == nil {
+			return nil
+		}
+	}
+
+	// At least one rule matched, but none allowed the call.
+	if denied != nil {
+		return denied
+	}
+
+	if p.Default != Allow {
+		return fmt.Errorf("%w: no rule allows %s", ErrDenied, method)
+	}
+
+	return nil
+}
+
+// authorize checks whether a rule allows an identity to call a method.
+func (r Rule) authorize(id Identity, method string, req interface{}) error {
+	if !r.allows(id) {
+		return fmt.Errorf("%w: %s may not call %s", ErrDenied, id, method)
+	}
+
+	if r.Self != "" {
+		val, ok := fieldValue(req, r.Self)
+		if !ok || id.Instance == "" || val != id.Instance {
+			return fmt.Errorf("%w: %s may not call %s for '%s'", ErrDenied, id, method, val)
+		}
+	}
+
+	return nil
+}
+
+// matchMethod reports whether a rule applies to a full method name.
+func (r Rule) matchMethod(method string) bool {
+	for _, m := range r.Methods {
+		switch {
+		case m == Wildcard, m == method:
+			return true
+		case strings.HasSuffix(m, "/"+Wildcard) && strings.HasPrefix(method, strings.TrimSuffix(m, Wildcard)):
+			return true
+		}
+	}
+
+	return false
+}
+
+// allows reports whether a rule allows an identity.
+func (r Rule) allows(id Identity) bool {
+	if id.IsAnonymous() {
+		return false
+	}
+
+	for _, svc := range r.Allow {
+		if svc == Wildcard || svc == id.Service {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fieldValue returns the string value of a dot separated field path within a
+// protobuf message.
+func fieldValue(req interface{}, path string) (string, bool) {
+	msg, ok := req.(proto.Message)
+	if !ok || msg == nil {
+		return "", false
+	}
+
+	m := msg.ProtoReflect()
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return "", false
+		}
+
+		// Return the value of the last field.
+		if i == len(names)-1 {
+			if fd.Kind() != protoreflect.StringKind || fd.IsList() {
+				return "", false
+			}
+			return m.Get(fd).String(), true
+		}
+
+		if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() || !m.Has(fd) {
+			return "", false
+		}
+		m = m.Get(fd).Message()
+	}
+
+	return "", false
+}
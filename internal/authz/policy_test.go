@@ -0,0 +1,79 @@
+package authz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	// Assert the example policy is valid.
+	_, err := LoadPolicy("../../config/authz/policy.json")
+	require.NoError(t, err)
+
+	// Assert invalid policies return an error.
+	path := filepath.Join(t.TempDir(), "policy.json")
+	for _, data := range []string{"{", `{"default":"maybe"}`, `{"default":"deny","rules":[{"allow":["*"]}]}`} {
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		_, err := LoadPolicy(path)
+		assert.Error(t, err, data)
+	}
+
+	_, err = LoadPolicy("missing.json")
+	assert.Error(t, err)
+}
+
+func TestAuthorize(t *testing.T) {
+	policy := &Policy{
+		Default: Deny,
+		Rules: []Rule{
+			{Methods: []string{"/proto.v1.DiscoveryService/*"}, Allow: []string{"platformctl"}},
+			{Methods: []string{apiv1.DiscoveryService_DeregisterService_FullMethodName}, Allow: []string{Wildcard}, Self: "uuid"},
+			{Methods: []string{apiv1.DiscoveryService_RegisterService_FullMethodName}, Allow: []string{Wildcard}, Self: "service.uuid"},
+			{Methods: []string{"/proto.v1.EventService/*"}, Allow: []string{"trafficd"}},
+		},
+	}
+
+	eventd := Identity{"eventd", "eventd-1234"}
+	deregister := apiv1.DiscoveryService_DeregisterService_FullMethodName
+	register := apiv1.DiscoveryService_RegisterService_FullMethodName
+
+	tests := map[string]struct {
+		id      Identity
+		method  string
+		req     interface{}
+		allowed bool
+	}{
+		"TestDeregisterSelf":       {eventd, deregister, &apiv1.DeregisterServiceRequest{Uuid: "eventd-1234"}, true},
+		"TestDeregisterOther":      {eventd, deregister, &apiv1.DeregisterServiceRequest{Uuid: "eventd-5678"}, false},
+		"TestDeregisterNoInstance": {Identity{Service: "eventd"}, deregister, &apiv1.DeregisterServiceRequest{}, false},
+		"TestRegisterSelf":         {eventd, register, &apiv1.RegisterServiceRequest{Service: &apiv1.Service{Uuid: "eventd-1234"}}, true},
+		"TestRegisterMissingField": {eventd, register, &apiv1.RegisterServiceRequest{}, false},
+		"TestRegisterNoRequest":    {eventd, register, nil, false},
+		"TestServiceWildcard":      {Identity{Service: "platformctl"}, deregister, &apiv1.DeregisterServiceRequest{Uuid: "eventd-1234"}, true},
+		"TestAllowedService":       {Identity{Service: "trafficd"}, "/proto.v1.EventService/Event", nil, true},
+		"TestDeniedService":        {eventd, "/proto.v1.EventService/Event", nil, false},
+		"TestAnonymous":            {Identity{}, deregister, &apiv1.DeregisterServiceRequest{}, false},
+		"TestDefault":              {eventd, "/unknown/Method", nil, false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := policy.Authorize(tc.id, tc.method, tc.req)
+			if tc.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrDenied)
+			}
+		})
+	}
+
+	// Assert the default decision applies to unmatched methods.
+	policy.Default = Allow
+	assert.NoError(t, policy.Authorize(Identity{}, "/unknown/Method", nil))
+}
@@ -0,0 +1,187 @@
+// Package ca implements a certificate authority that issues short-lived
+// certificates to platform service instances.
+package ca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/loshz/platform/internal/authz"
+	"github.com/loshz/platform/internal/uuid"
+)
+
+// DefaultTrustDomain is the SPIFFE trust domain of platform issued certificates.
+const DefaultTrustDomain = "platform"
+
+// Errors representing invalid certificate requests.
+var (
+	ErrInvalidCSR      = errors.New("invalid certificate signing request")
+	ErrInvalidInstance = errors.New("csr common name must be a service instance uuid")
+)
+
+// Authority signs certificates for service instances.
+type Authority struct {
+	cert    *x509.Certificate
+	certPEM []byte
+	key     crypto.Signer
+
+	// SPIFFE trust domain of issued certificates.
+	trustDomain string
+
+	// Lifetime of issued certificates.
+	ttl time.Duration
+}
+
+// Load creates an Authority from a PEM encoded CA cert and key.
+func Load(certPath, keyPath, trustDomain string, ttl time.Duration) (*Authority, error) {
+	pair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading ca cert/key pair: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ca cert: %w", err)
+	}
+
+	if !cert.IsCA {
+		return nil, errors.New("certificate is not a ca")
+	}
+
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("ca key can not be used for signing")
+	}
+
+	return &Authority{
+		cert:        cert,
+		certPEM:     EncodeCert(cert.Raw),
+		key:         key,
+		trustDomain: trustDomain,
+		ttl:         ttl,
+	}, nil
+}
+
+// CertPEM returns the PEM encoded CA cert.
+func (a *Authority) CertPEM() []byte { return a.certPEM }
+
+// CertPool returns a cert pool containing the CA cert.
+func (a *Authority) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(a.cert)
+
+	return pool
+}
+
+// Issue signs a certificate for the service instance named by the CSR's common
+// name. The certificate can be used for both client and server authentication,
+// and contains the instance's SPIFFE ID, service name and uuid as SANs.
+func (a *Authority) Issue(csr *x509.CertificateRequest) (*x509.Certificate, error) {
+	id, err := CSRIdentity(csr)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("error generating serial number: %w", err)
+	}
+
+	// Issued certs must not outlive the CA.
+	now := time.Now()
+	expiry := now.Add(a.ttl)
+	if expiry.After(a.cert.NotAfter) {
+		expiry = a.cert.NotAfter
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: id.Instance},
+		DNSNames:     []string{id.Service, id.Instance},
+		URIs:         []*url.URL{SpiffeID(a.trustDomain, id)},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     expiry,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, csr.PublicKey, a.key)
+	if err != nil {
+		return nil, fmt.Errorf("error signing certificate: %w", err)
+	}
+
+	return x509.ParseCertificate(der)
+}
+
+// SpiffeID returns the SPIFFE ID of a service instance.
+// E.g., spiffe://platform/eventd/eventd-xxxx-xxxx
+func SpiffeID(trustDomain string, id authz.Identity) *url.URL {
+	return &url.URL{
+		Scheme: authz.SpiffeScheme,
+		Host:   trustDomain,
+		Path:   "/" + id.Service + "/" + id.Instance,
+	}
+}
+
+// CSRIdentity validates a CSR's signature and returns the identity of the
+// service instance named by its common name.
+func CSRIdentity(csr *x509.CertificateRequest) (authz.Identity, error) {
+	if err := csr.CheckSignature(); err != nil {
+		return authz.Identity{}, fmt.Errorf("%w: %w", ErrInvalidCSR, err)
+	}
+
+	u, err := uuid.Parse(csr.Subject.CommonName)
+	if err != nil {
+		return authz.Identity{}, ErrInvalidInstance
+	}
+
+	return authz.Identity{Service: u.Name(), Instance: u.String()}, nil
+}
+
+// NewCSR generates a private key and PEM encoded CSR for a service instance.
+func NewCSR(instance string) (crypto.Signer, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error generating key: %w", err)
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: instance},
+	}, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating csr: %w", err)
+	}
+
+	return key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
+
+// ParseCSR parses a PEM encoded CSR.
+func ParseCSR(data []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, ErrInvalidCSR
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCSR, err)
+	}
+
+	return csr, nil
+}
+
+// EncodeCert PEM encodes a DER encoded cert.
+func EncodeCert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,190 @@
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/authz"
+	"github.com/loshz/platform/internal/uuid"
+)
+
+// testAuthority writes a CA cert/key that expires at the given time and loads
+// an Authority from them.
+func testAuthority(t *testing.T, expiry time.Time, ttl time.Duration) *Authority {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              expiry,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "ca.crt.pem"), filepath.Join(dir, "ca.key.pem")
+	require.NoError(t, os.WriteFile(certPath, EncodeCert(der), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	a, err := Load(certPath, keyPath, DefaultTrustDomain, ttl)
+	require.NoError(t, err)
+
+	return a
+}
+
+func TestIssue(t *testing.T) {
+	a := testAuthority(t, time.Now().Add(time.Hour), 10*time.Minute)
+	id := uuid.New("eventd")
+
+	t.Run("TestValid", func(t *testing.T) {
+		_, data, err := NewCSR(id.String())
+		require.NoError(t, err)
+		csr, err := ParseCSR(data)
+		require.NoError(t, err)
+
+		cert, err := a.Issue(csr)
+		require.NoError(t, err)
+
+		// Assert the cert identifies the instance and is trusted by the CA.
+		assert.Equal(t, authz.Identity{Service: "eventd", Instance: id.String()}, authz.IdentityFromCert(cert))
+		assert.Equal(t, []string{"eventd", id.String()}, cert.DNSNames)
+		assert.Equal(t, "spiffe://platform/eventd/"+id.String(), cert.URIs[0].String())
+		assert.WithinDuration(t, time.Now().Add(10*time.Minute), cert.NotAfter, time.Minute)
+
+		for _, usage := range []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth} {
+			_, err = cert.Verify(x509.VerifyOptions{Roots: a.CertPool(), DNSName: "eventd", KeyUsages: []x509.ExtKeyUsage{usage}})
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("TestCappedExpiry", func(t *testing.T) {
+		a := testAuthority(t, time.Now().Add(time.Hour), 24*time.Hour)
+
+		_, data, err := NewCSR(id.String())
+		require.NoError(t, err)
+		csr, err := ParseCSR(data)
+		require.NoError(t, err)
+
+		// Assert issued certs don't outlive the CA.
+		cert, err := a.Issue(csr)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), cert.NotAfter, time.Minute)
+	})
+
+	t.Run("TestInvalidInstance", func(t *testing.T) {
+		_, data, err := NewCSR("eventd")
+		require.NoError(t, err)
+		csr, err := ParseCSR(data)
+		require.NoError(t, err)
+
+		_, err = a.Issue(csr)
+		assert.ErrorIs(t, err, ErrInvalidInstance)
+	})
+
+	t.Run("TestInvalidCSR", func(t *testing.T) {
+		_, err := ParseCSR([]byte("invalid"))
+		assert.ErrorIs(t, err, ErrInvalidCSR)
+	})
+}
+
+func TestExamplePolicy(t *testing.T) {
+	policy, err := authz.LoadPolicy("../../config/authz/policy.json")
+	require.NoError(t, err)
+
+	a := testAuthority(t, time.Now().Add(time.Hour), 10*time.Minute)
+	issue := func(service string) authz.Identity {
+		_, data, err := NewCSR(uuid.New(service).String())
+		require.NoError(t, err)
+		csr, err := ParseCSR(data)
+		require.NoError(t, err)
+		cert, err := a.Issue(csr)
+		require.NoError(t, err)
+		return authz.IdentityFromCert(cert)
+	}
+
+	eventd, trafficd := issue("eventd"), issue("trafficd")
+	// Identities of the certs generated by make tls and make tls/platformctl.
+	shared := authz.IdentityFromCert(&x509.Certificate{Subject: pkix.Name{CommonName: "localhost"}, DNSNames: []string{"localhost"}})
+	platformctl := authz.IdentityFromCert(&x509.Certificate{
+		Subject: pkix.Name{CommonName: "platformctl"},
+		URIs:    []*url.URL{{Scheme: "spiffe", Host: "platform", Path: "/platformctl"}},
+	})
+
+	register := func(id authz.Identity) *apiv1.RegisterServiceRequest {
+		return &apiv1.RegisterServiceRequest{Service: &apiv1.Service{Uuid: id.Instance}}
+	}
+
+	tests := []struct {
+		name    string
+		id      authz.Identity
+		method  string
+		req     interface{}
+		allowed bool
+	}{
+		{"TestRegisterSelf", eventd, apiv1.DiscoveryService_RegisterService_FullMethodName, register(eventd), true},
+		{"TestRegisterOther", eventd, apiv1.DiscoveryService_RegisterService_FullMethodName, register(trafficd), false},
+		{"TestDeregisterSelf", trafficd, apiv1.DiscoveryService_DeregisterService_FullMethodName, &apiv1.DeregisterServiceRequest{Uuid: trafficd.Instance}, true},
+		{"TestDeregisterOther", trafficd, apiv1.DiscoveryService_DeregisterService_FullMethodName, &apiv1.DeregisterServiceRequest{Uuid: eventd.Instance}, false},
+		{"TestGetServices", trafficd, apiv1.DiscoveryService_GetServices_FullMethodName, nil, true},
+		{"TestWatchKeys", eventd, apiv1.KVService_Watch_FullMethodName, nil, true},
+		{"TestPutKey", eventd, apiv1.KVService_Put_FullMethodName, nil, false},
+		{"TestEvent", trafficd, apiv1.EventService_Event_FullMethodName, nil, true},
+		{"TestEventOther", eventd, apiv1.EventService_Event_FullMethodName, nil, false},
+		{"TestSharedRegister", shared, apiv1.DiscoveryService_RegisterService_FullMethodName, register(shared), false},
+		{"TestSharedEvent", shared, apiv1.EventService_Event_FullMethodName, nil, false},
+		{"TestPlatformctlRegister", platformctl, apiv1.DiscoveryService_RegisterService_FullMethodName, register(eventd), true},
+		{"TestPlatformctlPutKey", platformctl, apiv1.KVService_Put_FullMethodName, nil, true},
+		{"TestPlatformctlAdmin", platformctl, "/admin/drain", nil, true},
+		{"TestAnonymous", authz.Identity{}, apiv1.DiscoveryService_GetServices_FullMethodName, nil, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Authorize(tc.id, tc.method, tc.req)
+			if tc.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, authz.ErrDenied)
+			}
+		})
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tokens := NewTokens(map[string]string{
+		"token-a": "eventd",
+		"token-b": "trafficd",
+	})
+
+	// Assert tokens can be redeemed repeatedly, only by their service.
+	assert.True(t, tokens.Redeem("token-a", "eventd"))
+	assert.True(t, tokens.Redeem("token-a", "eventd"))
+	assert.False(t, tokens.Redeem("token-c", "eventd"))
+
+	// Assert tokens presented by another service are revoked.
+	assert.False(t, tokens.Redeem("token-b", "eventd"))
+	assert.False(t, tokens.Redeem("token-b", "trafficd"))
+}
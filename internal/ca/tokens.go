@@ -0,0 +1,48 @@
+package ca
+
+import (
+	"crypto/sha256"
+	"sync"
+)
+
+// Tokens stores bootstrap tokens that allow service instances without a
+// platform issued cert to request one.
+type Tokens struct {
+	mtx sync.Mutex
+
+	// Services keyed by the hash of their token, so lookups don't leak
+	// information about valid tokens through timing.
+	services map[[sha256.Size]byte]string
+}
+
+// NewTokens creates a token store from a map of tokens to the name of the
+// service they may be redeemed by.
+func NewTokens(tokens map[string]string) *Tokens {
+	t := &Tokens{services: make(map[[sha256.Size]byte]string, len(tokens))}
+	for token, service := range tokens {
+		t.services[sha256.Sum256([]byte(token))] = service
+	}
+
+	return t
+}
+
+// Redeem reports whether a token is valid for a service. Tokens can be
+// redeemed by every instance of their service, so instances can restart
+// without a new token, but are revoked if presented by another service.
+func (t *Tokens) Redeem(token, service string) bool {
+	key := sha256.Sum256([]byte(token))
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	svc, ok := t.services[key]
+	if !ok {
+		return false
+	}
+	if svc != service {
+		delete(t.services, key)
+		return false
+	}
+
+	return true
+}
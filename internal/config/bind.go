@@ -0,0 +1,266 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Struct tags used to bind config values to struct fields.
+const (
+	TagKey      = "plat"
+	TagDefault  = "default"
+	TagValidate = "validate"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// binding is a struct field and the key of its config value.
+type binding struct {
+	field reflect.Value
+	key   string
+}
+
+// Bind loads config values into the tagged fields of a struct pointer.
+// For example:
+//
+//	type ServerConfig struct {
+//		Port    int           `plat:"grpc.server.port" default:"0" validate:"min=0,max=65535"`
+//		Timeout time.Duration `plat:"grpc.server.conn.timeout" default:"10s" validate:"min=1s"`
+//		Token   string        `plat:"ca.token"`
+//	}
+//
+// Fields of registered keys default to, and are validated by, their registered
+// schema. Otherwise, fields without a default are required, as are string
+// fields with an empty default. Empty defaults of slice and map fields are empty values. Each value
+// is validated with the ParseFunc of its field type, followed by any comma
+// separated validate rules: min=<n>, max=<n>, oneof=<a b c> and loglevel.
+// Untagged struct fields are bound recursively.
+//
+// Values are loaded the same as Load, so are reloaded with their validation.
+// Every invalid value is reported in the returned error, and fields are only
+// set if all values are valid.
+func (c *Config) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("error binding config: %T is not a struct pointer", v)
+	}
+
+	var bindings []binding
+	var errs []error
+	c.bind(rv.Elem(), &bindings, &errs)
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("error binding config: %w", err)
+	}
+
+	for _, b := range bindings {
+		c.setField(b.field, b.key)
+	}
+
+	return nil
+}
+
+// MustBind is functionally equivalent to Bind, but panics on error.
+func (c *Config) MustBind(v interface{}) {
+	if err := c.Bind(v); err != nil {
+		panic(err)
+	}
+}
+
+// bind loads the config value of each tagged field in a struct, recording
+// bindings for valid values and errors for invalid ones.
+func (c *Config) bind(rv reflect.Value, bindings *[]binding, errs *[]error) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		key, ok := f.Tag.Lookup(TagKey)
+		if !ok {
+			if f.Type.Kind() == reflect.Struct && f.Type != durationType {
+				c.bind(rv.Field(i), bindings, errs)
+			}
+			continue
+		}
+		if key == "" || key == "-" {
+			continue
+		}
+
+		fns, err := fieldParseFuncs(f)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("error binding field '%s': %w", f.Name, err))
+			continue
+		}
+
+		// A nil default marks the value as required. Registered keys are also
+		// validated with their registered parse funcs, and default to their
+		// registered default.
+		var value interface{}
+		schema, registered := Lookup(key)
+		if registered {
+			value = schema.Default
+			fns = append(fns, schema.Parse...)
+		}
+		if def, ok := f.Tag.Lookup(TagDefault); ok {
+			value = def
+			if def == "" {
+				value = emptyValue(f.Type)
+			}
+		}
+
+		if err := c.Load(key, value, fns...); err != nil {
+			*errs = append(*errs, err)
+			continue
+		}
+		*bindings = append(*bindings, binding{field: rv.Field(i), key: key})
+	}
+}
+
+// emptyValue returns the empty value of slice and map types, or an empty string.
+func emptyValue(t reflect.Type) interface{} {
+	switch t.Kind() {
+	case reflect.Slice:
+		return []string{}
+	case reflect.Map:
+		return map[string]string{}
+	}
+
+	return ""
+}
+
+// setField sets a struct field to a validated config value.
+func (c *Config) setField(field reflect.Value, key string) {
+	if field.Type() == durationType {
+		field.SetInt(int64(c.Duration(key)))
+		return
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(c.String(key))
+	case reflect.Bool:
+		field.SetBool(c.Bool(key))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		field.SetInt(int64(c.Int(key)))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.SetUint(uint64(c.Uint(key)))
+	case reflect.Float32, reflect.Float64:
+		field.SetFloat(c.Float64(key))
+	case reflect.Slice:
+		field.Set(reflect.ValueOf(c.StringSlice(key)).Convert(field.Type()))
+	case reflect.Map:
+		field.Set(reflect.ValueOf(c.StringMap(key)).Convert(field.Type()))
+	}
+}
+
+// fieldParseFuncs returns the ParseFuncs used to validate the value of a field,
+// based on its type and validate tag.
+func fieldParseFuncs(f reflect.StructField) ([]ParseFunc, error) {
+	fns, err := typeParseFuncs(f.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, ok := f.Tag.Lookup(TagValidate)
+	if !ok {
+		return fns, nil
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		fn, err := ruleParseFunc(f.Type, name, arg)
+		if err != nil {
+			return nil, err
+		}
+		fns = append(fns, fn)
+	}
+
+	return fns, nil
+}
+
+// typeParseFuncs returns the ParseFuncs that ensure a value can be converted to
+// a given type. Sized ints are also checked for overflow.
+func typeParseFuncs(t reflect.Type) ([]ParseFunc, error) {
+	if t == durationType {
+		return []ParseFunc{ParseDuration}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return []ParseFunc{ParseString}, nil
+	case reflect.Bool:
+		return []ParseFunc{ParseBool}, nil
+	case reflect.Int, reflect.Int64:
+		return []ParseFunc{ParseInt}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		max := float64(int64(1)<<(t.Bits()-1) - 1)
+		return []ParseFunc{ParseInt, ParseMin(-max - 1), ParseMax(max)}, nil
+	case reflect.Uint, reflect.Uint64:
+		return []ParseFunc{ParseUint}, nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return []ParseFunc{ParseUint, ParseMax(float64(uint64(1)<<t.Bits() - 1))}, nil
+	case reflect.Float32:
+		return []ParseFunc{ParseFloat64, ParseMin(-math.MaxFloat32), ParseMax(math.MaxFloat32)}, nil
+	case reflect.Float64:
+		return []ParseFunc{ParseFloat64}, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			return []ParseFunc{ParseStringSlice}, nil
+		}
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String {
+			return []ParseFunc{ParseStringMap}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported type '%s'", t)
+}
+
+// ruleParseFunc returns the ParseFunc of a validate rule for a given type.
+func ruleParseFunc(t reflect.Type, name, arg string) (ParseFunc, error) {
+	switch name {
+	case "min", "max":
+		if t == durationType {
+			d, err := time.ParseDuration(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s rule '%s': %w", name, arg, err)
+			}
+			if name == "min" {
+				return ParseMinDuration(d), nil
+			}
+			return ParseMaxDuration(d), nil
+		}
+
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s rule '%s': %w", name, arg, err)
+			}
+			if name == "min" {
+				return ParseMin(n), nil
+			}
+			return ParseMax(n), nil
+		}
+	case "oneof":
+		if t.Kind() == reflect.String {
+			return ParseOneOf(strings.Fields(arg)...), nil
+		}
+	case "loglevel":
+		if t.Kind() == reflect.String {
+			return ParseLogLevel, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown validate rule '%s'", name)
+	}
+
+	return nil, fmt.Errorf("validate rule '%s' not supported for type '%s'", name, t)
+}
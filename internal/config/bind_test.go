@@ -0,0 +1,126 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testTLSConfig struct {
+	CA string `plat:"grpc.tls.ca" default:"/path/to/ca"`
+}
+
+type testBindConfig struct {
+	Port       int               `plat:"grpc.server.port" default:"0" validate:"min=1,max=65535"`
+	HTTPPort   uint16            `plat:"http.server.port" default:"8001"`
+	Timeout    time.Duration     `plat:"grpc.server.conn.timeout" default:"10s" validate:"min=1s"`
+	Sample     float64           `plat:"grpc.server.log.sample" default:"1.0" validate:"min=0,max=1"`
+	Reflection bool              `plat:"grpc.server.reflection" default:"false"`
+	Level      string            `plat:"service.log.level" default:"info" validate:"loglevel"`
+	Exporter   string            `plat:"tracing.exporter" default:"none" validate:"oneof=none stdout otlp"`
+	Origins    []string          `plat:"http.cors.origins" default:"a,b"`
+	Identities map[string]string `plat:"grpc.client.server.identities" default:"a=b"`
+	TLS        testTLSConfig
+
+	Ignored string `plat:"-"`
+	private string
+}
+
+func TestBind(t *testing.T) {
+	t.Run("TestValid", func(t *testing.T) {
+		t.Setenv("PLAT_GRPC_SERVER_PORT", "8000")
+		t.Setenv("PLAT_GRPC_TLS_CA", "/env/ca")
+
+		c := New()
+		var conf testBindConfig
+		require.NoError(t, c.Bind(&conf))
+
+		// Assert values are loaded from sources and defaults.
+		assert.Equal(t, testBindConfig{
+			Port:       8000,
+			HTTPPort:   8001,
+			Timeout:    10 * time.Second,
+			Sample:     1.0,
+			Level:      "info",
+			Exporter:   "none",
+			Origins:    []string{"a", "b"},
+			Identities: map[string]string{"a": "b"},
+			TLS:        testTLSConfig{CA: "/env/ca"},
+		}, conf)
+		assert.Equal(t, "8000", c.Get(KeyGrpcServerPort))
+	})
+
+	t.Run("TestInvalid", func(t *testing.T) {
+		t.Setenv("PLAT_GRPC_SERVER_PORT", "70000")
+		t.Setenv("PLAT_HTTP_SERVER_PORT", "-1")
+		t.Setenv("PLAT_GRPC_SERVER_CONN_TIMEOUT", "10ms")
+		t.Setenv("PLAT_TRACING_EXPORTER", "file")
+
+		var conf testBindConfig
+		err := New().Bind(&conf)
+
+		// Assert all errors are returned and no fields are set.
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalidMax)
+		assert.ErrorIs(t, err, ErrInvalidUint)
+		assert.ErrorIs(t, err, ErrInvalidMin)
+		assert.ErrorIs(t, err, ErrInvalidOption)
+		assert.Contains(t, err.Error(), "PLAT_GRPC_SERVER_PORT")
+		assert.Contains(t, err.Error(), "PLAT_HTTP_SERVER_PORT")
+		assert.Contains(t, err.Error(), "PLAT_GRPC_SERVER_CONN_TIMEOUT")
+		assert.Contains(t, err.Error(), "PLAT_TRACING_EXPORTER")
+		assert.Zero(t, conf)
+	})
+
+	t.Run("TestEmptyDefault", func(t *testing.T) {
+		var conf struct {
+			Tokens map[string]string `plat:"ca.bootstrap.tokens" default:""`
+			Names  []string          `plat:"some.names" default:""`
+		}
+		require.NoError(t, New().Bind(&conf))
+		assert.Empty(t, conf.Tokens)
+		assert.Empty(t, conf.Names)
+	})
+
+	t.Run("TestRequired", func(t *testing.T) {
+		var conf struct {
+			Token string `plat:"ca.token"`
+		}
+		assert.ErrorContains(t, New().Bind(&conf), "required config value 'PLAT_CA_TOKEN' not set")
+	})
+
+	t.Run("TestRegistered", func(t *testing.T) {
+		var conf struct {
+			TTL time.Duration `plat:"ca.cert.ttl"`
+		}
+		require.NoError(t, New().Bind(&conf))
+		assert.Equal(t, 24*time.Hour, conf.TTL)
+
+		// Assert registered parse funcs are applied.
+		t.Setenv("PLAT_CA_CERT_TTL", "30s")
+		assert.ErrorIs(t, New().Bind(&conf), ErrInvalidMin)
+	})
+
+	t.Run("TestOverflow", func(t *testing.T) {
+		t.Setenv("PLAT_HTTP_SERVER_PORT", "65536")
+
+		var conf testBindConfig
+		assert.ErrorIs(t, New().Bind(&conf), ErrInvalidMax)
+	})
+
+	t.Run("TestInvalidStruct", func(t *testing.T) {
+		assert.Error(t, New().Bind(testBindConfig{}))
+
+		var unsupported struct {
+			Value []int `plat:"some.key" default:"1"`
+		}
+		assert.ErrorContains(t, New().Bind(&unsupported), "unsupported type")
+
+		var rule struct {
+			Value bool `plat:"some.key" default:"true" validate:"min=1"`
+		}
+		assert.ErrorContains(t, New().Bind(&rule), "not supported")
+	})
+}
@@ -1,25 +1,61 @@
-// Package config provides functions to load config from os env vars
-// with the ability to perform optional validation on the returned values.
+// Package config provides functions to load config from layered sources with
+// the ability to perform optional validation on the returned values.
+//
+// Values are loaded from the highest precedence source in which they are set:
+// command-line flags, then remote values, then env vars, then a config file,
+// then defaults.
 package config
 
 import (
 	"fmt"
-	"os"
+	"reflect"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Config stores config key/values and provides methods
 // for concurrent read/write access.
 type Config struct {
-	values map[string]interface{}
-	mtx    sync.RWMutex
+	values  map[string]interface{}
+	sources map[string]Source
+	// Keys of values resolved from secret references.
+	secrets map[string]bool
+
+	// Values read from a config file, a remote source and command-line flags.
+	file        map[string]interface{}
+	fileModTime time.Time
+	remote      map[string]string
+	flags       map[string]string
+
+	// Modification times of files referenced by secret values.
+	secretFiles map[string]time.Time
+
+	// Defaults and parse funcs of loaded keys, used to reload them.
+	loaders map[string]loader
+
+	// Funcs called when the value of a key changes.
+	watchers map[string][]WatchFunc
+
+	mtx sync.RWMutex
+	// Serializes reloads, so the latest sources are always applied last.
+	reloadMtx sync.Mutex
+}
+
+// loader records how a key was loaded.
+type loader struct {
+	value interface{}
+	fns   []ParseFunc
 }
 
 // New creates a new config with an initialized in memory store.
 func New() *Config {
 	c := &Config{
-		values: make(map[string]interface{}),
+		values:   make(map[string]interface{}),
+		sources:  make(map[string]Source),
+		secrets:  make(map[string]bool),
+		loaders:  make(map[string]loader),
+		watchers: make(map[string][]WatchFunc),
 	}
 
 	return c
@@ -34,36 +70,61 @@ func (c *Config) Get(key string) interface{} {
 	return val
 }
 
-// Set sets a config value by key.
+// Set sets a config value by key at runtime, notifying watchers of the key if
+// the value changed. Values set at runtime are not replaced on reload.
 func (c *Config) Set(key string, value interface{}) {
+	if c.set(key, value, SourceRuntime) {
+		c.notify(key)
+	}
+}
+
+// set sets a config value by key and records its source. It reports whether
+// the value changed.
+func (c *Config) set(key string, value interface{}, source Source) bool {
 	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	old, ok := c.values[key]
 	c.values[key] = value
-	c.mtx.Unlock()
+	c.sources[key] = source
+
+	return !ok || !reflect.DeepEqual(old, value)
 }
 
-// Load attempts to read config values from env vars, setting a default value if not found.
+// Load attempts to read config values from flags, env vars or a config file,
+// setting a default value if not found. Secret references are resolved before
+// the value is validated, and rejected in remote values.
 // If supplied, all parse funcs will be ran against the value and panic on failure.
 func (c *Config) Load(key string, value interface{}, fns ...ParseFunc) error {
-	normKey := normalizeKey(key)
+	// Record the default and parse funcs so the key can be reloaded.
+	c.mtx.Lock()
+	c.loaders[key] = loader{value: value, fns: fns}
+	c.mtx.Unlock()
+
+	// Read value from the highest precedence source.
+	value, source := c.lookup(key, value)
 
-	// Read value from env vars.
-	if env := os.Getenv(normKey); env != "" {
-		value = env
+	if err := checkRemoteSecret(value, source); err != nil {
+		return fmt.Errorf("error resolving config value for '%s': %w", normalizeKey(key), err)
 	}
 
-	// Check for default value.
-	if value == nil || value == "" {
-		return fmt.Errorf("error: required config value '%s' not set", normKey)
+	r := c.secretResolver()
+	value, secret, err := r.resolve(value)
+	if err != nil {
+		return fmt.Errorf("error resolving config value for '%s': %w", normalizeKey(key), err)
 	}
 
-	// Run validate funtions.
-	for _, fn := range fns {
-		if err := fn(value); err != nil {
-			return fmt.Errorf("error validating config value for '%s': %w", normKey, err)
-		}
+	if err := validate(key, value, fns); err != nil {
+		return err
 	}
 
-	c.Set(key, value)
+	// Mark secrets before setting them, so they are never displayed.
+	c.mtx.Lock()
+	c.secrets[key] = secret
+	c.addSecretFiles(r.files)
+	c.mtx.Unlock()
+
+	c.set(key, value, source)
 
 	return nil
 }
@@ -75,6 +136,25 @@ func (c *Config) MustLoad(key string, value interface{}, fns ...ParseFunc) {
 	}
 }
 
+// validate ensures a required value is set and runs all parse funcs against it.
+func validate(key string, value interface{}, fns []ParseFunc) error {
+	normKey := normalizeKey(key)
+
+	// Check for default value.
+	if value == nil || value == "" {
+		return fmt.Errorf("error: required config value '%s' not set", normKey)
+	}
+
+	// Run validate funtions.
+	for _, fn := range fns {
+		if err := fn(value); err != nil {
+			return fmt.Errorf("error validating config value for '%s': %w", normKey, err)
+		}
+	}
+
+	return nil
+}
+
 // normalizeKey transforms a config key into a prefixed env var.
 // For example: log.level becomes PLAT_LOG_LEVEL
 func normalizeKey(key string) string {
@@ -27,6 +27,23 @@ func (c *Config) StringSlice(key string) []string {
 	return []string{}
 }
 
+// StringMap attempts to retrieve a config value as a map of strings, or returns an
+// empty map.
+func (c *Config) StringMap(key string) map[string]string {
+	value := c.Get(key)
+
+	switch t := value.(type) {
+	case map[string]string:
+		return t
+	case string:
+		if m, ok := stringMapValues(t); ok {
+			return m
+		}
+	}
+
+	return map[string]string{}
+}
+
 // Int attempts to retrieve a config value as an int, or returns a zero value.
 func (c *Config) Int(key string) int {
 	value := c.Get(key)
@@ -49,18 +66,31 @@ func (c *Config) Int(key string) int {
 }
 
 // Uint attempts to retrieve a config value as a uint, or returns a zero value.
+// Negative values are treated as invalid.
 func (c *Config) Uint(key string) uint {
 	value := c.Get(key)
 
 	switch t := value.(type) {
 	case uint:
-		return uint(t)
+		return t
 	case uint32:
 		return uint(t)
 	case uint64:
 		return uint(t)
+	case int:
+		if t >= 0 {
+			return uint(t)
+		}
+	case int32:
+		if t >= 0 {
+			return uint(t)
+		}
+	case int64:
+		if t >= 0 {
+			return uint(t)
+		}
 	case string:
-		val, err := strconv.Atoi(t)
+		val, err := strconv.ParseUint(t, 10, 0)
 		if err == nil {
 			return uint(val)
 		}
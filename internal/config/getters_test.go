@@ -21,6 +21,11 @@ func TestMain(m *testing.M) {
 	testGetterConfig.Set("stringslice1", []string{"a", "b", "c"})
 	testGetterConfig.Set("stringslice2", "d,e,f")
 
+	// Set string map values
+	testGetterConfig.Set("stringmap1", map[string]string{"a": "b"})
+	testGetterConfig.Set("stringmap2", "a = b, c=d=e")
+	testGetterConfig.Set("stringmap3", "a")
+
 	// Set int/uint values
 	testGetterConfig.Set("intstring", "1")
 	testGetterConfig.Set("uintstring", "1")
@@ -30,6 +35,8 @@ func TestMain(m *testing.M) {
 	testGetterConfig.Set("uint32", uint32(1))
 	testGetterConfig.Set("int64", int64(1))
 	testGetterConfig.Set("uint64", uint64(1))
+	testGetterConfig.Set("negativestring", "-1")
+	testGetterConfig.Set("negative", -1)
 
 	// Set float64 values
 	testGetterConfig.Set("floatstring", "10.00")
@@ -79,6 +86,25 @@ func TestConfigStringSlice(t *testing.T) {
 
 }
 
+func TestConfigStringMap(t *testing.T) {
+	t.Parallel()
+
+	// Assert that a not found key is empty.
+	m := testGetterConfig.StringMap("not_found")
+	assert.Empty(t, m)
+
+	// Assert maps and key=value strings return the expected values.
+	m = testGetterConfig.StringMap("stringmap1")
+	assert.Equal(t, map[string]string{"a": "b"}, m)
+
+	m = testGetterConfig.StringMap("stringmap2")
+	assert.Equal(t, map[string]string{"a": "b", "c": "d=e"}, m)
+
+	// Assert that an invalid map is empty.
+	m = testGetterConfig.StringMap("stringmap3")
+	assert.Empty(t, m)
+}
+
 func TestConfigInt(t *testing.T) {
 	t.Parallel()
 
@@ -113,12 +139,17 @@ func TestConfigUint(t *testing.T) {
 		"uint",
 		"uint32",
 		"uint64",
+		"int",
 	}
 
 	for _, in := range ints {
 		i := testGetterConfig.Uint(in)
 		assert.Equal(t, uint(1), i)
 	}
+
+	// Assert that negative values are zero.
+	assert.Zero(t, testGetterConfig.Uint("negativestring"))
+	assert.Zero(t, testGetterConfig.Uint("negative"))
 }
 
 func TestConfigFloat64(t *testing.T) {
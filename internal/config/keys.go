@@ -1,30 +1,185 @@
 package config
 
+import "time"
+
 const (
+	// Config file config. The file can only be set by the PLAT_CONFIG_FILE env
+	// var or --config flag.
+	KeyConfigFile           = "config.file"
+	KeyConfigReloadInterval = "config.reload.interval"
+	KeyConfigRemoteEnabled  = "config.remote.enabled"
+	KeyConfigSecretKeyFile  = "config.secret.key.file"
+
 	// Service config.
-	KeyServiceLogLevel         = "service.log.level"
-	KeyServiceShutdownTimeout  = "service.shutdown.timeout"
-	KeyServiceDiscoveryEnabled = "service.discovery.enabled"
-	KeyServiceDiscoveryAddr    = "service.discovery.addr"
-	KeyServiceRegisterInt      = "service.register.interval"
+	KeyServiceLogLevel          = "service.log.level"
+	KeyServiceShutdownTimeout   = "service.shutdown.timeout"
+	KeyServiceShutdownDrain     = "service.shutdown.drain"
+	KeyServiceDiscoveryEnabled  = "service.discovery.enabled"
+	KeyServiceDiscoveryAddr     = "service.discovery.addr"
+	KeyServiceRegisterInt       = "service.register.interval"
+	KeyServiceRegisterAddr      = "service.register.addr"
+	KeyServiceRestartIntensity  = "service.restart.intensity"
+	KeyServiceRestartPeriod     = "service.restart.period"
+	KeyServiceRestartBackoff    = "service.restart.backoff"
+	KeyServiceRestartBackoffMax = "service.restart.backoff.max"
+	KeyServiceDependencyTimeout = "service.dependency.timeout"
 
 	// HTTPS/S server config.
 	KeyHttpServerPort   = "http.server.port"
 	KeyHttpReadTimeout  = "http.read.timeout"
 	KeyHttpWriteTimeout = "http.write.timeout"
 	KeyHttpIdleTimeout  = "http.idle.timeout"
+	KeyHttpServerTLS    = "http.server.tls"
+	KeyHttpPprofLocal   = "http.pprof.localhost"
+	KeyHttpPprofPort    = "http.pprof.port"
 
 	// gRPC TLS config.
-	KeyGrpcTLSCA = "grpc.tls.ca"
+	KeyGrpcTLSCA             = "grpc.tls.ca"
+	KeyGrpcTLSReloadInterval = "grpc.tls.reload.interval"
 
 	// gRPC server config.
 	KeyGrpcServerPort        = "grpc.server.port"
 	KeyGrpcServerCert        = "grpc.server.cert"
 	KeyGrpcServerKey         = "grpc.server.key"
 	KeyGrpcServerConnTimeout = "grpc.server.conn.timeout"
+	KeyGrpcServerReflection  = "grpc.server.reflection"
+	KeyGrpcServerStopTimeout = "grpc.server.stop.timeout"
+	KeyGrpcServerLogSample   = "grpc.server.log.sample"
+	KeyGrpcServerAuthz       = "grpc.server.authz"
+	KeyGrpcServerAuthzPolicy = "grpc.server.authz.policy"
+	KeyGrpcServerTokenReq    = "grpc.server.token.required"
 
 	// gRPC client config.
-	KeyGrpcClientCert    = "grpc.client.cert"
-	KeyGrpcClientKey     = "grpc.client.key"
-	KeyGrpcClientTimeout = "grpc.client.timeout"
+	KeyGrpcClientCert         = "grpc.client.cert"
+	KeyGrpcClientKey          = "grpc.client.key"
+	KeyGrpcClientTimeout      = "grpc.client.timeout"
+	KeyGrpcClientMaxAttempts  = "grpc.client.max.attempts"
+	KeyGrpcClientHedgingDelay = "grpc.client.hedging.delay"
+	KeyGrpcClientIdentities   = "grpc.client.server.identities"
+	KeyGrpcClientTokenTTL     = "grpc.client.token.ttl"
+
+	// Discovery server config.
+	KeyDiscoveryEvictionWindow   = "discovery.eviction.window"
+	KeyDiscoveryEvictionInterval = "discovery.eviction.interval"
+
+	// Traffic generation config.
+	KeyTrafficEventInterval = "traffic.event.interval"
+
+	// Certificate authority config.
+	KeyCAEnabled         = "ca.enabled"
+	KeyCAAddr            = "ca.addr"
+	KeyCAToken           = "ca.token"
+	KeyCACert            = "ca.cert"
+	KeyCAKey             = "ca.key"
+	KeyCACertTTL         = "ca.cert.ttl"
+	KeyCATrustDomain     = "ca.trust.domain"
+	KeyCABootstrapTokens = "ca.bootstrap.tokens"
+
+	// Tracing config.
+	KeyTracingExporter     = "tracing.exporter"
+	KeyTracingOtlpEndpoint = "tracing.otlp.endpoint"
+	KeyTracingOtlpInsecure = "tracing.otlp.insecure"
+	KeyTracingFile         = "tracing.file"
+	KeyTracingSampleRatio  = "tracing.sample.ratio"
+)
+
+// HTTP server TLS modes.
+const (
+	HttpTLSNone = "none"
+	HttpTLS     = "tls"
+	HttpMTLS    = "mtls"
+)
+
+// Subsystems that own config keys.
+const (
+	SubsystemConfig    = "config"
+	SubsystemService   = "service"
+	SubsystemHTTP      = "http"
+	SubsystemGrpc      = "grpc"
+	SubsystemDiscovery = "discovery"
+	SubsystemTraffic   = "traffic"
+	SubsystemCA        = "ca"
+	SubsystemTracing   = "tracing"
 )
+
+func init() {
+	Register(
+		// Config file config.
+		Schema{Key: KeyConfigFile, Type: TypeString, Default: "", Subsystem: SubsystemConfig, Description: "Path to a YAML, JSON or TOML config file. Only set by env var or --config flag."},
+		Schema{Key: KeyConfigReloadInterval, Type: TypeDuration, Default: "30s", Parse: []ParseFunc{ParseDuration}, Subsystem: SubsystemConfig, Description: "How often config and secret files are checked for changes, or 0 to disable."},
+		Schema{Key: KeyConfigRemoteEnabled, Type: TypeBool, Default: false, Parse: []ParseFunc{ParseBool}, Subsystem: SubsystemConfig, Description: "Watch remote config values from the discovery KV store."},
+		Schema{Key: KeyConfigSecretKeyFile, Type: TypeString, Default: DefaultSecretKeyFile, Parse: []ParseFunc{ParseString}, Subsystem: SubsystemConfig, Description: "Path to the base64 encoded AES-256 key used to decrypt enc:// secrets."},
+
+		// Service config.
+		Schema{Key: KeyServiceLogLevel, Type: TypeString, Default: "info", Parse: []ParseFunc{ParseLogLevel}, Remote: true, Subsystem: SubsystemService, Description: "Minimum log level."},
+		Schema{Key: KeyServiceShutdownTimeout, Type: TypeDuration, Default: "10s", Parse: []ParseFunc{ParseDuration}, Remote: true, Subsystem: SubsystemService, Description: "Maximum time to wait for a graceful shutdown."},
+		Schema{Key: KeyServiceShutdownDrain, Type: TypeDuration, Default: "5s", Parse: []ParseFunc{ParseDuration}, Remote: true, Subsystem: SubsystemService, Description: "Time to wait after deregistering on shutdown, so clients stop routing requests before servers stop."},
+		Schema{Key: KeyServiceDiscoveryEnabled, Type: TypeBool, Default: true, Parse: []ParseFunc{ParseBool}, Subsystem: SubsystemService, Description: "Register the service for discovery."},
+		Schema{Key: KeyServiceDiscoveryAddr, Type: TypeString, Default: "discoveryd:8000", Parse: []ParseFunc{ParseString}, Subsystem: SubsystemService, Description: "Address of the discovery service."},
+		Schema{Key: KeyServiceRegisterInt, Type: TypeDuration, Default: "300s", Parse: []ParseFunc{ParseDuration}, Remote: true, Subsystem: SubsystemService, Description: "How often the service re-registers for discovery, or 0 to register once."},
+		Schema{Key: KeyServiceRegisterAddr, Type: TypeString, Default: "", Parse: []ParseFunc{ParseString}, Subsystem: SubsystemService, Description: "Address registered for discovery, defaults to the service name."},
+		Schema{Key: KeyServiceRestartIntensity, Type: TypeInt, Default: 5, Parse: []ParseFunc{ParseInt}, Remote: true, Subsystem: SubsystemService, Description: "Maximum component restarts within the restart period before the service exits."},
+		Schema{Key: KeyServiceRestartPeriod, Type: TypeDuration, Default: "1m", Parse: []ParseFunc{ParseDuration}, Remote: true, Subsystem: SubsystemService, Description: "Period over which component restarts are counted."},
+		Schema{Key: KeyServiceRestartBackoff, Type: TypeDuration, Default: "1s", Parse: []ParseFunc{ParseDuration}, Remote: true, Subsystem: SubsystemService, Description: "Initial delay before restarting a failed component, doubled on each consecutive failure."},
+		Schema{Key: KeyServiceRestartBackoffMax, Type: TypeDuration, Default: "30s", Parse: []ParseFunc{ParseDuration}, Remote: true, Subsystem: SubsystemService, Description: "Maximum delay before restarting a failed component."},
+		Schema{Key: KeyServiceDependencyTimeout, Type: TypeDuration, Default: "2m", Parse: []ParseFunc{ParsePositiveDuration}, Remote: true, Subsystem: SubsystemService, Description: "Maximum time to wait at startup for dependencies to be registered for discovery."},
+
+		// HTTP server config.
+		Schema{Key: KeyHttpServerPort, Type: TypeInt, Default: 0, Parse: []ParseFunc{ParseInt}, Subsystem: SubsystemHTTP, Description: "HTTP server port, or 0 for a random port."},
+		Schema{Key: KeyHttpReadTimeout, Type: TypeDuration, Default: "10s", Parse: []ParseFunc{ParseDuration}, Remote: true, Subsystem: SubsystemHTTP, Description: "Maximum time to read an HTTP request."},
+		Schema{Key: KeyHttpWriteTimeout, Type: TypeDuration, Default: "10s", Parse: []ParseFunc{ParseDuration}, Remote: true, Subsystem: SubsystemHTTP, Description: "Maximum time to write an HTTP response."},
+		Schema{Key: KeyHttpIdleTimeout, Type: TypeDuration, Default: "10s", Parse: []ParseFunc{ParseDuration}, Remote: true, Subsystem: SubsystemHTTP, Description: "Maximum time to wait for the next request on a keep-alive connection."},
+		Schema{Key: KeyHttpServerTLS, Type: TypeString, Default: HttpTLSNone, Parse: []ParseFunc{ParseOneOf(HttpTLSNone, HttpTLS, HttpMTLS)}, Subsystem: SubsystemHTTP, Description: "Serve HTTP over TLS with the gRPC server cert, one of: none, tls, mtls (client certs required)."},
+		Schema{Key: KeyHttpPprofLocal, Type: TypeBool, Default: false, Parse: []ParseFunc{ParseBool}, Subsystem: SubsystemHTTP, Description: "Serve pprof endpoints on a separate plain HTTP server bound to localhost only."},
+		Schema{Key: KeyHttpPprofPort, Type: TypeInt, Default: 0, Parse: []ParseFunc{ParseInt}, Subsystem: SubsystemHTTP, Description: "Localhost pprof server port, or 0 for a random port."},
+
+		// gRPC TLS config.
+		Schema{Key: KeyGrpcTLSCA, Type: TypeString, Default: "/usr/local/share/ca-certificates/ca.crt.pem", Parse: []ParseFunc{ParseString}, Subsystem: SubsystemGrpc, Description: "Path to the CA cert used to verify peers."},
+		Schema{Key: KeyGrpcTLSReloadInterval, Type: TypeDuration, Default: "1m", Parse: []ParseFunc{ParseDuration}, Subsystem: SubsystemGrpc, Description: "How often TLS certs are reloaded from disk, or 0 to disable. Certs issued by the CA are renewed independently."},
+
+		// gRPC server config.
+		Schema{Key: KeyGrpcServerPort, Type: TypeInt, Default: 0, Parse: []ParseFunc{ParseInt}, Subsystem: SubsystemGrpc, Description: "gRPC server port, or 0 for a random port."},
+		Schema{Key: KeyGrpcServerCert, Type: TypeString, Default: "/usr/local/share/ca-certificates/server.crt.pem", Parse: []ParseFunc{ParseString}, Subsystem: SubsystemGrpc, Description: "Path to the gRPC server cert."},
+		Schema{Key: KeyGrpcServerKey, Type: TypeString, Default: "/usr/local/share/ca-certificates/server.key.pem", Parse: []ParseFunc{ParseString}, Subsystem: SubsystemGrpc, Description: "Path to the gRPC server key."},
+		Schema{Key: KeyGrpcServerConnTimeout, Type: TypeDuration, Default: "10s", Parse: []ParseFunc{ParseDuration}, Remote: true, Subsystem: SubsystemGrpc, Description: "Maximum time to establish a gRPC connection."},
+		Schema{Key: KeyGrpcServerReflection, Type: TypeBool, Default: false, Parse: []ParseFunc{ParseBool}, Subsystem: SubsystemGrpc, Description: "Enable gRPC server reflection."},
+		Schema{Key: KeyGrpcServerStopTimeout, Type: TypeDuration, Default: "5s", Parse: []ParseFunc{ParseDuration}, Remote: true, Subsystem: SubsystemGrpc, Description: "Maximum time to wait for gRPC calls to complete on shutdown before forcing the server to stop."},
+		Schema{Key: KeyGrpcServerLogSample, Type: TypeFloat, Default: 1.0, Parse: []ParseFunc{ParseFloat64}, Remote: true, Subsystem: SubsystemGrpc, Description: "Ratio of successful gRPC calls that are logged."},
+		Schema{Key: KeyGrpcServerAuthz, Type: TypeBool, Default: false, Parse: []ParseFunc{ParseBool}, Subsystem: SubsystemGrpc, Description: "Authorize gRPC calls with the authz policy."},
+		Schema{Key: KeyGrpcServerAuthzPolicy, Type: TypeString, Default: "/etc/platform/authz.json", Parse: []ParseFunc{ParseString}, Subsystem: SubsystemGrpc, Description: "Path to the gRPC authz policy."},
+		Schema{Key: KeyGrpcServerTokenReq, Type: TypeBool, Default: false, Parse: []ParseFunc{ParseBool}, Subsystem: SubsystemGrpc, Description: "Require signed bearer tokens on gRPC calls."},
+
+		// gRPC client config.
+		Schema{Key: KeyGrpcClientCert, Type: TypeString, Default: "/usr/local/share/ca-certificates/client.crt.pem", Parse: []ParseFunc{ParseString}, Subsystem: SubsystemGrpc, Description: "Path to the gRPC client cert."},
+		Schema{Key: KeyGrpcClientKey, Type: TypeString, Default: "/usr/local/share/ca-certificates/client.key.pem", Parse: []ParseFunc{ParseString}, Subsystem: SubsystemGrpc, Description: "Path to the gRPC client key."},
+		Schema{Key: KeyGrpcClientTimeout, Type: TypeDuration, Default: "10s", Parse: []ParseFunc{ParseDuration}, Remote: true, Subsystem: SubsystemGrpc, Description: "Default deadline of gRPC calls."},
+		Schema{Key: KeyGrpcClientMaxAttempts, Type: TypeInt, Default: 3, Parse: []ParseFunc{ParseInt}, Remote: true, Subsystem: SubsystemGrpc, Description: "Maximum attempts of retried or hedged gRPC calls."},
+		Schema{Key: KeyGrpcClientHedgingDelay, Type: TypeDuration, Default: "100ms", Parse: []ParseFunc{ParseDuration}, Remote: true, Subsystem: SubsystemGrpc, Description: "Delay between hedged gRPC call attempts."},
+		Schema{Key: KeyGrpcClientIdentities, Type: TypeStringMap, Default: map[string]string{}, Parse: []ParseFunc{ParseStringMap}, Subsystem: SubsystemGrpc, Description: "Comma separated target=identity pairs servers must present."},
+		Schema{Key: KeyGrpcClientTokenTTL, Type: TypeDuration, Default: "5m", Parse: []ParseFunc{ParseDuration}, Subsystem: SubsystemGrpc, Description: "Lifetime of signed bearer tokens."},
+
+		// Discovery server config.
+		Schema{Key: KeyDiscoveryEvictionWindow, Type: TypeDuration, Default: "5m", Parse: []ParseFunc{ParsePositiveDuration}, Remote: true, Subsystem: SubsystemDiscovery, Description: "Time after which services that haven't re-registered are evicted."},
+		Schema{Key: KeyDiscoveryEvictionInterval, Type: TypeDuration, Default: "1m", Parse: []ParseFunc{ParsePositiveDuration}, Remote: true, Subsystem: SubsystemDiscovery, Description: "How often expired services are evicted."},
+
+		// Traffic generation config.
+		Schema{Key: KeyTrafficEventInterval, Type: TypeDuration, Default: "10s", Parse: []ParseFunc{ParsePositiveDuration}, Remote: true, Subsystem: SubsystemTraffic, Description: "How often test events are sent."},
+
+		// Certificate authority config.
+		Schema{Key: KeyCAEnabled, Type: TypeBool, Default: false, Parse: []ParseFunc{ParseBool}, Subsystem: SubsystemCA, Description: "Request gRPC certs from the platform CA."},
+		Schema{Key: KeyCAAddr, Type: TypeString, Default: "cad:8005", Parse: []ParseFunc{ParseString}, Subsystem: SubsystemCA, Description: "Address of the platform CA."},
+		Schema{Key: KeyCAToken, Type: TypeString, Parse: []ParseFunc{ParseString}, Subsystem: SubsystemCA, Description: "Bootstrap token of the service, required if the CA is enabled."},
+		Schema{Key: KeyCACert, Type: TypeString, Default: "/usr/local/share/ca-certificates/ca.crt.pem", Parse: []ParseFunc{ParseString}, Subsystem: SubsystemCA, Description: "Path to the CA cert used to sign certs."},
+		Schema{Key: KeyCAKey, Type: TypeString, Default: "/usr/local/share/ca-certificates/ca.key.pem", Parse: []ParseFunc{ParseString}, Subsystem: SubsystemCA, Description: "Path to the CA key used to sign certs."},
+		Schema{Key: KeyCACertTTL, Type: TypeDuration, Default: "24h", Parse: []ParseFunc{ParseDuration, ParseMinDuration(time.Minute)}, Subsystem: SubsystemCA, Description: "Lifetime of issued certs."},
+		Schema{Key: KeyCATrustDomain, Type: TypeString, Default: "platform", Parse: []ParseFunc{ParseString}, Subsystem: SubsystemCA, Description: "SPIFFE trust domain of issued certs."},
+		Schema{Key: KeyCABootstrapTokens, Type: TypeStringMap, Default: map[string]string{}, Parse: []ParseFunc{ParseStringMap}, Subsystem: SubsystemCA, Description: "Comma separated token=service pairs of bootstrap tokens, valid for every instance of the service."},
+
+		// Tracing config.
+		Schema{Key: KeyTracingExporter, Type: TypeString, Default: "none", Parse: []ParseFunc{ParseOneOf("none", "otlp", "stdout", "file")}, Subsystem: SubsystemTracing, Description: "Trace exporter, one of: none, otlp, stdout, file."},
+		Schema{Key: KeyTracingOtlpEndpoint, Type: TypeString, Default: "localhost:4317", Parse: []ParseFunc{ParseString}, Subsystem: SubsystemTracing, Description: "OTLP collector endpoint."},
+		Schema{Key: KeyTracingOtlpInsecure, Type: TypeBool, Default: true, Parse: []ParseFunc{ParseBool}, Subsystem: SubsystemTracing, Description: "Connect to the OTLP collector without TLS."},
+		Schema{Key: KeyTracingFile, Type: TypeString, Default: "traces.json", Parse: []ParseFunc{ParseString}, Subsystem: SubsystemTracing, Description: "Path traces are written to by the file exporter."},
+		Schema{Key: KeyTracingSampleRatio, Type: TypeFloat, Default: 1.0, Parse: []ParseFunc{ParseFloat64}, Remote: true, Subsystem: SubsystemTracing, Description: "Ratio of traces that are sampled."},
+	)
+}
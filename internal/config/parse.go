@@ -12,11 +12,17 @@ import (
 var (
 	ErrInvalidString      = errors.New("value must be a string")
 	ErrInvalidStringSlice = errors.New("value must be a slice of strings")
+	ErrInvalidStringMap   = errors.New("value must be a map of key=value strings")
 	ErrInvalidInt         = errors.New("value must be an integer")
+	ErrInvalidUint        = errors.New("value must be a non-negative integer")
 	ErrInvalidFloat64     = errors.New("value must be a float64")
 	ErrInvalidBool        = errors.New("value must be a boolean")
 	ErrInvalidDuration    = errors.New("value must be a duration with a time unit")
+	ErrInvalidPosDuration = errors.New("value must be a positive duration with a time unit")
 	ErrInvalidLogLevel    = errors.New("value must be a log level")
+	ErrInvalidOption      = errors.New("value must be one of the allowed options")
+	ErrInvalidMin         = errors.New("value must not be less than the minimum")
+	ErrInvalidMax         = errors.New("value must not be greater than the maximum")
 )
 
 // ParseFunc can be used to validate a given configuration value.
@@ -43,6 +49,21 @@ func ParseStringSlice(value interface{}) error {
 	return ErrInvalidStringSlice
 }
 
+// ParseStringMap ensures that a value is a comma delimited slice of key=value
+// strings. An empty value is an empty map.
+func ParseStringMap(value interface{}) error {
+	switch t := value.(type) {
+	case map[string]string:
+		return nil
+	case string:
+		if _, ok := stringMapValues(t); ok {
+			return nil
+		}
+	}
+
+	return ErrInvalidStringMap
+}
+
 // ParseInt ensures that a value is an int.
 func ParseInt(value interface{}) error {
 	switch t := value.(type) {
@@ -57,6 +78,24 @@ func ParseInt(value interface{}) error {
 	return ErrInvalidInt
 }
 
+// ParseUint ensures that a value is a non-negative int.
+func ParseUint(value interface{}) error {
+	switch t := value.(type) {
+	case uint, uint32, uint64:
+		return nil
+	case int, int32, int64:
+		if n, _ := strconv.ParseInt(stringValue(t), 10, 64); n >= 0 {
+			return nil
+		}
+	case string:
+		if _, err := strconv.ParseUint(t, 10, 0); err == nil {
+			return nil
+		}
+	}
+
+	return ErrInvalidUint
+}
+
 // ParseFloat64 ensures that a value is a float64.
 func ParseFloat64(value interface{}) error {
 	if _, err := strconv.ParseFloat(stringValue(value), 64); err != nil {
@@ -86,6 +125,15 @@ func ParseDuration(value interface{}) error {
 	return nil
 }
 
+// ParsePositiveDuration ensures that a value is a valid duration greater than zero.
+func ParsePositiveDuration(value interface{}) error {
+	if d, err := time.ParseDuration(stringValue(value)); err != nil || d <= 0 {
+		return ErrInvalidPosDuration
+	}
+
+	return nil
+}
+
 // ParseLogLevel validates that the value is a valid log level.
 // Valid log levels are one of: trace, debug, info, warn, error, fatal
 func ParseLogLevel(value interface{}) error {
@@ -97,6 +145,81 @@ func ParseLogLevel(value interface{}) error {
 	return ErrInvalidLogLevel
 }
 
+// ParseOneOf returns a ParseFunc that ensures a value is one of the given options.
+// Options are compared case-insensitively.
+func ParseOneOf(options ...string) ParseFunc {
+	return func(value interface{}) error {
+		v := stringValue(value)
+		for _, opt := range options {
+			if strings.EqualFold(v, opt) {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%w: %s", ErrInvalidOption, strings.Join(options, ", "))
+	}
+}
+
+// ParseMin returns a ParseFunc that ensures a numeric value is not less than min.
+func ParseMin(min float64) ParseFunc {
+	return func(value interface{}) error {
+		v, err := strconv.ParseFloat(stringValue(value), 64)
+		if err != nil {
+			return ErrInvalidFloat64
+		}
+		if v < min {
+			return fmt.Errorf("%w: %v", ErrInvalidMin, min)
+		}
+
+		return nil
+	}
+}
+
+// ParseMax returns a ParseFunc that ensures a numeric value is not greater than max.
+func ParseMax(max float64) ParseFunc {
+	return func(value interface{}) error {
+		v, err := strconv.ParseFloat(stringValue(value), 64)
+		if err != nil {
+			return ErrInvalidFloat64
+		}
+		if v > max {
+			return fmt.Errorf("%w: %v", ErrInvalidMax, max)
+		}
+
+		return nil
+	}
+}
+
+// ParseMinDuration returns a ParseFunc that ensures a duration is not less than min.
+func ParseMinDuration(min time.Duration) ParseFunc {
+	return func(value interface{}) error {
+		d, err := time.ParseDuration(stringValue(value))
+		if err != nil {
+			return ErrInvalidDuration
+		}
+		if d < min {
+			return fmt.Errorf("%w: %s", ErrInvalidMin, min)
+		}
+
+		return nil
+	}
+}
+
+// ParseMaxDuration returns a ParseFunc that ensures a duration is not greater than max.
+func ParseMaxDuration(max time.Duration) ParseFunc {
+	return func(value interface{}) error {
+		d, err := time.ParseDuration(stringValue(value))
+		if err != nil {
+			return ErrInvalidDuration
+		}
+		if d > max {
+			return fmt.Errorf("%w: %s", ErrInvalidMax, max)
+		}
+
+		return nil
+	}
+}
+
 func stringValue(value interface{}) string {
 	switch t := value.(type) {
 	case string:
@@ -123,3 +246,18 @@ func stringSliceValues(s string) []string {
 
 	return vals
 }
+
+// stringMapValues splits a string on commas and each segment on the first
+// equals sign. Keys and values are trimmed and keys must not be empty.
+func stringMapValues(s string) (map[string]string, bool) {
+	m := make(map[string]string)
+	for _, kv := range stringSliceValues(s) {
+		key, val, ok := strings.Cut(kv, "=")
+		if key = strings.TrimSpace(key); !ok || key == "" {
+			return nil, false
+		}
+		m[key] = strings.TrimSpace(val)
+	}
+
+	return m, true
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -33,6 +34,22 @@ func TestParseStringSlice(t *testing.T) {
 	assert.ErrorIs(t, err, nil)
 }
 
+func TestParseStringMap(t *testing.T) {
+	t.Parallel()
+
+	// Assert invalid string maps return an error.
+	for _, value := range []interface{}{1, "a", "=b", "a=b,c"} {
+		err := ParseStringMap(value)
+		assert.ErrorIs(t, err, ErrInvalidStringMap)
+	}
+
+	// Assert valid string maps do not return an error.
+	for _, value := range []interface{}{"a=b,c=d", map[string]string{"a": "b"}} {
+		err := ParseStringMap(value)
+		assert.ErrorIs(t, err, nil)
+	}
+}
+
 func TestParseInt(t *testing.T) {
 	t.Parallel()
 
@@ -124,6 +141,22 @@ func TestParseDuration(t *testing.T) {
 	}
 }
 
+func TestParsePositiveDuration(t *testing.T) {
+	t.Parallel()
+
+	// Assert invalid and non-positive durations return an error.
+	for _, dur := range []interface{}{"invalid", "0s", "-1m", time.Duration(0)} {
+		err := ParsePositiveDuration(dur)
+		assert.ErrorIs(t, err, ErrInvalidPosDuration)
+	}
+
+	// Assert positive durations return no error.
+	for _, dur := range []interface{}{"10s", 5 * time.Minute} {
+		err := ParsePositiveDuration(dur)
+		assert.ErrorIs(t, err, nil)
+	}
+}
+
 func TestParseLogLevel(t *testing.T) {
 	t.Parallel()
 
@@ -146,3 +179,49 @@ func TestParseLogLevel(t *testing.T) {
 		assert.ErrorIs(t, err, nil)
 	}
 }
+
+func TestParseOneOf(t *testing.T) {
+	t.Parallel()
+
+	parse := ParseOneOf("none", "otlp")
+
+	// Assert unknown options return an error.
+	err := parse("invalid")
+	assert.ErrorIs(t, err, ErrInvalidOption)
+
+	// Assert options are matched case-insensitively.
+	assert.NoError(t, parse("none"))
+	assert.NoError(t, parse("OTLP"))
+}
+
+func TestParseUint(t *testing.T) {
+	t.Parallel()
+
+	// Assert negative and invalid ints return an error.
+	for _, value := range []interface{}{"-1", -1, "a", 1.5} {
+		assert.ErrorIs(t, ParseUint(value), ErrInvalidUint)
+	}
+
+	// Assert non-negative ints return no error.
+	for _, value := range []interface{}{"0", 1, uint(1), int64(1)} {
+		assert.ErrorIs(t, ParseUint(value), nil)
+	}
+}
+
+func TestParseMinMax(t *testing.T) {
+	t.Parallel()
+
+	// Assert numeric values are compared with the bounds.
+	assert.ErrorIs(t, ParseMin(1)("0"), ErrInvalidMin)
+	assert.ErrorIs(t, ParseMin(1)(1), nil)
+	assert.ErrorIs(t, ParseMax(1)("1.5"), ErrInvalidMax)
+	assert.ErrorIs(t, ParseMax(1)(0.5), nil)
+	assert.ErrorIs(t, ParseMin(1)("a"), ErrInvalidFloat64)
+
+	// Assert durations are compared with the bounds.
+	assert.ErrorIs(t, ParseMinDuration(time.Second)("500ms"), ErrInvalidMin)
+	assert.ErrorIs(t, ParseMinDuration(time.Second)(time.Minute), nil)
+	assert.ErrorIs(t, ParseMaxDuration(time.Second)("1m"), ErrInvalidMax)
+	assert.ErrorIs(t, ParseMaxDuration(time.Second)("1s"), nil)
+	assert.ErrorIs(t, ParseMaxDuration(time.Second)("a"), ErrInvalidDuration)
+}
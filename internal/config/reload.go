@@ -0,0 +1,191 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// WatchFunc is called with the key of a config value after it changes.
+type WatchFunc func(key string)
+
+// Watch registers a func to be called when the value of a key changes, either
+// on reload or when set at runtime. Funcs are called synchronously and should
+// not block.
+func (c *Config) Watch(key string, fn WatchFunc) {
+	c.mtx.Lock()
+	c.watchers[key] = append(c.watchers[key], fn)
+	c.mtx.Unlock()
+}
+
+// Watched reports whether any funcs are watching a key, i.e. whether changes
+// to its value are applied without a restart.
+func (c *Config) Watched(key string) bool {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return len(c.watchers[key]) > 0
+}
+
+// Reload re-reads the config file, if set, and reloads all previously loaded
+// keys from their sources, resolving secret references again and validating
+// each with the parse funcs it was loaded with. If any value is invalid, all errors are returned and the current config
+// is left unchanged until the file is modified again. Values set at runtime are
+// not replaced.
+//
+// It returns the sorted keys of all changed values, after notifying watchers.
+func (c *Config) Reload() ([]string, error) {
+	c.reloadMtx.Lock()
+	defer c.reloadMtx.Unlock()
+
+	c.mtx.RLock()
+	path, _ := c.values[KeyConfigFile].(string)
+	file, remote, flags := c.file, c.remote, c.flags
+	loaders := make(map[string]loader, len(c.loaders))
+	for key, l := range c.loaders {
+		loaders[key] = l
+	}
+	c.mtx.RUnlock()
+
+	var modTime time.Time
+	if path != "" {
+		var err error
+		if file, modTime, err = readFile(path); err != nil {
+			c.setFileModTime(modTime)
+			return nil, err
+		}
+	}
+
+	// Validate all values before applying any of them.
+	keys := make([]string, 0, len(loaders))
+	for key := range loaders {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	r := newSecretResolver(file, flags)
+	values := make(map[string]interface{}, len(keys))
+	sources := make(map[string]Source, len(keys))
+	secrets := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		l := loaders[key]
+		value, source := lookup(file, remote, flags, key, l.value)
+		if err := checkRemoteSecret(value, source); err != nil {
+			errs = append(errs, fmt.Errorf("error resolving config value for '%s': %w", normalizeKey(key), err))
+			continue
+		}
+		value, secret, err := r.resolve(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error resolving config value for '%s': %w", normalizeKey(key), err))
+			continue
+		}
+		if err := validate(key, value, l.fns); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		values[key], sources[key], secrets[key] = value, source, secret
+	}
+	if err := errors.Join(errs...); err != nil {
+		c.setFileModTime(modTime)
+		c.mtx.Lock()
+		c.addSecretFiles(r.files)
+		c.mtx.Unlock()
+		return nil, fmt.Errorf("error reloading config: %w", err)
+	}
+
+	var changed []string
+	c.mtx.Lock()
+	if path != "" {
+		c.file, c.fileModTime = file, modTime
+	}
+	c.secretFiles = r.files
+	for _, key := range keys {
+		if c.sources[key] == SourceRuntime {
+			continue
+		}
+
+		c.sources[key], c.secrets[key] = sources[key], secrets[key]
+		if !reflect.DeepEqual(c.values[key], values[key]) {
+			c.values[key] = values[key]
+			changed = append(changed, key)
+		}
+	}
+	c.mtx.Unlock()
+
+	c.notify(changed...)
+
+	return changed, nil
+}
+
+// FileModified reports whether the config file, or any file referenced by a
+// secret value, has been modified since it was last read. It returns false if
+// no files are set.
+func (c *Config) FileModified() (bool, error) {
+	c.mtx.RLock()
+	path, _ := c.values[KeyConfigFile].(string)
+	modTime := c.fileModTime
+	secretFiles := make(map[string]time.Time, len(c.secretFiles))
+	for p, t := range c.secretFiles {
+		secretFiles[p] = t
+	}
+	c.mtx.RUnlock()
+
+	// Secret files that can't be read are reported when next reloaded.
+	for p, t := range secretFiles {
+		if info, err := os.Stat(p); err == nil && !info.ModTime().Equal(t) {
+			return true, nil
+		}
+	}
+
+	if path == "" {
+		return false, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	return !info.ModTime().Equal(modTime), nil
+}
+
+// setFileModTime records the modification time of an invalid config file, so
+// it isn't reloaded again until modified.
+func (c *Config) setFileModTime(modTime time.Time) {
+	if modTime.IsZero() {
+		return
+	}
+
+	c.mtx.Lock()
+	c.fileModTime = modTime
+	c.mtx.Unlock()
+}
+
+// addSecretFiles records the modification times of files referenced by secret
+// values. The caller must hold the lock.
+func (c *Config) addSecretFiles(files map[string]time.Time) {
+	if c.secretFiles == nil {
+		c.secretFiles = make(map[string]time.Time, len(files))
+	}
+	for path, modTime := range files {
+		c.secretFiles[path] = modTime
+	}
+}
+
+// notify calls the watch funcs of each key, outside of the config lock so
+// they can read the new values.
+func (c *Config) notify(keys ...string) {
+	for _, key := range keys {
+		c.mtx.RLock()
+		fns := c.watchers[key]
+		c.mtx.RUnlock()
+
+		for _, fn := range fns {
+			fn(key)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(data string) {
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		// Ensure the modification time changes on filesystems with coarse timestamps.
+		modTime := time.Now().Add(time.Duration(len(data)) * time.Second)
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	write("service: {log: {level: info}, register: {interval: 5m}}")
+
+	c := New()
+	require.NoError(t, c.LoadSources([]string{"--config", path}))
+	c.MustLoad(KeyServiceLogLevel, "info", ParseLogLevel)
+	c.MustLoad(KeyServiceRegisterInt, "300s", ParsePositiveDuration)
+	c.MustLoad(KeyHttpServerPort, 0, ParseInt)
+	c.Set(KeyHttpServerPort, 8001)
+
+	var notified []string
+	c.Watch(KeyServiceLogLevel, func(key string) { notified = append(notified, key) })
+
+	modified, err := c.FileModified()
+	require.NoError(t, err)
+	assert.False(t, modified)
+
+	t.Run("TestChanged", func(t *testing.T) {
+		write("service: {log: {level: debug}, register: {interval: 5m}}\nhttp: {server: {port: 9000}}")
+
+		modified, err := c.FileModified()
+		require.NoError(t, err)
+		assert.True(t, modified)
+
+		changed, err := c.Reload()
+		require.NoError(t, err)
+
+		// Assert only changed values are returned, and runtime values are kept.
+		assert.Equal(t, []string{KeyServiceLogLevel}, changed)
+		assert.Equal(t, []string{KeyServiceLogLevel}, notified)
+		assert.Equal(t, "debug", c.String(KeyServiceLogLevel))
+		assert.Equal(t, 8001, c.Int(KeyHttpServerPort))
+
+		modified, err = c.FileModified()
+		require.NoError(t, err)
+		assert.False(t, modified)
+	})
+
+	t.Run("TestInvalid", func(t *testing.T) {
+		write("service: {log: {level: loud}, register: {interval: 0s}}")
+
+		// Assert all invalid values are reported and no values are applied.
+		_, err := c.Reload()
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalidLogLevel)
+		assert.ErrorIs(t, err, ErrInvalidPosDuration)
+		assert.Equal(t, "debug", c.String(KeyServiceLogLevel))
+		assert.Equal(t, 5*time.Minute, c.Duration(KeyServiceRegisterInt))
+
+		// Assert invalid files aren't reloaded again until modified.
+		modified, err := c.FileModified()
+		require.NoError(t, err)
+		assert.False(t, modified)
+	})
+
+	t.Run("TestRemote", func(t *testing.T) {
+		write("service: {log: {level: debug}, register: {interval: 5m}}")
+		require.NoError(t, c.SetRemote(map[string]string{KeyServiceRegisterInt: "1m"}))
+
+		// Assert remote values are applied on reload.
+		changed, err := c.Reload()
+		require.NoError(t, err)
+		assert.Equal(t, []string{KeyServiceRegisterInt}, changed)
+		assert.Equal(t, time.Minute, c.Duration(KeyServiceRegisterInt))
+		assert.Equal(t, SourceRemote, c.Source(KeyServiceRegisterInt))
+
+		// Assert removed remote values fall back to other sources.
+		require.NoError(t, c.SetRemote(nil))
+		changed, err = c.Reload()
+		require.NoError(t, err)
+		assert.Equal(t, []string{KeyServiceRegisterInt}, changed)
+		assert.Equal(t, SourceFile, c.Source(KeyServiceRegisterInt))
+	})
+
+	t.Run("TestSet", func(t *testing.T) {
+		notified = nil
+		c.Set(KeyServiceLogLevel, "warn")
+		c.Set(KeyServiceLogLevel, "warn")
+
+		// Assert watchers are only notified of changed values.
+		assert.Equal(t, []string{KeyServiceLogLevel}, notified)
+		assert.True(t, c.Watched(KeyServiceLogLevel))
+		assert.False(t, c.Watched(KeyServiceRegisterInt))
+	})
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Types of config values, as displayed in the schema.
+const (
+	TypeString      = "string"
+	TypeInt         = "int"
+	TypeFloat       = "float"
+	TypeBool        = "bool"
+	TypeDuration    = "duration"
+	TypeStringSlice = "[]string"
+	TypeStringMap   = "map[string]string"
+)
+
+// Formats the schema can be written in.
+const (
+	SchemaFormatJSON     = "json"
+	SchemaFormatMarkdown = "markdown"
+)
+
+// Schema describes a config key: the type of its value, its default, the parse
+// funcs used to validate it and the subsystem that owns it. A nil default marks
+// the value as required. Only keys marked Remote can be set by remote sources,
+// so security sensitive config can't be changed through the KV store.
+type Schema struct {
+	Key         string      `json:"key"`
+	Env         string      `json:"env"`
+	Type        string      `json:"type"`
+	Default     interface{} `json:"default"`
+	Required    bool        `json:"required"`
+	Remote      bool        `json:"remote"`
+	Description string      `json:"description"`
+	Subsystem   string      `json:"subsystem"`
+	Parse       []ParseFunc `json:"-"`
+}
+
+// registry stores the schema of every known config key.
+var registry = struct {
+	mtx     sync.RWMutex
+	schemas map[string]Schema
+}{schemas: make(map[string]Schema)}
+
+// Register adds config keys to the schema registry, so they can be loaded by
+// LoadKey and are documented by WriteSchema. It panics if a key is already
+// registered.
+func Register(schemas ...Schema) {
+	registry.mtx.Lock()
+	defer registry.mtx.Unlock()
+
+	for _, s := range schemas {
+		if _, ok := registry.schemas[s.Key]; ok {
+			panic(fmt.Sprintf("config key '%s' already registered", s.Key))
+		}
+
+		s.Env = normalizeKey(s.Key)
+		s.Required = s.Default == nil
+		registry.schemas[s.Key] = s
+	}
+}
+
+// Lookup returns the schema of a registered config key.
+func Lookup(key string) (Schema, bool) {
+	registry.mtx.RLock()
+	defer registry.mtx.RUnlock()
+
+	s, ok := registry.schemas[key]
+	return s, ok
+}
+
+// Schemas returns the schema of all registered config keys, sorted by
+// subsystem and then key.
+func Schemas() []Schema {
+	registry.mtx.RLock()
+	schemas := make([]Schema, 0, len(registry.schemas))
+	for _, s := range registry.schemas {
+		schemas = append(schemas, s)
+	}
+	registry.mtx.RUnlock()
+
+	sort.Slice(schemas, func(i, j int) bool {
+		if schemas[i].Subsystem != schemas[j].Subsystem {
+			return schemas[i].Subsystem < schemas[j].Subsystem
+		}
+		return schemas[i].Key < schemas[j].Key
+	})
+
+	return schemas
+}
+
+// LoadKey loads the value of a registered key with its registered default and
+// parse funcs.
+func (c *Config) LoadKey(key string) error {
+	s, ok := Lookup(key)
+	if !ok {
+		return fmt.Errorf("error: config key '%s' not registered", key)
+	}
+
+	return c.Load(key, s.Default, s.Parse...)
+}
+
+// MustLoadKeys is functionally equivalent to calling LoadKey for each key, but
+// panics on error.
+func (c *Config) MustLoadKeys(keys ...string) {
+	for _, key := range keys {
+		if err := c.LoadKey(key); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// UnknownEnv returns the sorted names of set PLAT_* env vars that don't match a
+// registered key or a key loaded by this config. These are usually typos.
+func (c *Config) UnknownEnv() []string {
+	known := make(map[string]bool)
+	for _, s := range Schemas() {
+		known[s.Env] = true
+	}
+
+	c.mtx.RLock()
+	for key := range c.loaders {
+		known[normalizeKey(key)] = true
+	}
+	c.mtx.RUnlock()
+
+	var unknown []string
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		if strings.HasPrefix(name, "PLAT_") && !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+
+	return unknown
+}
+
+// WriteSchema writes the schema of all registered config keys as JSON or a
+// Markdown table per subsystem.
+func WriteSchema(w io.Writer, format string) error {
+	schemas := Schemas()
+	for i := range schemas {
+		schemas[i].Default = schemaDefault(schemas[i].Default)
+	}
+
+	switch format {
+	case SchemaFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(schemas)
+	case SchemaFormatMarkdown:
+		return writeSchemaMarkdown(w, schemas)
+	}
+
+	return fmt.Errorf("error: unknown schema format '%s'", format)
+}
+
+// writeSchemaMarkdown writes a Markdown table of config keys per subsystem.
+func writeSchemaMarkdown(w io.Writer, schemas []Schema) error {
+	var b strings.Builder
+	b.WriteString("# Configuration\n")
+
+	subsystem := ""
+	for i, s := range schemas {
+		if i == 0 || s.Subsystem != subsystem {
+			subsystem = s.Subsystem
+			fmt.Fprintf(&b, "\n## %s\n\n", subsystem)
+			b.WriteString("| Key | Env | Type | Default | Remote | Description |\n")
+			b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+		}
+
+		def := "required"
+		switch {
+		case s.Default == "":
+			def = "none"
+		case !s.Required:
+			def = fmt.Sprintf("`%v`", s.Default)
+		}
+		remote := "no"
+		if s.Remote {
+			remote = "yes"
+		}
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s | %s | %s |\n", s.Key, s.Env, s.Type, def, remote, s.Description)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// schemaDefault returns a default value suitable for display.
+func schemaDefault(value interface{}) interface{} {
+	switch t := value.(type) {
+	case time.Duration:
+		return t.String()
+	case map[string]string:
+		pairs := make([]string, 0, len(t))
+		for k, v := range t {
+			pairs = append(pairs, k+"="+v)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	case []string:
+		return strings.Join(t, ",")
+	}
+
+	return value
+}
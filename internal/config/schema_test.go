@@ -0,0 +1,82 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	Register(Schema{Key: "test.register.key", Type: TypeInt, Default: 1, Parse: []ParseFunc{ParseInt}, Subsystem: "test"})
+
+	s, ok := Lookup("test.register.key")
+	require.True(t, ok)
+	assert.Equal(t, "PLAT_TEST_REGISTER_KEY", s.Env)
+	assert.False(t, s.Required)
+
+	// Assert keys can only be registered once.
+	assert.Panics(t, func() { Register(Schema{Key: "test.register.key"}) })
+
+	// Assert keys without a default are required.
+	s, ok = Lookup(KeyCAToken)
+	require.True(t, ok)
+	assert.True(t, s.Required)
+}
+
+func TestLoadKey(t *testing.T) {
+	c := New()
+	require.NoError(t, c.LoadKey(KeyGrpcClientMaxAttempts))
+	assert.Equal(t, 3, c.Int(KeyGrpcClientMaxAttempts))
+
+	t.Setenv("PLAT_DISCOVERY_EVICTION_WINDOW", "0s")
+	assert.ErrorIs(t, c.LoadKey(KeyDiscoveryEvictionWindow), ErrInvalidPosDuration)
+
+	assert.ErrorContains(t, c.LoadKey("not.registered"), "not registered")
+	assert.Panics(t, func() { c.MustLoadKeys(KeyCAToken) })
+}
+
+func TestUnknownEnv(t *testing.T) {
+	t.Setenv("PLAT_SERVICE_LOG_LEVEL", "debug")
+	t.Setenv("PLAT_SERVICE_LOG_LEVLE", "debug")
+	t.Setenv("PLAT_SOME_BOUND_KEY", "value")
+
+	c := New()
+	c.MustLoad("some.bound.key", "default", ParseString)
+
+	// Assert only env vars that don't match registered or loaded keys are
+	// returned.
+	assert.Equal(t, []string{"PLAT_SERVICE_LOG_LEVLE"}, c.UnknownEnv())
+}
+
+func TestWriteSchema(t *testing.T) {
+	t.Run("TestJSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteSchema(&buf, SchemaFormatJSON))
+
+		var schemas []Schema
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &schemas))
+		assert.Len(t, schemas, len(Schemas()))
+		assert.Contains(t, schemas, Schema{
+			Key:         KeyServiceLogLevel,
+			Env:         "PLAT_SERVICE_LOG_LEVEL",
+			Type:        TypeString,
+			Default:     "info",
+			Remote:      true,
+			Description: "Minimum log level.",
+			Subsystem:   SubsystemService,
+		})
+	})
+
+	t.Run("TestMarkdown", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteSchema(&buf, SchemaFormatMarkdown))
+		assert.Contains(t, buf.String(), "\n## service\n")
+		assert.Contains(t, buf.String(), "| `service.log.level` | `PLAT_SERVICE_LOG_LEVEL` | string | `info` | yes | Minimum log level. |\n")
+		assert.Contains(t, buf.String(), "| `ca.token` | `PLAT_CA_TOKEN` | string | required |")
+	})
+
+	assert.Error(t, WriteSchema(&bytes.Buffer{}, "yaml"))
+}
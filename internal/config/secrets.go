@@ -0,0 +1,235 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Prefixes of config values that reference secrets. References are resolved to
+// the secret value when loaded and reloaded, e.g. file:///run/secrets/ca-token,
+// env://CA_TOKEN or enc://<base64 ciphertext>.
+const (
+	SecretFilePrefix      = "file://"
+	SecretEnvPrefix       = "env://"
+	SecretEncryptedPrefix = "enc://"
+)
+
+// DefaultSecretKeyFile is the default path of the key used to decrypt encrypted
+// secret references.
+const DefaultSecretKeyFile = "/etc/platform/secret.key"
+
+// secretKeySize is the size of an AES-256 key.
+const secretKeySize = 32
+
+// ErrNoSecretKey is returned when decrypting a secret without a key file.
+var ErrNoSecretKey = errors.New("no secret key file set")
+
+// ErrRemoteSecret is returned when a remote value references a secret.
+var ErrRemoteSecret = errors.New("secret references can't be set remotely")
+
+// secretResolver resolves secret references in config values, recording the
+// modification time of any referenced files.
+type secretResolver struct {
+	keyFile string
+	// Loaded on first use, so the key file is only required if secrets are
+	// encrypted.
+	aead  cipher.AEAD
+	files map[string]time.Time
+}
+
+// secretResolver returns a resolver using the secret key file set by the
+// current sources.
+func (c *Config) secretResolver() *secretResolver {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return newSecretResolver(c.file, c.flags)
+}
+
+// newSecretResolver returns a resolver using the secret key file set by the
+// given sources. The key file can't itself be a reference, or be set remotely.
+func newSecretResolver(file map[string]interface{}, flags map[string]string) *secretResolver {
+	keyFile, _ := lookup(file, nil, flags, KeyConfigSecretKeyFile, DefaultSecretKeyFile)
+	path, _ := keyFile.(string)
+
+	return &secretResolver{keyFile: path, files: make(map[string]time.Time)}
+}
+
+// checkRemoteSecret rejects remote values that reference secrets. Remote values
+// can be written by any client of the KV store, so they must never be used to
+// read local files, env vars or keys. The secret key file can't be set
+// remotely, see SetRemote.
+func checkRemoteSecret(value interface{}, source Source) error {
+	if source != SourceRemote {
+		return nil
+	}
+
+	if v, ok := value.(string); ok && isSecretRef(v) {
+		return ErrRemoteSecret
+	}
+
+	return nil
+}
+
+// isSecretRef reports whether a value is a secret reference.
+func isSecretRef(value string) bool {
+	for _, prefix := range []string{SecretFilePrefix, SecretEnvPrefix, SecretEncryptedPrefix} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolve resolves secret references in a string, or the elements of a slice
+// or map of strings. It reports whether any references were resolved, in which
+// case the value is a secret.
+func (r *secretResolver) resolve(value interface{}) (interface{}, bool, error) {
+	switch t := value.(type) {
+	case string:
+		return r.resolveString(t)
+	case []string:
+		var secret bool
+		out := make([]string, 0, len(t))
+		for _, v := range t {
+			resolved, ok, err := r.resolveString(v)
+			if err != nil {
+				return nil, false, err
+			}
+			out = append(out, resolved)
+			secret = secret || ok
+		}
+		return out, secret, nil
+	case map[string]string:
+		var secret bool
+		out := make(map[string]string, len(t))
+		for k, v := range t {
+			resolved, ok, err := r.resolveString(v)
+			if err != nil {
+				return nil, false, err
+			}
+			out[k] = resolved
+			secret = secret || ok
+		}
+		return out, secret, nil
+	}
+
+	return value, false, nil
+}
+
+// resolveString resolves a single secret reference. Errors never include the
+// secret value.
+func (r *secretResolver) resolveString(value string) (string, bool, error) {
+	switch {
+	case strings.HasPrefix(value, SecretFilePrefix):
+		path := strings.TrimPrefix(value, SecretFilePrefix)
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", false, fmt.Errorf("error reading secret file: %w", err)
+		}
+		r.files[path] = info.ModTime()
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("error reading secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	case strings.HasPrefix(value, SecretEnvPrefix):
+		name := strings.TrimPrefix(value, SecretEnvPrefix)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", false, fmt.Errorf("error reading secret env var: '%s' not set", name)
+		}
+		return secret, true, nil
+	case strings.HasPrefix(value, SecretEncryptedPrefix):
+		if r.aead == nil {
+			aead, err := loadSecretKey(r.keyFile)
+			if err != nil {
+				return "", false, err
+			}
+			r.aead = aead
+		}
+
+		secret, err := decryptSecret(r.aead, strings.TrimPrefix(value, SecretEncryptedPrefix))
+		if err != nil {
+			return "", false, err
+		}
+		return secret, true, nil
+	}
+
+	return value, false, nil
+}
+
+// EncryptSecret encrypts a secret with the key in a key file, returning an
+// encrypted reference that can be used as a config value.
+func EncryptSecret(keyFile, secret string) (string, error) {
+	aead, err := loadSecretKey(keyFile)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("error encrypting secret: %w", err)
+	}
+
+	ciphertext := aead.Seal(nonce, nonce, []byte(secret), nil)
+
+	return SecretEncryptedPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// decryptSecret decrypts a base64 encoded nonce and ciphertext.
+func decryptSecret(aead cipher.AEAD, encoded string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("error decoding encrypted secret: %w", err)
+	}
+
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("error decrypting secret: ciphertext too short")
+	}
+
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	secret, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting secret: %w", err)
+	}
+
+	return string(secret), nil
+}
+
+// loadSecretKey reads a base64 encoded AES-256 key from a file, e.g. one
+// generated by `openssl rand -base64 32`.
+func loadSecretKey(path string) (cipher.AEAD, error) {
+	if path == "" {
+		return nil, ErrNoSecretKey
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading secret key file: %w", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding secret key: %w", err)
+	}
+	if len(key) != secretKeySize {
+		return nil, fmt.Errorf("error decoding secret key: key must be %d bytes", secretKeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating secret cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
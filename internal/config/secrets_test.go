@@ -0,0 +1,170 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSecretKey writes a random base64 encoded secret key to a temp file.
+func writeSecretKey(t *testing.T) string {
+	key := make([]byte, secretKeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "secret.key")
+	require.NoError(t, os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600))
+
+	return path
+}
+
+func TestEncryptSecret(t *testing.T) {
+	keyFile := writeSecretKey(t)
+
+	enc, err := EncryptSecret(keyFile, "s3cret")
+	require.NoError(t, err)
+	assert.Regexp(t, "^enc://", enc)
+
+	r := &secretResolver{keyFile: keyFile, files: make(map[string]time.Time)}
+	secret, ok, err := r.resolveString(enc)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "s3cret", secret)
+
+	// Assert secrets can't be decrypted with a different key.
+	r = &secretResolver{keyFile: writeSecretKey(t), files: make(map[string]time.Time)}
+	_, _, err = r.resolveString(enc)
+	assert.Error(t, err)
+
+	_, err = EncryptSecret("", "s3cret")
+	assert.ErrorIs(t, err, ErrNoSecretKey)
+}
+
+func TestResolveSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("file-token\n"), 0o600))
+	t.Setenv("TEST_TOKEN", "env-token")
+
+	r := &secretResolver{files: make(map[string]time.Time)}
+
+	tests := []struct {
+		name   string
+		value  interface{}
+		exp    interface{}
+		secret bool
+	}{
+		{"TestPlain", "info", "info", false},
+		{"TestNonString", 8000, 8000, false},
+		{"TestFile", "file://" + path, "file-token", true},
+		{"TestEnv", "env://TEST_TOKEN", "env-token", true},
+		{"TestSlice", []string{"a", "env://TEST_TOKEN"}, []string{"a", "env-token"}, true},
+		{"TestMap", map[string]string{"platformctl": "file://" + path}, map[string]string{"platformctl": "file-token"}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			value, secret, err := r.resolve(tc.value)
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, value)
+			assert.Equal(t, tc.secret, secret)
+		})
+	}
+
+	// Assert referenced files are recorded.
+	assert.Contains(t, r.files, path)
+
+	t.Run("TestMissing", func(t *testing.T) {
+		_, _, err := r.resolve("file://" + filepath.Join(t.TempDir(), "missing"))
+		assert.Error(t, err)
+
+		_, _, err = r.resolve("env://TEST_MISSING_TOKEN")
+		assert.Error(t, err)
+
+		_, _, err = r.resolve("enc://abcd")
+		assert.Error(t, err)
+	})
+}
+
+func TestLoadSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	write := func(data string) {
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		// Ensure the modification time changes on filesystems with coarse timestamps.
+		modTime := time.Now().Add(time.Duration(len(data)) * time.Second)
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	write("token-1")
+	t.Setenv("PLAT_SERVICE_DISCOVERY_ADDR", "file://"+path)
+
+	c := New()
+	require.NoError(t, c.Load(KeyServiceDiscoveryAddr, "discoveryd:8000", ParseString))
+	assert.Equal(t, "token-1", c.String(KeyServiceDiscoveryAddr))
+
+	// Assert values resolved from references are redacted, even if the key
+	// isn't a secret.
+	assert.Equal(t, []Value{{Key: KeyServiceDiscoveryAddr, Value: Redacted, Source: SourceEnv}}, c.Effective())
+
+	// Assert secrets are resolved again on reload after the file is modified.
+	write("token-2")
+	modified, err := c.FileModified()
+	require.NoError(t, err)
+	assert.True(t, modified)
+
+	changed, err := c.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, []string{KeyServiceDiscoveryAddr}, changed)
+	assert.Equal(t, "token-2", c.String(KeyServiceDiscoveryAddr))
+
+	modified, err = c.FileModified()
+	require.NoError(t, err)
+	assert.False(t, modified)
+
+	// Assert unresolvable references are rejected.
+	require.NoError(t, os.Remove(path))
+	_, err = c.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, "token-2", c.String(KeyServiceDiscoveryAddr))
+}
+
+func TestRemoteSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("file-token"), 0o600))
+	t.Setenv("TEST_TOKEN", "env-token")
+
+	t.Run("TestLoad", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.SetRemote(map[string]string{KeyServiceLogLevel: "file://" + path}))
+
+		// Assert remote file references aren't dereferenced.
+		err := c.Load(KeyServiceLogLevel, "info", ParseString)
+		assert.ErrorIs(t, err, ErrRemoteSecret)
+		assert.Empty(t, c.String(KeyServiceLogLevel))
+	})
+
+	t.Run("TestReload", func(t *testing.T) {
+		c := New()
+		require.NoError(t, c.Load(KeyServiceLogLevel, "info", ParseString))
+
+		// Assert remote env references are rejected, leaving the current value.
+		require.NoError(t, c.SetRemote(map[string]string{KeyServiceLogLevel: "env://TEST_TOKEN"}))
+		_, err := c.Reload()
+		assert.ErrorIs(t, err, ErrRemoteSecret)
+		assert.Equal(t, "info", c.String(KeyServiceLogLevel))
+	})
+
+	t.Run("TestKeyFile", func(t *testing.T) {
+		c := New()
+
+		// Assert the secret key file can't be set remotely.
+		assert.ErrorIs(t, c.SetRemote(map[string]string{KeyConfigSecretKeyFile: path}), ErrRemoteNotAllowed)
+		require.NoError(t, c.Load(KeyConfigSecretKeyFile, DefaultSecretKeyFile, ParseString))
+		assert.Equal(t, DefaultSecretKeyFile, c.String(KeyConfigSecretKeyFile))
+		assert.Equal(t, DefaultSecretKeyFile, c.secretResolver().keyFile)
+	})
+}
@@ -0,0 +1,323 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrRemoteNotAllowed is returned when a remote value is set for a registered
+// key that can't be set remotely.
+var ErrRemoteNotAllowed = errors.New("config key can't be set remotely")
+
+// Source describes where a config value was loaded from. Sources are listed in
+// order of increasing precedence.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceRemote  Source = "remote"
+	SourceFlag    Source = "flag"
+	// SourceRuntime values are set by the service after loading.
+	SourceRuntime Source = "runtime"
+)
+
+// Redacted replaces the value of secret config keys when displayed.
+const Redacted = "[REDACTED]"
+
+// secretSegments are final key segments that identify config values as secrets.
+var secretSegments = map[string]bool{
+	"token":    true,
+	"tokens":   true,
+	"secret":   true,
+	"secrets":  true,
+	"password": true,
+}
+
+// IsSecret reports whether a key holds a secret value that must be redacted
+// when displayed, e.g. ca.token but not grpc.server.token.required.
+func IsSecret(key string) bool {
+	return secretSegments[key[strings.LastIndex(key, ".")+1:]]
+}
+
+// LoadSources loads config file and command-line flag sources from args, which
+// are used by subsequent calls to Load.
+//
+// Flags take the form --<key>=<value> or --<key> <value>, e.g.
+// --grpc.server.port=8000. Flags without a value are set to true. The config
+// file is set by the --config flag, or the PLAT_CONFIG_FILE env var.
+func (c *Config) LoadSources(args []string) error {
+	flags, err := parseFlags(args)
+	if err != nil {
+		return err
+	}
+
+	path, source := os.Getenv(normalizeKey(KeyConfigFile)), SourceEnv
+	if p, ok := flags["config"]; ok {
+		path, source = p, SourceFlag
+		delete(flags, "config")
+	}
+
+	c.mtx.Lock()
+	c.flags = flags
+	c.mtx.Unlock()
+
+	if path == "" {
+		return nil
+	}
+
+	if err := c.LoadFile(path); err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	c.values[KeyConfigFile] = path
+	c.sources[KeyConfigFile] = source
+	c.mtx.Unlock()
+
+	return nil
+}
+
+// LoadFile reads a YAML, JSON or TOML config file, based on its extension, which
+// is used as a source of values by subsequent calls to Load. Nested keys are
+// flattened, e.g. {grpc: {server: {port: 8000}}} sets grpc.server.port.
+func (c *Config) LoadFile(path string) error {
+	file, modTime, err := readFile(path)
+	if err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	c.file = file
+	c.fileModTime = modTime
+	c.mtx.Unlock()
+
+	return nil
+}
+
+// SetRemote replaces all values read from a remote source, such as the
+// discovery KV store, which take precedence over env vars but not flags. They
+// are applied by subsequent calls to Load or Reload.
+//
+// Remote values can be written by any client of the KV store, so values of
+// registered keys that aren't marked Remote in their schema, such as TLS and
+// authz config, are ignored and returned as errors.
+func (c *Config) SetRemote(values map[string]string) error {
+	var errs []error
+	remote := make(map[string]string, len(values))
+	for key, value := range values {
+		if s, ok := Lookup(key); ok && !s.Remote {
+			errs = append(errs, fmt.Errorf("error setting remote config value for '%s': %w", key, ErrRemoteNotAllowed))
+			continue
+		}
+		remote[key] = value
+	}
+
+	c.mtx.Lock()
+	c.remote = remote
+	c.mtx.Unlock()
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// Source returns the source a config value was loaded from, or an empty source
+// if the key isn't set.
+func (c *Config) Source(key string) Source {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.sources[key]
+}
+
+// Value is a config value and the source it was loaded from.
+type Value struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source Source      `json:"source"`
+}
+
+// Effective returns all config values sorted by key, with secrets and values
+// resolved from secret references redacted.
+func (c *Config) Effective() []Value {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	values := make([]Value, 0, len(c.values))
+	for key, val := range c.values {
+		values = append(values, Value{Key: key, Value: displayValue(key, val, c.secrets[key]), Source: c.sources[key]})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Key < values[j].Key })
+
+	return values
+}
+
+// lookup returns the value of a key from the highest precedence source, or the
+// given default.
+func (c *Config) lookup(key string, value interface{}) (interface{}, Source) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return lookup(c.file, c.remote, c.flags, key, value)
+}
+
+// lookup returns the value of a key from the given file values, env vars,
+// remote values or flags, or the given default.
+func lookup(file map[string]interface{}, remote, flags map[string]string, key string, value interface{}) (interface{}, Source) {
+	source := SourceDefault
+	if v, ok := file[key]; ok {
+		value, source = v, SourceFile
+	}
+	if env := os.Getenv(normalizeKey(key)); env != "" {
+		value, source = env, SourceEnv
+	}
+	if v, ok := remote[key]; ok {
+		value, source = v, SourceRemote
+	}
+	if v, ok := flags[key]; ok {
+		value, source = v, SourceFlag
+	}
+
+	return value, source
+}
+
+// readFile reads and flattens a config file, returning its values and the
+// time it was last modified. The modification time is also returned if the
+// file can't be parsed.
+func readFile(path string) (map[string]interface{}, time.Time, error) {
+	// Record the modification time before reading so concurrent writes are
+	// picked up by the next reload.
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error reading config file: %w", err)
+	}
+	modTime := info.ModTime()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, modTime, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	var raw map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, modTime, fmt.Errorf("error reading config file: unsupported format '%s'", ext)
+	}
+	if err != nil {
+		return nil, modTime, fmt.Errorf("error parsing config file: %w", err)
+	}
+
+	file := make(map[string]interface{})
+	flatten("", raw, file)
+
+	return file, modTime, nil
+}
+
+// displayValue returns a config value suitable for display. Secret values are
+// redacted, including those resolved from secret references.
+func displayValue(key string, value interface{}, secret bool) interface{} {
+	if (secret || IsSecret(key)) && value != nil && value != "" {
+		return Redacted
+	}
+
+	if d, ok := value.(time.Duration); ok {
+		return d.String()
+	}
+
+	return value
+}
+
+// parseFlags parses command-line flags into a map of keys to values.
+func parseFlags(args []string) (map[string]string, error) {
+	flags := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") || len(arg) == 2 {
+			return nil, fmt.Errorf("error parsing flags: unexpected argument '%s'", arg)
+		}
+
+		key, value, ok := strings.Cut(arg[2:], "=")
+		if !ok {
+			// Use the next arg as the value, unless it is another flag.
+			value = "true"
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+				value = args[i+1]
+				i++
+			}
+		}
+
+		flags[key] = value
+	}
+
+	return flags, nil
+}
+
+// flatten flattens nested file values into dot separated keys. Scalars are
+// converted to strings so they are parsed the same as env vars, lists become
+// slices of strings, and maps of scalars are also stored as maps of strings.
+func flatten(prefix string, raw map[string]interface{}, out map[string]interface{}) {
+	for k, v := range raw {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		switch t := v.(type) {
+		case map[string]interface{}:
+			flatten(key, t, out)
+			if m, ok := scalarMap(t); ok {
+				out[key] = m
+			}
+		case []interface{}:
+			s := make([]string, 0, len(t))
+			for _, item := range t {
+				s = append(s, scalarString(item))
+			}
+			out[key] = s
+		case nil:
+		default:
+			out[key] = scalarString(t)
+		}
+	}
+}
+
+// scalarMap converts a map of scalar values to a map of strings.
+func scalarMap(raw map[string]interface{}) (map[string]string, bool) {
+	m := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}, nil:
+			return nil, false
+		}
+		m[k] = scalarString(v)
+	}
+
+	return m, true
+}
+
+// scalarString formats a scalar file value as a string. Floats are formatted
+// without exponents, as JSON decodes all numbers as floats.
+func scalarString(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Equivalent config files in each supported format.
+var testFiles = map[string]string{
+	"config.yaml": `
+http:
+  server:
+    port: 8001
+grpc:
+  server:
+    port: 8000
+    log:
+      sample: 0.5
+  client:
+    server:
+      identities:
+        discoveryd:8000: spiffe://platform/discoveryd
+service:
+  log:
+    level: warn
+  shutdown:
+    timeout: 5s
+`,
+	"config.json": `{
+  "http": {"server": {"port": 8001}},
+  "grpc": {
+    "server": {"port": 8000, "log": {"sample": 0.5}},
+    "client": {"server": {"identities": {"discoveryd:8000": "spiffe://platform/discoveryd"}}}
+  },
+  "service": {"log": {"level": "warn"}, "shutdown": {"timeout": "5s"}}
+}`,
+	"config.toml": `
+[http.server]
+port = 8001
+
+[grpc.server]
+port = 8000
+log.sample = 0.5
+
+[grpc.client.server.identities]
+"discoveryd:8000" = "spiffe://platform/discoveryd"
+
+[service]
+log.level = "warn"
+shutdown.timeout = "5s"
+`,
+}
+
+func TestLoadFile(t *testing.T) {
+	for name, data := range testFiles {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+			c := New()
+			require.NoError(t, c.LoadFile(path))
+
+			c.MustLoad(KeyHttpServerPort, 0, ParseInt)
+			c.MustLoad(KeyGrpcServerPort, 0, ParseInt)
+			c.MustLoad(KeyGrpcServerLogSample, 1.0, ParseFloat64)
+			c.MustLoad(KeyGrpcClientIdentities, map[string]string{}, ParseStringMap)
+			c.MustLoad(KeyServiceLogLevel, "info", ParseLogLevel)
+			c.MustLoad(KeyServiceShutdownTimeout, "10s", ParseDuration)
+
+			// Assert all values are read from the file.
+			assert.Equal(t, 8001, c.Int(KeyHttpServerPort))
+			assert.Equal(t, 8000, c.Int(KeyGrpcServerPort))
+			assert.Equal(t, 0.5, c.Float64(KeyGrpcServerLogSample))
+			assert.Equal(t, map[string]string{"discoveryd:8000": "spiffe://platform/discoveryd"}, c.StringMap(KeyGrpcClientIdentities))
+			assert.Equal(t, "warn", c.String(KeyServiceLogLevel))
+			assert.Equal(t, 5*time.Second, c.Duration(KeyServiceShutdownTimeout))
+			assert.Equal(t, SourceFile, c.Source(KeyServiceShutdownTimeout))
+		})
+	}
+
+	t.Run("TestUnsupported", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.ini")
+		require.NoError(t, os.WriteFile(path, []byte("key=value"), 0o600))
+
+		assert.Error(t, New().LoadFile(path))
+	})
+
+	t.Run("TestInvalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+		assert.Error(t, New().LoadFile(path))
+	})
+}
+
+func TestLoadSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("a: {file: file, env: file, flag: file}"), 0o600))
+	t.Setenv("PLAT_A_ENV", "env")
+	t.Setenv("PLAT_A_REMOTE", "env")
+	t.Setenv("PLAT_A_FLAG", "env")
+
+	c := New()
+	require.NoError(t, c.LoadSources([]string{"--config", path, "--a.flag=flag", "--b.bool"}))
+	require.NoError(t, c.SetRemote(map[string]string{"a.remote": "remote", "a.flag": "remote"}))
+
+	for key, source := range map[string]Source{
+		"a.default": SourceDefault,
+		"a.file":    SourceFile,
+		"a.env":     SourceEnv,
+		"a.remote":  SourceRemote,
+		"a.flag":    SourceFlag,
+	} {
+		c.MustLoad(key, "default", ParseString)
+
+		// Assert each value is loaded from the highest precedence source.
+		assert.Equal(t, string(source), c.String(key))
+		assert.Equal(t, source, c.Source(key))
+	}
+
+	c.MustLoad("b.bool", false, ParseBool)
+	assert.True(t, c.Bool("b.bool"))
+	assert.Equal(t, path, c.String(KeyConfigFile))
+	assert.Equal(t, SourceFlag, c.Source(KeyConfigFile))
+
+	t.Run("TestEnvFile", func(t *testing.T) {
+		t.Setenv("PLAT_CONFIG_FILE", path)
+
+		c := New()
+		require.NoError(t, c.LoadSources(nil))
+		c.MustLoad("a.file", "default", ParseString)
+		assert.Equal(t, "file", c.String("a.file"))
+		assert.Equal(t, SourceEnv, c.Source(KeyConfigFile))
+	})
+
+	t.Run("TestInvalidArgs", func(t *testing.T) {
+		assert.Error(t, New().LoadSources([]string{"value"}))
+		assert.Error(t, New().LoadSources([]string{"-a.flag=flag"}))
+		assert.Error(t, New().LoadSources([]string{"--config", "does-not-exist.yaml"}))
+	})
+}
+
+func TestSetRemote(t *testing.T) {
+	c := New()
+	err := c.SetRemote(map[string]string{
+		KeyServiceLogLevel:    "debug",
+		KeyGrpcServerAuthz:    "false",
+		KeyGrpcTLSCA:          "/tmp/ca.crt.pem",
+		"test.remote.unknown": "remote",
+	})
+
+	// Assert security sensitive keys can't be set remotely.
+	assert.ErrorIs(t, err, ErrRemoteNotAllowed)
+	assert.ErrorContains(t, err, KeyGrpcServerAuthz)
+	assert.ErrorContains(t, err, KeyGrpcTLSCA)
+
+	c.MustLoadKeys(KeyServiceLogLevel, KeyGrpcServerAuthz, KeyGrpcTLSCA)
+	c.MustLoad("test.remote.unknown", "default", ParseString)
+	assert.Equal(t, SourceRemote, c.Source(KeyServiceLogLevel))
+	assert.Equal(t, SourceDefault, c.Source(KeyGrpcServerAuthz))
+	assert.Equal(t, SourceDefault, c.Source(KeyGrpcTLSCA))
+	assert.Equal(t, SourceRemote, c.Source("test.remote.unknown"))
+}
+
+func TestEffective(t *testing.T) {
+	c := New()
+	c.MustLoad("service.log.level", "info", ParseLogLevel)
+	c.MustLoad("ca.token", "token", ParseString)
+	c.Set("http.idle.timeout", 10*time.Second)
+
+	assert.True(t, IsSecret("ca.bootstrap.tokens"))
+	assert.False(t, IsSecret("grpc.server.key"))
+	assert.False(t, IsSecret("grpc.server.token.required"))
+	assert.Equal(t, []Value{
+		{Key: "ca.token", Value: Redacted, Source: SourceDefault},
+		{Key: "http.idle.timeout", Value: "10s", Source: SourceRuntime},
+		{Key: "service.log.level", Value: "info", Source: SourceDefault},
+	}, c.Effective())
+}
@@ -1,12 +1,20 @@
 package credentials
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/rs/zerolog/log"
 	grpc "google.golang.org/grpc/credentials"
 
+	"github.com/loshz/platform/internal/authz"
 	"github.com/loshz/platform/internal/config"
 	pgrpc "github.com/loshz/platform/internal/grpc"
+	"github.com/loshz/platform/internal/metrics"
+	"github.com/loshz/platform/internal/token"
 )
 
 type Credential uint
@@ -14,16 +22,58 @@ type Credential uint
 const (
 	GrpcClient Credential = iota
 	GrpcServer
+	// GrpcToken attaches signed bearer tokens to gRPC calls as per-RPC
+	// credentials. Requires GrpcClient.
+	GrpcToken
+	// HTTPServer serves the admin HTTP server over TLS with the gRPC server cert.
+	HTTPServer
 )
 
+// Store loads and serves service credentials. TLS certs are served through
+// callbacks, so reloaded certs are used by new connections without recreating
+// the credentials.
 type Store struct {
 	grpc struct {
-		client, server grpc.TransportCredentials
+		client, server           *certReloader
+		clientCreds, serverCreds grpc.TransportCredentials
+		tokenCreds               *pgrpc.TokenCredentials
 	}
+
+	http struct {
+		server *certReloader
+	}
+
+	// Set if certs are issued by the platform CA.
+	issuer *issuer
 }
 
-func (s *Store) GrpcClient() grpc.TransportCredentials { return s.grpc.client }
-func (s *Store) GrpcServer() grpc.TransportCredentials { return s.grpc.server }
+func (s *Store) GrpcClient() grpc.TransportCredentials { return s.grpc.clientCreds }
+func (s *Store) GrpcServer() grpc.TransportCredentials { return s.grpc.serverCreds }
+
+// GrpcToken returns per-RPC bearer token credentials, or nil if not loaded.
+func (s *Store) GrpcToken() grpc.PerRPCCredentials {
+	if s.grpc.tokenCreds == nil {
+		return nil
+	}
+
+	return s.grpc.tokenCreds
+}
+
+// GrpcClientFor returns gRPC client credentials that expect servers to present
+// the given identity instead of the dialed host name.
+// See pgrpc.VerifyServer for supported identities.
+func (s *Store) GrpcClientFor(identity string) (grpc.TransportCredentials, error) {
+	if identity == "" {
+		return s.GrpcClient(), nil
+	}
+
+	tlsConfig, err := s.grpc.client.clientConfig(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return grpc.NewTLS(tlsConfig), nil
+}
 
 func (s *Store) LoadGrpcClientCreds(c *config.Config) error {
 	// Load TLS credentials.
@@ -31,13 +81,19 @@ func (s *Store) LoadGrpcClientCreds(c *config.Config) error {
 	cert := c.String(config.KeyGrpcClientCert)
 	key := c.String(config.KeyGrpcClientKey)
 
-	// Create new gRPC server TLS credentials.
-	creds, err := pgrpc.NewClientTransportCreds(ca, cert, key)
+	r, err := newCertReloader("grpc_client", ca, cert, key)
+	if err != nil {
+		return fmt.Errorf("error loading grpc client tls credentials: %w", err)
+	}
+
+	// Create new gRPC client TLS credentials.
+	tlsConfig, err := r.clientConfig("")
 	if err != nil {
 		return fmt.Errorf("error loading grpc client tls credentials: %w", err)
 	}
 
-	s.grpc.client = creds
+	s.grpc.client = r
+	s.grpc.clientCreds = grpc.NewTLS(tlsConfig)
 	return nil
 }
 
@@ -47,12 +103,157 @@ func (s *Store) LoadGrpcServerCreds(c *config.Config) error {
 	cert := c.String(config.KeyGrpcServerCert)
 	key := c.String(config.KeyGrpcServerKey)
 
-	// Create new gRPC server TLS credentials.
-	creds, err := pgrpc.NewServerTransportCreds(ca, cert, key)
+	r, err := newCertReloader("grpc_server", ca, cert, key)
 	if err != nil {
 		return fmt.Errorf("error loading grpc server tls credentials: %w", err)
 	}
 
-	s.grpc.server = creds
+	// Create new gRPC server TLS credentials.
+	s.grpc.server = r
+	s.grpc.serverCreds = grpc.NewTLS(r.serverConfig())
 	return nil
 }
+
+// LoadGrpcTokenCreds loads per-RPC credentials that attach bearer tokens signed
+// with the current gRPC client cert. Client credentials must already be loaded.
+func (s *Store) LoadGrpcTokenCreds(c *config.Config) error {
+	if s.grpc.client == nil {
+		return errors.New("error loading grpc token credentials: grpc client credentials not loaded")
+	}
+
+	s.grpc.tokenCreds = pgrpc.NewTokenCredentials(s.grpc.client.certificate, c.Duration(config.KeyGrpcClientTokenTTL))
+	return nil
+}
+
+// LoadHTTPServerCreds loads TLS credentials for the HTTP server. The gRPC server
+// cert is reused if already loaded, as both serve the same identity, otherwise
+// it is loaded from the gRPC server cert files.
+func (s *Store) LoadHTTPServerCreds(c *config.Config) error {
+	if s.grpc.server != nil {
+		s.http.server = s.grpc.server
+		return nil
+	}
+
+	ca := c.String(config.KeyGrpcTLSCA)
+	cert := c.String(config.KeyGrpcServerCert)
+	key := c.String(config.KeyGrpcServerKey)
+
+	r, err := newCertReloader("http_server", ca, cert, key)
+	if err != nil {
+		return fmt.Errorf("error loading http server tls credentials: %w", err)
+	}
+
+	s.http.server = r
+	return nil
+}
+
+// HTTPServer returns an HTTP server TLS config, or nil if not loaded. If
+// verifyClient is set, clients must present a cert signed by the CA.
+func (s *Store) HTTPServer(verifyClient bool) *tls.Config {
+	if s.http.server == nil {
+		return nil
+	}
+
+	clientAuth := tls.NoClientCert
+	if verifyClient {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return s.http.server.newServerConfig(clientAuth, "h2", "http/1.1")
+}
+
+// HTTPClient returns an HTTP client TLS config that presents the gRPC client
+// cert, or an error if client credentials aren't loaded.
+func (s *Store) HTTPClient() (*tls.Config, error) {
+	if s.grpc.client == nil {
+		return nil, errors.New("grpc client credentials not loaded")
+	}
+
+	return s.grpc.client.clientConfig("")
+}
+
+// VerifyGrpcToken verifies a bearer token for an audience against the current
+// gRPC server CA pool, returning the identity of its signer.
+func (s *Store) VerifyGrpcToken(tok, audience string) (authz.Identity, error) {
+	if s.grpc.server == nil {
+		return authz.Identity{}, errors.New("grpc server credentials not loaded")
+	}
+
+	return token.Verify(tok, s.grpc.server.certPool(), audience)
+}
+
+// Watch periodically reloads any loaded TLS credentials whose files have changed,
+// and renews certs issued by the platform CA before they expire, until the
+// context is cancelled. Renewals are scheduled from the issued cert's lifetime,
+// so they don't depend on the reload interval, which disables reloads if <= 0.
+// Failed reloads and renewals are logged and the existing credentials continue
+// to be used.
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	var reload <-chan time.Time
+	if interval > 0 {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		reload = t.C
+	}
+
+	var renew <-chan time.Time
+	var timer *time.Timer
+	if s.issuer != nil {
+		timer = time.NewTimer(time.Until(s.issuer.renewAt()))
+		defer timer.Stop()
+		renew = timer.C
+	}
+
+	if reload == nil && renew == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reload:
+			s.reload()
+		case <-renew:
+			timer.Reset(time.Until(s.renew(ctx)))
+		}
+	}
+}
+
+// reload reloads all loaded TLS credentials.
+func (s *Store) reload() {
+	seen := make(map[*certReloader]bool)
+	for _, r := range []*certReloader{s.grpc.client, s.grpc.server, s.http.server} {
+		// Reloaders may be shared, e.g. issued creds are used for both client
+		// and server.
+		if r == nil || seen[r] {
+			continue
+		}
+		seen[r] = true
+
+		reloaded, err := r.reload()
+		if err != nil {
+			log.Error().Err(err).Str("credential", r.name).Msg("error reloading tls credentials")
+			continue
+		}
+
+		if reloaded {
+			log.Info().Str("credential", r.name).Time("expiry", r.certificate().Leaf.NotAfter).Msg("tls credentials reloaded")
+		}
+	}
+}
+
+// renew renews the issued cert, returning when it should next be renewed.
+// Failed renewals are retried after renewRetry.
+func (s *Store) renew(ctx context.Context) time.Time {
+	if err := s.issuer.renew(ctx); err != nil {
+		metrics.TLSReloadsTotal.WithLabelValues(s.issuer.r.name, "error").Inc()
+		log.Error().Err(err).Str("credential", s.issuer.r.name).Msg("error renewing tls credentials")
+		return time.Now().Add(renewRetry)
+	}
+	metrics.TLSReloadsTotal.WithLabelValues(s.issuer.r.name, "success").Inc()
+
+	log.Info().Str("credential", s.issuer.r.name).Time("expiry", s.issuer.r.certificate().Leaf.NotAfter).Msg("tls credentials renewed")
+
+	return s.issuer.renewAt()
+}
@@ -1 +1,60 @@
 package credentials
+
+import (
+	"crypto/tls"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/loshz/platform/internal/config"
+)
+
+func TestStoreHTTP(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	server := writeTestCerts(t, dir, time.Now().Add(time.Hour))
+
+	conf := config.New()
+	conf.Set(config.KeyGrpcTLSCA, path("ca.crt.pem"))
+	conf.Set(config.KeyGrpcServerCert, path("server.crt.pem"))
+	conf.Set(config.KeyGrpcServerKey, path("server.key.pem"))
+	conf.Set(config.KeyGrpcClientCert, path("client.crt.pem"))
+	conf.Set(config.KeyGrpcClientKey, path("client.key.pem"))
+
+	s := new(Store)
+	assert.Nil(t, s.HTTPServer(true))
+	_, err := s.HTTPClient()
+	assert.Error(t, err)
+
+	require.NoError(t, s.LoadHTTPServerCreds(conf))
+	require.NoError(t, s.LoadGrpcClientCreds(conf))
+
+	clientConfig, err := s.HTTPClient()
+	require.NoError(t, err)
+
+	// Assert the server cert is served, and client certs are only required for mTLS.
+	for clientAuth, verifyClient := range map[tls.ClientAuthType]bool{
+		tls.NoClientCert:               false,
+		tls.RequireAndVerifyClientCert: true,
+	} {
+		serverConfig := s.HTTPServer(verifyClient)
+		cert, err := handshake(clientConfig, serverConfig)
+		require.NoError(t, err)
+		assert.Equal(t, server.SerialNumber, cert.SerialNumber)
+
+		conn, err := serverConfig.GetConfigForClient(nil)
+		require.NoError(t, err)
+		assert.Equal(t, clientAuth, conn.ClientAuth)
+		assert.Equal(t, []string{"h2", "http/1.1"}, conn.NextProtos)
+	}
+
+	t.Run("TestSharedWithGrpc", func(t *testing.T) {
+		s := new(Store)
+		require.NoError(t, s.LoadGrpcServerCreds(conf))
+		require.NoError(t, s.LoadHTTPServerCreds(conf))
+		assert.Same(t, s.grpc.server, s.http.server)
+	})
+}
@@ -0,0 +1,154 @@
+package credentials
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	grpc "google.golang.org/grpc/credentials"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/ca"
+	"github.com/loshz/platform/internal/config"
+	pgrpc "github.com/loshz/platform/internal/grpc"
+)
+
+// renewAfter is the fraction of an issued cert's lifetime after which it is renewed.
+const renewAfter = 2.0 / 3.0
+
+// renewRetry is the delay before a failed renewal is retried.
+const renewRetry = 10 * time.Second
+
+// issuer requests certs for a service instance from the platform CA.
+type issuer struct {
+	// Address of the CA service, and the identity it must present.
+	addr, identity string
+
+	// UUID of the service instance.
+	instance string
+
+	// Timeout of issue requests.
+	timeout time.Duration
+
+	// Reloader serving the issued cert.
+	r *certReloader
+}
+
+// LoadIssuedCreds requests a cert for a service instance from the platform CA,
+// using a bootstrap token, and uses it for both gRPC client and server
+// credentials. Issued certs are renewed by Watch before they expire.
+//
+// Subsequent calls are no-ops, so it is safe to call when loading both client
+// and server credentials.
+func (s *Store) LoadIssuedCreds(ctx context.Context, c *config.Config, instance string) error {
+	if s.issuer != nil {
+		return nil
+	}
+
+	// Only the CA is loaded from file, the cert is set once issued.
+	r := &certReloader{name: "grpc_issued", ca: c.String(config.KeyGrpcTLSCA)}
+	if err := r.load(); err != nil {
+		return fmt.Errorf("error loading ca cert: %w", err)
+	}
+
+	addr := c.String(config.KeyCAAddr)
+	i := &issuer{
+		addr:     addr,
+		identity: c.StringMap(config.KeyGrpcClientIdentities)[addr],
+		instance: instance,
+		timeout:  c.Duration(config.KeyGrpcClientTimeout),
+		r:        r,
+	}
+
+	cert, err := i.issue(ctx, c.String(config.KeyCAToken))
+	if err != nil {
+		return fmt.Errorf("error issuing certificate: %w", err)
+	}
+	r.setCertificate(cert)
+
+	tlsConfig, err := r.clientConfig("")
+	if err != nil {
+		return fmt.Errorf("error loading grpc client tls credentials: %w", err)
+	}
+
+	s.issuer = i
+	s.grpc.client, s.grpc.server = r, r
+	s.grpc.clientCreds = grpc.NewTLS(tlsConfig)
+	s.grpc.serverCreds = grpc.NewTLS(r.serverConfig())
+	return nil
+}
+
+// renewAt returns the time the current cert should be renewed.
+func (i *issuer) renewAt() time.Time {
+	leaf := i.r.certificate().Leaf
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+
+	return leaf.NotBefore.Add(time.Duration(float64(lifetime) * renewAfter))
+}
+
+// renew requests a new cert, authenticating with the current cert.
+func (i *issuer) renew(ctx context.Context) error {
+	cert, err := i.issue(ctx, "")
+	if err != nil {
+		return err
+	}
+	i.r.setCertificate(cert)
+
+	return nil
+}
+
+// issue generates a new key and requests a cert for it from the CA. If a token
+// is given it is used to authenticate the request, otherwise the current cert is.
+func (i *issuer) issue(ctx context.Context, token string) (*tls.Certificate, error) {
+	key, csr, err := ca.NewCSR(i.instance)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := i.r.clientConfig(i.identity)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := pgrpc.DefaultClientPolicy()
+	policy.Timeout = i.timeout
+
+	conn, err := pgrpc.Dial(ctx, i.addr, grpc.NewTLS(tlsConfig), policy)
+	if err != nil {
+		return nil, fmt.Errorf("error dialing ca: %w", err)
+	}
+	defer conn.Close()
+
+	res, err := apiv1.NewCertificateServiceClient(conn).IssueCertificate(ctx, &apiv1.IssueCertificateRequest{
+		Csr:   csr,
+		Token: token,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	certs, err := parseCerts(res.GetCertificate())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing issued cert: %w", err)
+	}
+
+	// Ensure the issued cert is trusted by the current CA pool, and valid for
+	// both client and server auth as it is used for both, otherwise peers will
+	// reject it.
+	for _, usage := range []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth} {
+		if _, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:     i.r.certPool(),
+			KeyUsages: []x509.ExtKeyUsage{usage},
+		}); err != nil {
+			return nil, fmt.Errorf("error verifying issued cert: %w", err)
+		}
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{certs[0].Raw},
+		PrivateKey:  key,
+		Leaf:        certs[0],
+	}, nil
+}
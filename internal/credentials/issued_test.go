@@ -0,0 +1,167 @@
+package credentials
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	grpccreds "google.golang.org/grpc/credentials"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/authz"
+	"github.com/loshz/platform/internal/ca"
+	"github.com/loshz/platform/internal/config"
+	"github.com/loshz/platform/internal/uuid"
+)
+
+// testCAServer issues certs for any request, recording the caller.
+type testCAServer struct {
+	apiv1.UnimplementedCertificateServiceServer
+
+	authority *ca.Authority
+	peers     []authz.Identity
+	tokens    []string
+}
+
+func (s *testCAServer) IssueCertificate(ctx context.Context, req *apiv1.IssueCertificateRequest) (*apiv1.IssueCertificateResponse, error) {
+	s.peers = append(s.peers, authz.IdentityFromContext(ctx))
+	s.tokens = append(s.tokens, req.GetToken())
+
+	csr, err := ca.ParseCSR(req.GetCsr())
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := s.authority.Issue(csr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiv1.IssueCertificateResponse{Certificate: ca.EncodeCert(cert.Raw), Ca: s.authority.CertPEM()}, nil
+}
+
+// startTestCA starts a CA server on localhost, returning its address.
+func startTestCA(t *testing.T, srv *testCAServer) string {
+	t.Helper()
+
+	// Serve a cert issued for localhost.
+	key, data, err := ca.NewCSR(uuid.New("localhost").String())
+	require.NoError(t, err)
+	csr, err := ca.ParseCSR(data)
+	require.NoError(t, err)
+	cert, err := srv.authority.Issue(csr)
+	require.NoError(t, err)
+
+	creds := grpccreds.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{cert.Raw}, PrivateKey: key}},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    srv.authority.CertPool(),
+		MinVersion:   tls.VersionTLS13,
+	})
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	gs := grpc.NewServer(grpc.Creds(creds))
+	gs.RegisterService(&apiv1.CertificateService_ServiceDesc, srv)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	return lis.Addr().String()
+}
+
+func TestStoreIssued(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCerts(t, dir, time.Now().Add(time.Hour))
+
+	authority, err := ca.Load(filepath.Join(dir, "ca.crt.pem"), filepath.Join(dir, "ca.key.pem"), ca.DefaultTrustDomain, 30*time.Minute)
+	require.NoError(t, err)
+	srv := &testCAServer{authority: authority}
+	instance := uuid.New("eventd").String()
+
+	conf := config.New()
+	conf.Set(config.KeyGrpcTLSCA, filepath.Join(dir, "ca.crt.pem"))
+	conf.Set(config.KeyGrpcClientTimeout, 5*time.Second)
+	conf.Set(config.KeyCAAddr, startTestCA(t, srv))
+	conf.Set(config.KeyCAToken, "token")
+
+	s := new(Store)
+	require.NoError(t, s.LoadIssuedCreds(context.Background(), conf, instance))
+
+	// Assert the token was used to bootstrap without a client cert.
+	require.Len(t, srv.peers, 1)
+	assert.True(t, srv.peers[0].IsAnonymous())
+	assert.Equal(t, "token", srv.tokens[0])
+
+	// Assert the issued cert is used for both client and server creds.
+	clientConfig, err := s.grpc.client.clientConfig("spiffe://platform/eventd")
+	require.NoError(t, err)
+	cert, err := handshake(clientConfig, s.grpc.server.serverConfig())
+	require.NoError(t, err)
+	assert.Equal(t, instance, cert.Subject.CommonName)
+
+	t.Run("TestLoadedOnce", func(t *testing.T) {
+		require.NoError(t, s.LoadIssuedCreds(context.Background(), conf, instance))
+		assert.Len(t, srv.peers, 1)
+	})
+
+	t.Run("TestRenewAt", func(t *testing.T) {
+		// Certs are backdated by a minute, so are renewed 2/3 through 31m.
+		leaf := s.grpc.client.certificate().Leaf
+		assert.WithinDuration(t, leaf.NotBefore.Add(31*time.Minute*2/3), s.issuer.renewAt(), time.Second)
+	})
+
+	t.Run("TestRenew", func(t *testing.T) {
+		before := s.grpc.client.certificate()
+		require.NoError(t, s.issuer.renew(context.Background()))
+
+		// Assert renewals authenticate with the current cert instead of a token.
+		require.Len(t, srv.peers, 2)
+		assert.Equal(t, instance, srv.peers[1].Instance)
+		assert.Empty(t, srv.tokens[1])
+		assert.NotEqual(t, before.Leaf.SerialNumber, s.grpc.server.certificate().Leaf.SerialNumber)
+	})
+}
+
+func TestStoreWatchIssued(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCerts(t, dir, time.Now().Add(time.Hour))
+
+	// Issued certs are backdated by a minute, so a 31s TTL is due for renewal
+	// about 1s after it is issued.
+	authority, err := ca.Load(filepath.Join(dir, "ca.crt.pem"), filepath.Join(dir, "ca.key.pem"), ca.DefaultTrustDomain, 31*time.Second)
+	require.NoError(t, err)
+	srv := &testCAServer{authority: authority}
+
+	conf := config.New()
+	conf.Set(config.KeyGrpcTLSCA, filepath.Join(dir, "ca.crt.pem"))
+	conf.Set(config.KeyGrpcClientTimeout, 5*time.Second)
+	conf.Set(config.KeyCAAddr, startTestCA(t, srv))
+	conf.Set(config.KeyCAToken, "token")
+
+	s := new(Store)
+	require.NoError(t, s.LoadIssuedCreds(context.Background(), conf, uuid.New("eventd").String()))
+	before := s.grpc.server.certificate().Leaf.SerialNumber
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Watch(ctx, 0)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// Assert issued certs are renewed when due, even if reloads are disabled.
+	assert.Eventually(t, func() bool {
+		return s.grpc.server.certificate().Leaf.SerialNumber.Cmp(before) != 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
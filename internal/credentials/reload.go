@@ -0,0 +1,273 @@
+package credentials
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	pgrpc "github.com/loshz/platform/internal/grpc"
+	"github.com/loshz/platform/internal/metrics"
+)
+
+// certReloader serves a TLS cert/key pair and CA pool loaded from files,
+// reloading them when any of the files change. New handshakes use the
+// current material, so certs can be rotated without restarting.
+//
+// If the cert and key paths are empty, only the CA pool is loaded from file
+// and the cert is set in memory, e.g. when issued by the platform CA.
+type certReloader struct {
+	// Name of the credential, used to label metrics.
+	name string
+
+	// Paths of the CA cert, cert and key.
+	ca, cert, key string
+
+	mtx     sync.RWMutex
+	current *tls.Certificate
+	pool    *x509.CertPool
+	modTime map[string]time.Time
+}
+
+// newCertReloader creates a certReloader and loads the initial cert material.
+func newCertReloader(name, ca, cert, key string) (*certReloader, error) {
+	r := &certReloader{
+		name: name,
+		ca:   ca,
+		cert: cert,
+		key:  key,
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// certificate returns the current cert.
+func (r *certReloader) certificate() *tls.Certificate {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.current
+}
+
+// certPool returns the current CA pool.
+func (r *certReloader) certPool() *x509.CertPool {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.pool
+}
+
+// setCertificate replaces the current cert.
+func (r *certReloader) setCertificate(cert *tls.Certificate) {
+	r.mtx.Lock()
+	r.current = cert
+	r.mtx.Unlock()
+
+	metrics.TLSCertExpiry.WithLabelValues(r.name, "cert").Set(float64(cert.Leaf.NotAfter.Unix()))
+}
+
+// paths returns the paths of all files the cert material is loaded from.
+func (r *certReloader) paths() []string {
+	if r.cert == "" {
+		return []string{r.ca}
+	}
+
+	return []string{r.ca, r.cert, r.key}
+}
+
+// serverConfig returns a server TLS config that serves the current cert and
+// requires client certs signed by the current CA pool.
+func (r *certReloader) serverConfig() *tls.Config {
+	return r.newServerConfig(tls.RequireAndVerifyClientCert, "h2")
+}
+
+// newServerConfig returns a server TLS config that serves the current cert,
+// verifying client certs against the current CA pool with the given policy.
+func (r *certReloader) newServerConfig(clientAuth tls.ClientAuthType, protos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS13,
+		// The CA pool can't be changed after a config is created, so a new config
+		// is created for every handshake.
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return &tls.Config{
+				Certificates: []tls.Certificate{*r.certificate()},
+				ClientAuth:   clientAuth,
+				ClientCAs:    r.certPool(),
+				MinVersion:   tls.VersionTLS13,
+				NextProtos:   protos,
+			}, nil
+		},
+	}
+}
+
+// clientConfig returns a client TLS config that presents the current cert and
+// expects servers to present the given identity, signed by the current CA pool.
+// See pgrpc.VerifyServer for supported identities.
+func (r *certReloader) clientConfig(identity string) (*tls.Config, error) {
+	verify, err := pgrpc.VerifyServer(r.certPool, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			// An empty cert is sent if no cert has been issued yet.
+			if cert := r.certificate(); cert != nil {
+				return cert, nil
+			}
+			return &tls.Certificate{}, nil
+		},
+		MinVersion: tls.VersionTLS13,
+		// The default verification uses a fixed CA pool, so it is disabled in
+		// favour of verifying against the current pool.
+		InsecureSkipVerify: true,
+		VerifyConnection:   verify,
+	}, nil
+}
+
+// reload loads the cert material if any of the files have been modified since
+// they were last loaded. It reports whether the material was reloaded. On
+// error, the existing material continues to be served.
+func (r *certReloader) reload() (bool, error) {
+	changed, err := r.changed()
+	if err != nil || !changed {
+		return false, err
+	}
+
+	if err := r.load(); err != nil {
+		metrics.TLSReloadsTotal.WithLabelValues(r.name, "error").Inc()
+		return false, err
+	}
+	metrics.TLSReloadsTotal.WithLabelValues(r.name, "success").Inc()
+
+	return true, nil
+}
+
+// changed reports whether any of the files have been modified since they were
+// last loaded.
+func (r *certReloader) changed() (bool, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	for _, path := range r.paths() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+
+		if !info.ModTime().Equal(r.modTime[path]) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// load reads and parses the cert material, replacing the current material if
+// successful.
+func (r *certReloader) load() error {
+	// Record modification times before reading so concurrent writes are picked
+	// up by the next reload.
+	modTime := make(map[string]time.Time, 3)
+	for _, path := range r.paths() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		modTime[path] = info.ModTime()
+	}
+
+	var cert *tls.Certificate
+	if r.cert != "" {
+		pair, err := tls.LoadX509KeyPair(r.cert, r.key)
+		if err != nil {
+			return fmt.Errorf("error loading cert/key pair: %w", err)
+		}
+
+		// The leaf is only parsed by default in newer versions of Go.
+		if pair.Leaf == nil {
+			if pair.Leaf, err = x509.ParseCertificate(pair.Certificate[0]); err != nil {
+				return fmt.Errorf("error parsing cert: %w", err)
+			}
+		}
+		cert = &pair
+	}
+
+	caData, err := os.ReadFile(r.ca)
+	if err != nil {
+		return fmt.Errorf("error reading ca cert: %w", err)
+	}
+
+	cas, err := parseCerts(caData)
+	if err != nil {
+		return fmt.Errorf("error parsing ca cert: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	for _, ca := range cas {
+		pool.AddCert(ca)
+	}
+
+	r.mtx.Lock()
+	if cert != nil {
+		r.current = cert
+	}
+	r.pool = pool
+	r.modTime = modTime
+	r.mtx.Unlock()
+
+	// Export the expiry of the leaf cert and the earliest expiring CA.
+	if cert != nil {
+		metrics.TLSCertExpiry.WithLabelValues(r.name, "cert").Set(float64(cert.Leaf.NotAfter.Unix()))
+	}
+	metrics.TLSCertExpiry.WithLabelValues(r.name, "ca").Set(float64(earliestExpiry(cas).Unix()))
+
+	return nil
+}
+
+// parseCerts parses all PEM encoded certs.
+func parseCerts(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no valid certificates found")
+	}
+
+	return certs, nil
+}
+
+// earliestExpiry returns the earliest expiry time of the given certs.
+func earliestExpiry(certs []*x509.Certificate) time.Time {
+	expiry := certs[0].NotAfter
+	for _, cert := range certs[1:] {
+		if cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+	}
+
+	return expiry
+}
@@ -0,0 +1,163 @@
+package credentials
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/loshz/platform/internal/config"
+	"github.com/loshz/platform/internal/metrics"
+)
+
+// writeTestCerts generates a CA with server and client certs and writes them to
+// a directory, returning the server cert.
+func writeTestCerts(t *testing.T, dir string, expiry time.Time) *x509.Certificate {
+	t.Helper()
+
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              expiry.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caKey := writeTestCert(t, dir, "ca", ca, nil, nil)
+
+	server := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     expiry,
+	}
+	writeTestCert(t, dir, "server", server, ca, caKey)
+
+	client := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     expiry,
+	}
+	writeTestCert(t, dir, "client", client, ca, caKey)
+
+	return server
+}
+
+// writeTestCert signs a cert with a parent, or self-signs it if parent is nil,
+// and writes the cert and key to a directory.
+func writeTestCert(t *testing.T, dir, name string, tmpl, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".key.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return key
+}
+
+// handshake performs a TLS handshake between a client and server config,
+// returning the cert presented by the server.
+func handshake(client, server *tls.Config) (*x509.Certificate, error) {
+	c, s := net.Pipe()
+	defer c.Close()
+	defer s.Close()
+
+	go func() { _ = tls.Server(s, server).Handshake() }()
+
+	client = client.Clone()
+	client.ServerName = "localhost"
+	conn := tls.Client(c, client)
+	if err := conn.Handshake(); err != nil {
+		return nil, err
+	}
+
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestStoreReload(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	server := writeTestCerts(t, dir, expiry)
+
+	conf := config.New()
+	conf.Set(config.KeyGrpcTLSCA, path("ca.crt.pem"))
+	conf.Set(config.KeyGrpcServerCert, path("server.crt.pem"))
+	conf.Set(config.KeyGrpcServerKey, path("server.key.pem"))
+	conf.Set(config.KeyGrpcClientCert, path("client.crt.pem"))
+	conf.Set(config.KeyGrpcClientKey, path("client.key.pem"))
+
+	s := new(Store)
+	require.NoError(t, s.LoadGrpcServerCreds(conf))
+	require.NoError(t, s.LoadGrpcClientCreds(conf))
+
+	serverConfig := s.grpc.server.serverConfig()
+	clientConfig, err := s.grpc.client.clientConfig("")
+	require.NoError(t, err)
+
+	// Assert the initial certs are served and expiry is exported.
+	cert, err := handshake(clientConfig, serverConfig)
+	require.NoError(t, err)
+	assert.Equal(t, server.SerialNumber, cert.SerialNumber)
+	assert.Equal(t, float64(expiry.Unix()), testutil.ToFloat64(metrics.TLSCertExpiry.WithLabelValues("grpc_server", "cert")))
+
+	t.Run("TestUnchanged", func(t *testing.T) {
+		reloaded, err := s.grpc.server.reload()
+		require.NoError(t, err)
+		assert.False(t, reloaded)
+	})
+
+	t.Run("TestRotated", func(t *testing.T) {
+		// Rotate the CA and all certs.
+		expiry := expiry.Add(time.Hour)
+		server := writeTestCerts(t, dir, expiry)
+
+		// Ensure modification times change regardless of file system precision.
+		mod := time.Now().Add(time.Minute)
+		for _, name := range []string{"ca", "server", "client"} {
+			require.NoError(t, os.Chtimes(path(name+".crt.pem"), mod, mod))
+		}
+		s.reload()
+
+		// Assert new handshakes use the rotated certs without recreating configs.
+		cert, err := handshake(clientConfig, serverConfig)
+		require.NoError(t, err)
+		assert.Equal(t, server.SerialNumber, cert.SerialNumber)
+		assert.Equal(t, float64(expiry.Unix()), testutil.ToFloat64(metrics.TLSCertExpiry.WithLabelValues("grpc_server", "cert")))
+	})
+
+	t.Run("TestInvalid", func(t *testing.T) {
+		before := s.grpc.server.certificate()
+		require.NoError(t, os.WriteFile(path("server.key.pem"), []byte("invalid"), 0o600))
+
+		// Assert the existing cert is still served.
+		_, err := s.grpc.server.reload()
+		assert.Error(t, err)
+		assert.Equal(t, before, s.grpc.server.certificate())
+	})
+}
@@ -11,18 +11,38 @@ import (
 	"google.golang.org/grpc/status"
 
 	apiv1 "github.com/loshz/platform/internal/api/v1"
+	pgrpc "github.com/loshz/platform/internal/grpc"
 )
 
+// CallTypes describes how calls to discovery methods may be safely repeated.
+// Registration is keyed by service uuid, so all writes are idempotent. KV writes
+// may be conditional on the key revision, so are never repeated.
+var CallTypes = map[string]pgrpc.CallType{
+	apiv1.DiscoveryService_RegisterService_FullMethodName:   pgrpc.CallIdempotent,
+	apiv1.DiscoveryService_DeregisterService_FullMethodName: pgrpc.CallIdempotent,
+	apiv1.DiscoveryService_GetServices_FullMethodName:       pgrpc.CallHedged,
+	apiv1.KVService_Get_FullMethodName:                      pgrpc.CallHedged,
+	apiv1.KVService_List_FullMethodName:                     pgrpc.CallHedged,
+}
+
 type Service struct {
 	client apiv1.DiscoveryServiceClient
+	kv     apiv1.KVServiceClient
 }
 
-func (s *Service) Start(ctx context.Context, addr string, creds credentials.TransportCredentials) error {
-	conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(creds))
+// KV returns a client for the KV store provided by the discovery service.
+func (s *Service) KV() apiv1.KVServiceClient { return s.kv }
+
+// Start dials the discovery service, applying the given call policy and dial
+// options to all methods.
+func (s *Service) Start(ctx context.Context, addr string, creds credentials.TransportCredentials, policy pgrpc.ClientPolicy, opts ...grpc.DialOption) error {
+	policy.Methods = CallTypes
+	conn, err := pgrpc.Dial(ctx, addr, creds, policy, opts...)
 	if err != nil {
 		return fmt.Errorf("error dialing discovery service: %w", err)
 	}
 	s.client = apiv1.NewDiscoveryServiceClient(conn)
+	s.kv = apiv1.NewKVServiceClient(conn)
 
 	go func() {
 		<-ctx.Done()
@@ -0,0 +1,249 @@
+package discoveryd
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/authz"
+)
+
+// watchBuffer is the no. of events buffered for each watcher. Watchers that fall
+// further behind are closed, so they can watch again from a new snapshot.
+const watchBuffer = 64
+
+// Error messages returned by the KV store.
+var (
+	MsgInvalidKey       = "error: invalid key '%s'"
+	MsgKeyNotFound      = "error: key '%s' not found"
+	MsgRevisionMismatch = "error: key '%s' revision is %d"
+)
+
+// KVServer is an in memory, hierarchical key/value store. Every change
+// increments the store revision, which is recorded against the changed key.
+type KVServer struct {
+	apiv1.UnimplementedKVServiceServer
+
+	mtx      sync.RWMutex
+	kvs      map[string]*apiv1.KeyValue
+	revision int64
+	watchers map[*kvWatcher]struct{}
+
+	// Closed to stop all watchers on shutdown.
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// kvWatcher receives events for keys with a given prefix.
+type kvWatcher struct {
+	prefix string
+	events chan *apiv1.KeyEvent
+	// Closed if the watcher falls behind.
+	overflow chan struct{}
+}
+
+func NewKVServer() *KVServer {
+	return &KVServer{
+		kvs:      make(map[string]*apiv1.KeyValue),
+		watchers: make(map[*kvWatcher]struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Stop closes all watch streams so the gRPC server can gracefully stop.
+func (kv *KVServer) Stop() {
+	kv.stopOnce.Do(func() { close(kv.done) })
+}
+
+// Get returns the value of a key.
+func (kv *KVServer) Get(_ context.Context, req *apiv1.GetKeyRequest) (*apiv1.GetKeyResponse, error) {
+	key := req.GetKey()
+	if key == "" {
+		return nil, status.Errorf(codes.InvalidArgument, MsgMissingRequiredField, "key")
+	}
+
+	kv.mtx.RLock()
+	entry, ok := kv.kvs[key]
+	kv.mtx.RUnlock()
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, MsgKeyNotFound, key)
+	}
+
+	return &apiv1.GetKeyResponse{Kv: entry}, nil
+}
+
+// Put sets the value of a key. If compare is set, the key is only put if its
+// revision matches, where 0 means the key must not exist.
+func (kv *KVServer) Put(ctx context.Context, req *apiv1.PutKeyRequest) (*apiv1.PutKeyResponse, error) {
+	key := req.GetKey()
+	if !validKey(key) {
+		return nil, status.Errorf(codes.InvalidArgument, MsgInvalidKey, key)
+	}
+
+	kv.mtx.Lock()
+	defer kv.mtx.Unlock()
+
+	if req.GetCompare() {
+		if rev := kv.kvs[key].GetRevision(); rev != req.GetRevision() {
+			return nil, status.Errorf(codes.FailedPrecondition, MsgRevisionMismatch, key, rev)
+		}
+	}
+
+	kv.revision++
+	entry := &apiv1.KeyValue{Key: key, Value: req.GetValue(), Revision: kv.revision}
+	kv.kvs[key] = entry
+	kv.notify(&apiv1.KeyEvent{Kv: entry})
+
+	// Values may be secret, so are never logged.
+	log.Info().Ctx(ctx).
+		Bool("audit", true).
+		Str("identity", authz.IdentityFromContext(ctx).String()).
+		Str("key", key).
+		Int64("revision", entry.Revision).
+		Msg("key put")
+
+	return &apiv1.PutKeyResponse{Kv: entry}, nil
+}
+
+// Delete removes a key. If compare is set, the key is only deleted if its
+// revision matches.
+func (kv *KVServer) Delete(ctx context.Context, req *apiv1.DeleteKeyRequest) (*apiv1.DeleteKeyResponse, error) {
+	key := req.GetKey()
+	if key == "" {
+		return nil, status.Errorf(codes.InvalidArgument, MsgMissingRequiredField, "key")
+	}
+
+	kv.mtx.Lock()
+	defer kv.mtx.Unlock()
+
+	entry, ok := kv.kvs[key]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, MsgKeyNotFound, key)
+	}
+	if req.GetCompare() && entry.GetRevision() != req.GetRevision() {
+		return nil, status.Errorf(codes.FailedPrecondition, MsgRevisionMismatch, key, entry.GetRevision())
+	}
+
+	kv.revision++
+	delete(kv.kvs, key)
+	kv.notify(&apiv1.KeyEvent{Kv: &apiv1.KeyValue{Key: key, Revision: kv.revision}, Deleted: true})
+
+	log.Info().Ctx(ctx).
+		Bool("audit", true).
+		Str("identity", authz.IdentityFromContext(ctx).String()).
+		Str("key", key).
+		Int64("revision", kv.revision).
+		Msg("key deleted")
+
+	return &apiv1.DeleteKeyResponse{Kv: entry}, nil
+}
+
+// List returns all keys with a given prefix sorted by key. An empty prefix
+// returns all keys.
+func (kv *KVServer) List(_ context.Context, req *apiv1.ListKeysRequest) (*apiv1.ListKeysResponse, error) {
+	kv.mtx.RLock()
+	defer kv.mtx.RUnlock()
+
+	return &apiv1.ListKeysResponse{
+		Kvs:      kv.list(req.GetPrefix()),
+		Revision: kv.revision,
+	}, nil
+}
+
+// Watch streams a snapshot of all keys with a given prefix, followed by each
+// change to them. Watchers that fall behind are closed with ResourceExhausted.
+func (kv *KVServer) Watch(req *apiv1.WatchKeysRequest, stream apiv1.KVService_WatchServer) error {
+	w := &kvWatcher{
+		prefix:   req.GetPrefix(),
+		events:   make(chan *apiv1.KeyEvent, watchBuffer),
+		overflow: make(chan struct{}),
+	}
+
+	// Take the snapshot and start watching atomically, so no changes are missed.
+	kv.mtx.Lock()
+	kvs := kv.list(w.prefix)
+	kv.watchers[w] = struct{}{}
+	kv.mtx.Unlock()
+
+	defer func() {
+		kv.mtx.Lock()
+		delete(kv.watchers, w)
+		kv.mtx.Unlock()
+	}()
+
+	snapshot := &apiv1.WatchKeysResponse{Snapshot: true, Events: make([]*apiv1.KeyEvent, 0, len(kvs))}
+	for _, entry := range kvs {
+		snapshot.Events = append(snapshot.Events, &apiv1.KeyEvent{Kv: entry})
+	}
+	if err := stream.Send(snapshot); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case event := <-w.events:
+			if err := stream.Send(&apiv1.WatchKeysResponse{Events: []*apiv1.KeyEvent{event}}); err != nil {
+				return err
+			}
+		case <-w.overflow:
+			return status.Error(codes.ResourceExhausted, "error: watcher fell behind")
+		case <-kv.done:
+			return status.Error(codes.Unavailable, "error: server shutting down")
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// list returns all keys with a given prefix sorted by key. The caller must
+// hold the lock.
+func (kv *KVServer) list(prefix string) []*apiv1.KeyValue {
+	kvs := make([]*apiv1.KeyValue, 0)
+	for key, entry := range kv.kvs {
+		if strings.HasPrefix(key, prefix) {
+			kvs = append(kvs, entry)
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+
+	return kvs
+}
+
+// notify sends an event to all watchers of the key, closing any that have
+// fallen behind. The caller must hold the write lock.
+func (kv *KVServer) notify(event *apiv1.KeyEvent) {
+	for w := range kv.watchers {
+		if !strings.HasPrefix(event.GetKv().GetKey(), w.prefix) {
+			continue
+		}
+
+		select {
+		case w.events <- event:
+		default:
+			close(w.overflow)
+			delete(kv.watchers, w)
+		}
+	}
+}
+
+// validKey reports whether a key is a slash separated path without empty
+// segments or whitespace, e.g. service/eventd/service.log.level.
+func validKey(key string) bool {
+	if key == "" || strings.ContainsAny(key, " \t\r\n") {
+		return false
+	}
+
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" {
+			return false
+		}
+	}
+
+	return true
+}
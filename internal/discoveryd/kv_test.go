@@ -0,0 +1,132 @@
+package discoveryd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+)
+
+// mockWatchServer records responses sent to a watch stream.
+type mockWatchServer struct {
+	grpc.ServerStream
+	ctx context.Context
+	res chan *apiv1.WatchKeysResponse
+}
+
+func (m *mockWatchServer) Context() context.Context { return m.ctx }
+
+func (m *mockWatchServer) Send(res *apiv1.WatchKeysResponse) error {
+	m.res <- res
+	return nil
+}
+
+func TestKVServer(t *testing.T) {
+	kv := NewKVServer()
+	ctx := context.Background()
+
+	t.Run("TestInvalidKey", func(t *testing.T) {
+		for _, key := range []string{"", "/service", "service//eventd", "service/", "a key"} {
+			_, err := kv.Put(ctx, &apiv1.PutKeyRequest{Key: key, Value: "value"})
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), key)
+		}
+	})
+
+	t.Run("TestPutGet", func(t *testing.T) {
+		put, err := kv.Put(ctx, &apiv1.PutKeyRequest{Key: "service/eventd/service.log.level", Value: "debug"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), put.GetKv().GetRevision())
+
+		got, err := kv.Get(ctx, &apiv1.GetKeyRequest{Key: "service/eventd/service.log.level"})
+		require.NoError(t, err)
+		assert.Equal(t, "debug", got.GetKv().GetValue())
+
+		_, err = kv.Get(ctx, &apiv1.GetKeyRequest{Key: "service/eventd/does.not.exist"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("TestCompareAndSwap", func(t *testing.T) {
+		// Assert keys are only put if the revision matches.
+		_, err := kv.Put(ctx, &apiv1.PutKeyRequest{Key: "service/eventd/service.log.level", Value: "warn", Compare: true, Revision: 0})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		put, err := kv.Put(ctx, &apiv1.PutKeyRequest{Key: "service/eventd/service.log.level", Value: "warn", Compare: true, Revision: 1})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), put.GetKv().GetRevision())
+
+		// Assert a zero revision creates a key that doesn't exist.
+		_, err = kv.Put(ctx, &apiv1.PutKeyRequest{Key: "platform/service.log.level", Value: "info", Compare: true, Revision: 0})
+		require.NoError(t, err)
+
+		_, err = kv.Delete(ctx, &apiv1.DeleteKeyRequest{Key: "platform/service.log.level", Compare: true, Revision: 1})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("TestList", func(t *testing.T) {
+		_, err := kv.Put(ctx, &apiv1.PutKeyRequest{Key: "service/eventd/grpc.server.log.sample", Value: "0.5"})
+		require.NoError(t, err)
+
+		res, err := kv.List(ctx, &apiv1.ListKeysRequest{Prefix: "service/eventd/"})
+		require.NoError(t, err)
+
+		// Assert keys are sorted and filtered by prefix.
+		require.Len(t, res.GetKvs(), 2)
+		assert.Equal(t, "service/eventd/grpc.server.log.sample", res.GetKvs()[0].GetKey())
+		assert.Equal(t, "service/eventd/service.log.level", res.GetKvs()[1].GetKey())
+		assert.Equal(t, int64(4), res.GetRevision())
+	})
+
+	t.Run("TestDelete", func(t *testing.T) {
+		_, err := kv.Delete(ctx, &apiv1.DeleteKeyRequest{Key: "platform/service.log.level"})
+		require.NoError(t, err)
+
+		_, err = kv.Delete(ctx, &apiv1.DeleteKeyRequest{Key: "platform/service.log.level"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestKVServerWatch(t *testing.T) {
+	kv := NewKVServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := kv.Put(ctx, &apiv1.PutKeyRequest{Key: "service/eventd/service.log.level", Value: "debug"})
+	require.NoError(t, err)
+
+	stream := &mockWatchServer{ctx: ctx, res: make(chan *apiv1.WatchKeysResponse, 10)}
+	errCh := make(chan error, 1)
+	go func() { errCh <- kv.Watch(&apiv1.WatchKeysRequest{Prefix: "service/eventd/"}, stream) }()
+
+	// Assert a snapshot of existing keys is sent first.
+	res := <-stream.res
+	assert.True(t, res.GetSnapshot())
+	require.Len(t, res.GetEvents(), 1)
+	assert.Equal(t, "debug", res.GetEvents()[0].GetKv().GetValue())
+
+	// Assert only changes to keys with the prefix are sent.
+	_, err = kv.Put(ctx, &apiv1.PutKeyRequest{Key: "service/trafficd/service.log.level", Value: "warn"})
+	require.NoError(t, err)
+	_, err = kv.Delete(ctx, &apiv1.DeleteKeyRequest{Key: "service/eventd/service.log.level"})
+	require.NoError(t, err)
+
+	select {
+	case res := <-stream.res:
+		assert.False(t, res.GetSnapshot())
+		require.Len(t, res.GetEvents(), 1)
+		assert.True(t, res.GetEvents()[0].GetDeleted())
+		assert.Equal(t, "service/eventd/service.log.level", res.GetEvents()[0].GetKv().GetKey())
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for watch event")
+	}
+
+	// Assert watchers are closed when the server stops.
+	kv.Stop()
+	assert.Equal(t, codes.Unavailable, status.Code(<-errCh))
+}
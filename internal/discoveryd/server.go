@@ -1,9 +1,10 @@
-package main
+package discoveryd
 
 import (
 	"context"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -20,6 +21,10 @@ const DelimeterAll = "*"
 // missing request fields.
 var MsgMissingRequiredField = "error: missing required '%s' field"
 
+// DefaultEvictionWindow is the default time after which services that have not
+// re-registered are evicted.
+const DefaultEvictionWindow = 5 * time.Minute
+
 // Services represents a map of individually registered services keyed by the
 // service uuid.
 type Services map[string]*apiv1.Service
@@ -29,24 +34,36 @@ type DiscoveryServer struct {
 
 	mtx      sync.RWMutex
 	services Services
+
+	// Time after which services that have not re-registered are evicted.
+	window atomic.Int64
 }
 
 func NewDiscoveryServer() *DiscoveryServer {
-	return &DiscoveryServer{
+	ds := &DiscoveryServer{
 		services: make(Services),
 	}
+	ds.SetEvictionWindow(DefaultEvictionWindow)
+
+	return ds
+}
+
+// SetEvictionWindow sets the time after which services that have not
+// re-registered are evicted. It is safe to call while evicting services.
+func (ds *DiscoveryServer) SetEvictionWindow(d time.Duration) {
+	ds.window.Store(int64(d))
 }
 
-// EvictExpiredServices removes services that have a registration timestamp greater
-// then 5 minutes.
+// EvictExpiredServices removes services that have a registration timestamp older
+// than the eviction window.
 func (ds *DiscoveryServer) EvictExpiredServices() {
 	ds.mtx.Lock()
 	defer ds.mtx.Unlock()
 
 	// Loop through all services and check if the current timestamp is
 	// after the expiry threshold.
+	expired := time.Now().Add(-time.Duration(ds.window.Load()))
 	for uuid, svc := range ds.services {
-		expired := time.Now().Add(-5 * time.Minute)
 		if time.Unix(svc.LastSeen, 0).Before(expired) {
 			log.Info().Msgf("expired service evicted: %s", uuid)
 			delete(ds.services, uuid)
@@ -54,9 +71,12 @@ func (ds *DiscoveryServer) EvictExpiredServices() {
 	}
 }
 
-func (ds *DiscoveryServer) StartEvictionProcess(ctx context.Context) {
-	log.Info().Msg("polling for expired services every 60s")
-	t := time.NewTicker(60 * time.Second)
+// StartEvictionProcess evicts expired services at a given interval until the
+// context is done.
+func (ds *DiscoveryServer) StartEvictionProcess(ctx context.Context, interval time.Duration) {
+	log.Info().Msgf("polling for expired services every %s", interval)
+	t := time.NewTicker(interval)
+	defer t.Stop()
 	for {
 		select {
 		case <-t.C:
@@ -68,7 +88,7 @@ func (ds *DiscoveryServer) StartEvictionProcess(ctx context.Context) {
 }
 
 // RegisterService validates service data and stores it in the DiscoveryServer.
-func (ds *DiscoveryServer) RegisterService(_ context.Context, req *apiv1.RegisterServiceRequest) (*apiv1.RegisterServiceResponse, error) {
+func (ds *DiscoveryServer) RegisterService(ctx context.Context, req *apiv1.RegisterServiceRequest) (*apiv1.RegisterServiceResponse, error) {
 	svc := req.GetService()
 	if svc == nil {
 		return nil, status.Errorf(codes.InvalidArgument, MsgMissingRequiredField, "service")
@@ -83,7 +103,7 @@ func (ds *DiscoveryServer) RegisterService(_ context.Context, req *apiv1.Registe
 	ds.services[uuid] = svc
 	ds.mtx.Unlock()
 
-	log.Info().Msgf("service registered: %s", uuid)
+	log.Info().Ctx(ctx).Msgf("service registered: %s", uuid)
 
 	return &apiv1.RegisterServiceResponse{
 		Service: svc,
@@ -91,7 +111,7 @@ func (ds *DiscoveryServer) RegisterService(_ context.Context, req *apiv1.Registe
 }
 
 // RegisterService deletes a service from the DiscoveryServer.
-func (ds *DiscoveryServer) DeregisterService(_ context.Context, req *apiv1.DeregisterServiceRequest) (*apiv1.DeregisterServiceResponse, error) {
+func (ds *DiscoveryServer) DeregisterService(ctx context.Context, req *apiv1.DeregisterServiceRequest) (*apiv1.DeregisterServiceResponse, error) {
 	uuid := req.GetUuid()
 	if uuid == "" {
 		return nil, status.Errorf(codes.InvalidArgument, MsgMissingRequiredField, "uuid")
@@ -101,7 +121,7 @@ func (ds *DiscoveryServer) DeregisterService(_ context.Context, req *apiv1.Dereg
 	delete(ds.services, uuid)
 	ds.mtx.Unlock()
 
-	log.Info().Msgf("service deregistered: %s", uuid)
+	log.Info().Ctx(ctx).Msgf("service deregistered: %s", uuid)
 
 	return &apiv1.DeregisterServiceResponse{
 		Uuid: uuid,
@@ -1,4 +1,4 @@
-package main
+package discoveryd
 
 import (
 	"context"
@@ -34,6 +34,21 @@ func TestEvictExpiredServices(t *testing.T) {
 	assert.Nil(t, server.services["expired-service-a"])
 	assert.Nil(t, server.services["expired-service-b"])
 	assert.NotNil(t, server.services["service-a"])
+
+	t.Run("TestEvictionWindow", func(t *testing.T) {
+		server.services["service-b"] = &apiv1.Service{
+			LastSeen: time.Now().Add(-2 * time.Minute).Unix(),
+		}
+
+		// Assert services are evicted using the current window.
+		server.EvictExpiredServices()
+		assert.NotNil(t, server.services["service-b"])
+
+		server.SetEvictionWindow(time.Minute)
+		server.EvictExpiredServices()
+		assert.Nil(t, server.services["service-b"])
+		assert.NotNil(t, server.services["service-a"])
+	})
 }
 
 func TestRegisterService(t *testing.T) {
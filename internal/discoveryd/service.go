@@ -0,0 +1,62 @@
+// Package discoveryd implements the discovery service, which stores service
+// registrations and distributes config via a KV store.
+package discoveryd
+
+import (
+	"context"
+	"net/http"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/config"
+	"github.com/loshz/platform/internal/credentials"
+	pgrpc "github.com/loshz/platform/internal/grpc"
+	"github.com/loshz/platform/internal/service"
+)
+
+// Name of the discovery service.
+const Name = "discoveryd"
+
+// Configure loads the credentials and config required to run the service. It
+// must be called after config sources are loaded.
+func Configure(s *service.Service) {
+	s.LoadCredentials(credentials.GrpcServer)
+	s.LoadDiscoveryServerConfig()
+}
+
+// Run initializes the discovery and KV servers.
+func Run(ctx context.Context, s *service.Service) error {
+	// Create a discovery server and run the service eviction process in the background.
+	ds := NewDiscoveryServer()
+	ds.SetEvictionWindow(s.Config().Duration(config.KeyDiscoveryEvictionWindow))
+	s.Go("eviction", func(ctx context.Context) error {
+		ds.StartEvictionProcess(ctx, s.Config().Duration(config.KeyDiscoveryEvictionInterval))
+		return nil
+	})
+
+	// Apply changes to the eviction window while running.
+	s.Config().Watch(config.KeyDiscoveryEvictionWindow, func(key string) {
+		ds.SetEvictionWindow(s.Config().Duration(key))
+	})
+
+	// Create a KV store used to distribute config.
+	kv := NewKVServer()
+
+	// Create a gRPC server with default options and register the services,
+	// closing watch streams on shutdown so the server can gracefully stop.
+	grpcSrv := pgrpc.NewServer(s.GrpcServerOptions())
+	grpcSrv.RegisterService(&apiv1.DiscoveryService_ServiceDesc, ds)
+	grpcSrv.RegisterService(&apiv1.KVService_ServiceDesc, kv)
+	grpcSrv.OnShutdown(kv.Stop)
+
+	// Expose the service as JSON over HTTP, if served with mTLS.
+	gw := s.GrpcGateway(grpcSrv)
+	gw.Handle(http.MethodPost, "/v1/discovery/services", apiv1.DiscoveryService_RegisterService_FullMethodName)
+	gw.Handle(http.MethodGet, "/v1/discovery/services", apiv1.DiscoveryService_GetServices_FullMethodName)
+	gw.Handle(http.MethodDelete, "/v1/discovery/services/{uuid}", apiv1.DiscoveryService_DeregisterService_FullMethodName)
+	s.HandleGateway("/v1/", gw)
+
+	// Serve gRPC once the service has run.
+	s.ServeGRPC(grpcSrv)
+
+	return nil
+}
@@ -1,4 +1,4 @@
-package main
+package eventd
 
 import (
 	"context"
@@ -13,7 +13,7 @@ type grpcServer struct {
 }
 
 func (s *grpcServer) Event(ctx context.Context, req *apiv1.EventRequest) (*apiv1.EventResponse, error) {
-	log.Info().Str("hostname", req.Hostname).Msg("request received")
+	log.Info().Ctx(ctx).Str("hostname", req.Hostname).Msg("request received")
 
 	return &apiv1.EventResponse{
 		Uuid: "test",
@@ -0,0 +1,39 @@
+// Package eventd implements the event service, which receives events from
+// other platform services.
+package eventd
+
+import (
+	"context"
+	"net/http"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/credentials"
+	pgrpc "github.com/loshz/platform/internal/grpc"
+	"github.com/loshz/platform/internal/service"
+)
+
+// Name of the event service.
+const Name = "eventd"
+
+// Configure loads the credentials required to run the service. It must be
+// called after config sources are loaded.
+func Configure(s *service.Service) {
+	s.LoadCredentials(credentials.GrpcClient, credentials.GrpcServer, credentials.GrpcToken)
+}
+
+// Run initializes the event server.
+func Run(ctx context.Context, s *service.Service) error {
+	// Create a gRPC server with default options and register the service.
+	grpcSrv := pgrpc.NewServer(s.GrpcServerOptions())
+	grpcSrv.RegisterService(&apiv1.EventService_ServiceDesc, &grpcServer{})
+
+	// Expose the service as JSON over HTTP, if served with mTLS.
+	gw := s.GrpcGateway(grpcSrv)
+	gw.Handle(http.MethodPost, "/v1/events", apiv1.EventService_Event_FullMethodName)
+	s.HandleGateway("/v1/", gw)
+
+	// Serve gRPC once the service has run.
+	s.ServeGRPC(grpcSrv)
+
+	return nil
+}
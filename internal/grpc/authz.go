@@ -0,0 +1,63 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/loshz/platform/internal/authz"
+	"github.com/loshz/platform/internal/metrics"
+)
+
+// UnaryAuthzInterceptor authorizes gRPC unary calls against a policy using the
+// identity of the peer's client certificate. Denied calls are audited and return
+// a PermissionDenied error.
+func UnaryAuthzInterceptor(service_id string, policy *authz.Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorize(ctx, service_id, policy, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthzInterceptor authorizes gRPC stream calls against a policy using the
+// identity of the peer's client certificate. As the request isn't known when a
+// stream is opened, rules that restrict request fields always deny streams.
+func StreamAuthzInterceptor(service_id string, policy *authz.Policy) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorize(ss.Context(), service_id, policy, info.FullMethod, nil); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// authorize checks a call against a policy, auditing any denied calls.
+func authorize(ctx context.Context, service_id string, policy *authz.Policy, method string, req interface{}) error {
+	id := authz.IdentityFromContext(ctx)
+
+	err := policy.Authorize(id, method, req)
+	if err == nil {
+		return nil
+	}
+
+	metrics.GRPCAuthzDeniedTotal.WithLabelValues(service_id, method).Inc()
+	addr, subject := peerInfo(ctx)
+	log.Warn().
+		Ctx(ctx).
+		Err(err).
+		Bool("audit", true).
+		Str("grpc.method", method).
+		Str("peer.address", addr).
+		Str("peer.subject", subject).
+		Str("peer.identity", id.String()).
+		Msg("grpc call denied")
+
+	return status.Error(codes.PermissionDenied, err.Error())
+}
@@ -0,0 +1,66 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/authz"
+	"github.com/loshz/platform/internal/metrics"
+)
+
+func TestUnaryAuthzInterceptor(t *testing.T) {
+	method := apiv1.DiscoveryService_DeregisterService_FullMethodName
+	policy := &authz.Policy{
+		Default: authz.Deny,
+		Rules:   []authz.Rule{{Methods: []string{method}, Allow: []string{authz.Wildcard}, Self: "uuid"}},
+	}
+	interceptor := UnaryAuthzInterceptor("authz_test", policy)
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	handler := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+
+	// Mock a peer with a verified client certificate.
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "eventd-00000000-0000-0000-0000-000000000001"}}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert}},
+		},
+	}})
+
+	t.Run("TestAllowed", func(t *testing.T) {
+		req := &apiv1.DeregisterServiceRequest{Uuid: cert.Subject.CommonName}
+		res, err := interceptor(ctx, req, info, handler)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ok", res)
+	})
+
+	t.Run("TestDenied", func(t *testing.T) {
+		denied := metrics.GRPCAuthzDeniedTotal.WithLabelValues("authz_test", method)
+		before := testutil.ToFloat64(denied)
+
+		req := &apiv1.DeregisterServiceRequest{Uuid: "eventd-00000000-0000-0000-0000-000000000002"}
+		_, err := interceptor(ctx, req, info, handler)
+
+		// Assert the call is denied and recorded.
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Equal(t, before+1, testutil.ToFloat64(denied))
+	})
+
+	t.Run("TestAnonymous", func(t *testing.T) {
+		_, err := interceptor(context.Background(), &apiv1.DeregisterServiceRequest{}, info, handler)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
@@ -0,0 +1,256 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// CallType describes how calls to a method may be safely repeated by a client.
+type CallType int
+
+const (
+	// CallDefault calls are never repeated.
+	CallDefault CallType = iota
+	// CallIdempotent calls are retried on transient failures.
+	CallIdempotent
+	// CallHedged calls are idempotent reads that are sent again if a response
+	// isn't received within the hedging delay, using the first response.
+	CallHedged
+)
+
+// Retry backoff configuration used for idempotent calls.
+const (
+	retryInitialBackoff    = 100 * time.Millisecond
+	retryMaxBackoff        = 1 * time.Second
+	retryBackoffMultiplier = 2.0
+)
+
+// ClientPolicy configures the default call behaviour of a client connection.
+type ClientPolicy struct {
+	// Default deadline applied to unary calls without one.
+	Timeout time.Duration
+
+	// Max no. of attempts made for idempotent and hedged calls, including the
+	// original. Values less than 2 disable retries and hedging.
+	MaxAttempts int
+
+	// Time to wait for a response before sending a hedged request.
+	HedgingDelay time.Duration
+
+	// Call types keyed by full method name, e.g. /proto.v1.DiscoveryService/GetServices.
+	// Methods not found are never repeated.
+	Methods map[string]CallType
+}
+
+// DefaultClientPolicy returns a policy with sane defaults and no repeatable methods.
+func DefaultClientPolicy() ClientPolicy {
+	return ClientPolicy{
+		Timeout:      10 * time.Second,
+		MaxAttempts:  3,
+		HedgingDelay: 100 * time.Millisecond,
+	}
+}
+
+// Dial creates a client connection to the target with the given transport
+// credentials and call policy.
+//
+// Interceptors given in opts wrap those of the policy, so they observe calls as
+// made by the caller rather than individual hedged attempts.
+func Dial(ctx context.Context, target string, creds credentials.TransportCredentials, policy ClientPolicy, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	sc, err := policy.serviceConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error creating service config: %w", err)
+	}
+
+	opts = append(opts,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultServiceConfig(sc),
+		grpc.WithChainUnaryInterceptor(
+			TimeoutInterceptor(policy.Timeout),
+			HedgingInterceptor(policy),
+		),
+	)
+
+	return grpc.DialContext(ctx, target, opts...)
+}
+
+// TimeoutInterceptor applies a default deadline to unary calls if the call
+// context doesn't already have one.
+func TimeoutInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok && timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// HedgingInterceptor sends concurrent requests for hedged methods, returning the
+// first successful response. A new request is sent each time the hedging delay
+// passes without a response, or immediately after a non-fatal error, up to the
+// policy's max attempts. Outstanding requests are cancelled once a response is
+// returned.
+func HedgingInterceptor(policy ClientPolicy) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		msg, ok := reply.(proto.Message)
+		if !ok || policy.Methods[method] != CallHedged || policy.MaxAttempts < 2 || policy.HedgingDelay <= 0 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type result struct {
+			reply proto.Message
+			err   error
+		}
+
+		// Buffer results so outstanding requests never block on return.
+		results := make(chan result, policy.MaxAttempts)
+		sent, received := 0, 0
+		send := func() {
+			sent++
+			res := proto.Clone(msg)
+			go func() {
+				err := invoker(ctx, method, req, res, cc, opts...)
+				results <- result{res, err}
+			}()
+		}
+
+		t := time.NewTimer(policy.HedgingDelay)
+		defer t.Stop()
+
+		send()
+		for {
+			select {
+			case <-t.C:
+				if sent < policy.MaxAttempts {
+					send()
+					t.Reset(policy.HedgingDelay)
+				}
+			case res := <-results:
+				received++
+				if res.err == nil {
+					proto.Reset(msg)
+					proto.Merge(msg, res.reply)
+					return nil
+				}
+
+				// Return fatal errors immediately, otherwise send the next request.
+				if !isNonFatal(res.err) {
+					return res.err
+				}
+				if sent < policy.MaxAttempts {
+					send()
+					resetTimer(t, policy.HedgingDelay)
+				} else if received == sent {
+					return res.err
+				}
+			}
+		}
+	}
+}
+
+// resetTimer stops a timer and drains its channel before resetting it, so a
+// tick that already fired isn't received after the reset.
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
+
+// isNonFatal reports whether a failed call may be safely repeated.
+func isNonFatal(err error) bool {
+	return status.Code(err) == codes.Unavailable
+}
+
+// serviceConfig creates a JSON encoded gRPC service config containing retry
+// policies for idempotent methods.
+//
+// Hedged methods are handled by the HedgingInterceptor as hedging policies are
+// not supported by grpc-go.
+func (p ClientPolicy) serviceConfig() (string, error) {
+	type name struct {
+		Service string `json:"service"`
+		Method  string `json:"method"`
+	}
+
+	type retryPolicy struct {
+		MaxAttempts          int      `json:"maxAttempts"`
+		InitialBackoff       string   `json:"initialBackoff"`
+		MaxBackoff           string   `json:"maxBackoff"`
+		BackoffMultiplier    float64  `json:"backoffMultiplier"`
+		RetryableStatusCodes []string `json:"retryableStatusCodes"`
+	}
+
+	type methodConfig struct {
+		Name        []name       `json:"name"`
+		RetryPolicy *retryPolicy `json:"retryPolicy,omitempty"`
+	}
+
+	sc := struct {
+		MethodConfig []methodConfig `json:"methodConfig"`
+	}{[]methodConfig{}}
+
+	if p.MaxAttempts > 1 {
+		var names []name
+		for fullMethod, ct := range p.Methods {
+			if ct != CallIdempotent {
+				continue
+			}
+
+			service, method, ok := splitMethodName(fullMethod)
+			if !ok {
+				return "", fmt.Errorf("invalid method name '%s'", fullMethod)
+			}
+			names = append(names, name{service, method})
+		}
+
+		if len(names) > 0 {
+			// Sort names so the config is deterministic.
+			sort.Slice(names, func(i, j int) bool {
+				return names[i].Service+names[i].Method < names[j].Service+names[j].Method
+			})
+
+			sc.MethodConfig = append(sc.MethodConfig, methodConfig{
+				Name: names,
+				RetryPolicy: &retryPolicy{
+					MaxAttempts:          p.MaxAttempts,
+					InitialBackoff:       durationSeconds(retryInitialBackoff),
+					MaxBackoff:           durationSeconds(retryMaxBackoff),
+					BackoffMultiplier:    retryBackoffMultiplier,
+					RetryableStatusCodes: []string{"UNAVAILABLE"},
+				},
+			})
+		}
+	}
+
+	data, err := json.Marshal(sc)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// durationSeconds formats a duration as seconds, as required by service configs.
+func durationSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
@@ -0,0 +1,209 @@
+package grpc
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+)
+
+var testClientPolicy = ClientPolicy{
+	Timeout:      time.Second,
+	MaxAttempts:  3,
+	HedgingDelay: 10 * time.Millisecond,
+	Methods: map[string]CallType{
+		apiv1.DiscoveryService_RegisterService_FullMethodName: CallIdempotent,
+		apiv1.DiscoveryService_GetServices_FullMethodName:     CallHedged,
+	},
+}
+
+func TestDial(t *testing.T) {
+	// Assert the generated service config is accepted by grpc.
+	conn, err := Dial(context.Background(), "localhost:0", insecure.NewCredentials(), testClientPolicy)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	// Assert invalid method names return an error.
+	policy := testClientPolicy
+	policy.Methods = map[string]CallType{"invalid": CallIdempotent}
+	_, err = Dial(context.Background(), "localhost:0", insecure.NewCredentials(), policy)
+	assert.Error(t, err)
+}
+
+func TestTimeoutInterceptor(t *testing.T) {
+	interceptor := TimeoutInterceptor(time.Second)
+
+	t.Run("TestDefaultDeadline", func(t *testing.T) {
+		invoker := func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+			_, ok := ctx.Deadline()
+			assert.True(t, ok)
+			return nil
+		}
+
+		err := interceptor(context.Background(), "test", nil, nil, nil, invoker)
+		assert.NoError(t, err)
+	})
+
+	t.Run("TestExistingDeadline", func(t *testing.T) {
+		expected := time.Now().Add(time.Hour)
+		invoker := func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+			deadline, _ := ctx.Deadline()
+			assert.Equal(t, expected, deadline)
+			return nil
+		}
+
+		ctx, cancel := context.WithDeadline(context.Background(), expected)
+		defer cancel()
+
+		err := interceptor(ctx, "test", nil, nil, nil, invoker)
+		assert.NoError(t, err)
+	})
+}
+
+func TestResetTimer(t *testing.T) {
+	timer := time.NewTimer(time.Millisecond)
+	defer timer.Stop()
+	time.Sleep(10 * time.Millisecond)
+
+	// Assert a tick that already fired isn't received after the reset.
+	resetTimer(timer, time.Hour)
+	select {
+	case <-timer.C:
+		t.Fatal("received tick fired before reset")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestHedgingInterceptor(t *testing.T) {
+	interceptor := HedgingInterceptor(testClientPolicy)
+	method := apiv1.DiscoveryService_GetServices_FullMethodName
+
+	t.Run("TestSlowResponse", func(t *testing.T) {
+		var calls atomic.Int32
+		invoker := func(ctx context.Context, _ string, _, reply interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+			// Block the first call until it is cancelled.
+			if calls.Add(1) == 1 {
+				<-ctx.Done()
+				return status.FromContextError(ctx.Err()).Err()
+			}
+
+			reply.(*apiv1.GetServicesResponse).Services = []*apiv1.Service{{Uuid: "hedged"}}
+			return nil
+		}
+
+		reply := new(apiv1.GetServicesResponse)
+		err := interceptor(context.Background(), method, nil, reply, nil, invoker)
+		require.NoError(t, err)
+
+		// Assert the hedged response is returned.
+		assert.Equal(t, int32(2), calls.Load())
+		assert.Equal(t, "hedged", reply.Services[0].Uuid)
+	})
+
+	t.Run("TestFailedAttemptDelay", func(t *testing.T) {
+		policy := testClientPolicy
+		policy.HedgingDelay = 50 * time.Millisecond
+
+		var calls atomic.Int32
+		sent := make(chan time.Time, policy.MaxAttempts)
+		invoker := func(ctx context.Context, _ string, _, reply interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+			sent <- time.Now()
+			switch calls.Add(1) {
+			case 1:
+				return status.Error(codes.Unavailable, "unavailable")
+			case 2:
+				<-ctx.Done()
+				return status.FromContextError(ctx.Err()).Err()
+			}
+
+			reply.(*apiv1.GetServicesResponse).Services = []*apiv1.Service{{Uuid: "hedged"}}
+			return nil
+		}
+
+		err := HedgingInterceptor(policy)(context.Background(), method, nil, new(apiv1.GetServicesResponse), nil, invoker)
+		require.NoError(t, err)
+		require.Len(t, sent, 3)
+
+		// Assert the failed attempt is retried immediately, and the next attempt
+		// is hedged a full delay after the retry.
+		first, retry, hedge := <-sent, <-sent, <-sent
+		assert.Less(t, retry.Sub(first), policy.HedgingDelay)
+		assert.GreaterOrEqual(t, hedge.Sub(retry), policy.HedgingDelay-5*time.Millisecond)
+	})
+
+	t.Run("TestNonFatalErrors", func(t *testing.T) {
+		var calls atomic.Int32
+		invoker := func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+			calls.Add(1)
+			return status.Error(codes.Unavailable, "unavailable")
+		}
+
+		err := interceptor(context.Background(), method, nil, new(apiv1.GetServicesResponse), nil, invoker)
+
+		// Assert all attempts were made before returning the error.
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, int32(testClientPolicy.MaxAttempts), calls.Load())
+	})
+
+	t.Run("TestFatalError", func(t *testing.T) {
+		var calls atomic.Int32
+		invoker := func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+			calls.Add(1)
+			return status.Error(codes.InvalidArgument, "invalid")
+		}
+
+		err := interceptor(context.Background(), method, nil, new(apiv1.GetServicesResponse), nil, invoker)
+
+		// Assert the error is returned without further attempts.
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("TestNotHedged", func(t *testing.T) {
+		var calls atomic.Int32
+		invoker := func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+			calls.Add(1)
+			return status.Error(codes.Unavailable, "unavailable")
+		}
+
+		method := apiv1.DiscoveryService_RegisterService_FullMethodName
+		err := interceptor(context.Background(), method, nil, new(apiv1.RegisterServiceResponse), nil, invoker)
+
+		// Assert non-hedged methods are only called once.
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, int32(1), calls.Load())
+	})
+}
+
+func TestServiceConfig(t *testing.T) {
+	sc, err := testClientPolicy.serviceConfig()
+	require.NoError(t, err)
+
+	// Assert only idempotent methods have a retry policy.
+	assert.JSONEq(t, `{"methodConfig":[{
+		"name":[{"service":"proto.v1.DiscoveryService","method":"RegisterService"}],
+		"retryPolicy":{
+			"maxAttempts":3,
+			"initialBackoff":"0.1s",
+			"maxBackoff":"1s",
+			"backoffMultiplier":2,
+			"retryableStatusCodes":["UNAVAILABLE"]
+		}
+	}]}`, sc)
+
+	// Assert retries are disabled with a single attempt.
+	policy := testClientPolicy
+	policy.MaxAttempts = 1
+	sc, err = policy.serviceConfig()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"methodConfig":[]}`, sc)
+}
@@ -0,0 +1,394 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// MetadataHeaderPrefix is prepended to gRPC response metadata when written
+	// as HTTP headers, and stripped from HTTP request headers.
+	MetadataHeaderPrefix = "Grpc-Metadata-"
+
+	// Max size of a gateway request body in bytes.
+	maxGatewayBodySize = 1 << 20
+)
+
+// Gateway transcodes JSON over HTTP requests into unary gRPC calls against
+// services registered to a Server. Request and response messages are
+// (un)marshalled using the descriptors generated from proto/v1.
+//
+// Routes are registered with Handle rather than generated from google.api.http
+// annotations, as proto/v1 is built without the googleapis protos.
+//
+// Gateway calls bypass the gRPC server's transport credentials, so callers
+// have no identity unless the HTTP server verifies client certs. Services
+// must not serve a gateway to unauthenticated callers.
+type Gateway struct {
+	srv         *Server
+	interceptor grpc.UnaryServerInterceptor
+	routes      []route
+}
+
+// route represents a mapping of an HTTP method and path template to a gRPC method.
+type route struct {
+	method   string
+	segments []string
+	rpc      string
+}
+
+// NewGateway creates a Gateway for the given Server. The interceptor is ran for
+// every transcoded call, so metrics are recorded as if it were a gRPC request.
+func NewGateway(srv *Server, interceptor grpc.UnaryServerInterceptor) *Gateway {
+	return &Gateway{
+		srv:         srv,
+		interceptor: interceptor,
+	}
+}
+
+// Handle maps an HTTP method and path template to a full gRPC method name.
+// Path templates may contain named segments, e.g. /v1/services/{uuid}, which
+// populate the request field of the same name.
+func (g *Gateway) Handle(method, path, rpc string) {
+	g.routes = append(g.routes, route{
+		method:   method,
+		segments: splitPath(path),
+		rpc:      rpc,
+	})
+}
+
+// ServeHTTP implements http.Handler.
+//
+// The request message is populated from the JSON body, followed by query
+// parameters and finally path parameters.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rpc, params, ok := g.match(r.Method, r.URL.Path)
+	if !ok {
+		writeGatewayError(w, status.Errorf(codes.NotFound, "no route for %s %s", r.Method, r.URL.Path))
+		return
+	}
+
+	impl, md, ok := g.srv.unaryHandler(rpc)
+	if !ok {
+		writeGatewayError(w, status.Errorf(codes.Unimplemented, "unknown method %s", rpc))
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxGatewayBodySize))
+	if err != nil {
+		writeGatewayError(w, status.Errorf(codes.InvalidArgument, "error reading request body: %v", err))
+		return
+	}
+
+	// Decode the request into the message created by the generated handler.
+	dec := func(v interface{}) error {
+		msg, ok := v.(proto.Message)
+		if !ok {
+			return status.Errorf(codes.Internal, "unexpected request type %T", v)
+		}
+
+		if len(body) > 0 {
+			if err := protojson.Unmarshal(body, msg); err != nil {
+				return status.Errorf(codes.InvalidArgument, "error decoding request body: %v", err)
+			}
+		}
+
+		for key, vals := range r.URL.Query() {
+			if err := setField(msg.ProtoReflect(), key, vals); err != nil {
+				return err
+			}
+		}
+
+		for key, val := range params {
+			if err := setField(msg.ProtoReflect(), key, []string{val}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	stream := &gatewayStream{method: rpc}
+	ctx := gatewayContext(r, stream)
+
+	res, err := md.Handler(impl, ctx, dec, g.interceptor)
+
+	// Write any metadata set by the handler as response headers.
+	stream.mtx.Lock()
+	for key, vals := range metadata.Join(stream.header, stream.trailer) {
+		for _, val := range vals {
+			w.Header().Add(MetadataHeaderPrefix+key, val)
+		}
+	}
+	stream.mtx.Unlock()
+
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+
+	msg, ok := res.(proto.Message)
+	if !ok {
+		writeGatewayError(w, status.Errorf(codes.Internal, "unexpected response type %T", res))
+		return
+	}
+
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		writeGatewayError(w, status.Errorf(codes.Internal, "error encoding response: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		log.Error().Err(err).Msg("error writing gateway response")
+	}
+}
+
+// match finds the first route matching the given HTTP method and path,
+// returning the gRPC method and any path parameters.
+func (g *Gateway) match(method, path string) (string, map[string]string, bool) {
+	segments := splitPath(path)
+
+	for _, rt := range g.routes {
+		if rt.method != method || len(rt.segments) != len(segments) {
+			continue
+		}
+
+		params := make(map[string]string)
+		matched := true
+		for i, seg := range rt.segments {
+			if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+				params[seg[1:len(seg)-1]] = segments[i]
+				continue
+			}
+
+			if seg != segments[i] {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return rt.rpc, params, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// gatewayContext creates a gRPC server context from an HTTP request, including
+// request headers as incoming metadata and the remote address as the peer. The
+// TLS connection state is used as the peer's auth info, so interceptors see the
+// identity of callers that presented a verified client cert.
+func gatewayContext(r *http.Request, stream grpc.ServerTransportStream) context.Context {
+	md := make(metadata.MD, len(r.Header))
+	for key, vals := range r.Header {
+		key = strings.TrimPrefix(key, MetadataHeaderPrefix)
+		md.Append(strings.ToLower(key), vals...)
+	}
+
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+	ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+	p := &peer.Peer{}
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		p.Addr = addr
+	}
+	if r.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{
+			State:          *r.TLS,
+			CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity},
+		}
+	}
+	ctx = peer.NewContext(ctx, p)
+
+	return ctx
+}
+
+// gatewayStream implements grpc.ServerTransportStream, capturing metadata
+// set by handlers so it can be returned as HTTP headers.
+type gatewayStream struct {
+	method string
+
+	mtx             sync.Mutex
+	header, trailer metadata.MD
+}
+
+func (s *gatewayStream) Method() string { return s.method }
+
+func (s *gatewayStream) SetHeader(md metadata.MD) error {
+	s.mtx.Lock()
+	s.header = metadata.Join(s.header, md)
+	s.mtx.Unlock()
+
+	return nil
+}
+
+func (s *gatewayStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *gatewayStream) SetTrailer(md metadata.MD) error {
+	s.mtx.Lock()
+	s.trailer = metadata.Join(s.trailer, md)
+	s.mtx.Unlock()
+
+	return nil
+}
+
+// setField sets a message field by its JSON or proto name from string values.
+// Only scalar and repeated scalar fields are supported.
+func setField(msg protoreflect.Message, name string, vals []string) error {
+	fields := msg.Descriptor().Fields()
+
+	fd := fields.ByJSONName(name)
+	if fd == nil {
+		fd = fields.ByName(protoreflect.Name(name))
+	}
+	if fd == nil {
+		return status.Errorf(codes.InvalidArgument, "unknown field '%s'", name)
+	}
+
+	if fd.IsMap() || fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		return status.Errorf(codes.InvalidArgument, "field '%s' cannot be set from a parameter", name)
+	}
+
+	if fd.IsList() {
+		list := msg.Mutable(fd).List()
+		for _, val := range vals {
+			v, err := parseScalar(fd, val)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid value for field '%s': %v", name, err)
+			}
+			list.Append(v)
+		}
+
+		return nil
+	}
+
+	if len(vals) == 0 {
+		return nil
+	}
+
+	v, err := parseScalar(fd, vals[len(vals)-1])
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid value for field '%s': %v", name, err)
+	}
+	msg.Set(fd, v)
+
+	return nil
+}
+
+// parseScalar parses a string into a protoreflect.Value of the field's kind.
+func parseScalar(fd protoreflect.FieldDescriptor, val string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(val), nil
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(val)), nil
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(val)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		i, err := strconv.ParseInt(val, 10, 32)
+		return protoreflect.ValueOfInt32(int32(i)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		i, err := strconv.ParseInt(val, 10, 64)
+		return protoreflect.ValueOfInt64(i), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		i, err := strconv.ParseUint(val, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(i)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		i, err := strconv.ParseUint(val, 10, 64)
+		return protoreflect.ValueOfUint64(i), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(val, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(val, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(val)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		i, err := strconv.ParseInt(val, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(i)), err
+	}
+
+	return protoreflect.Value{}, status.Errorf(codes.InvalidArgument, "unsupported field kind %s", fd.Kind())
+}
+
+// writeGatewayError writes a gRPC error as a JSON encoded status with the
+// equivalent HTTP status code.
+func writeGatewayError(w http.ResponseWriter, err error) {
+	stat := status.Convert(err)
+
+	data, merr := protojson.Marshal(stat.Proto())
+	if merr != nil {
+		http.Error(w, stat.Message(), HTTPStatusFromCode(stat.Code()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(HTTPStatusFromCode(stat.Code()))
+	if _, err := w.Write(data); err != nil {
+		log.Error().Err(err).Msg("error writing gateway error response")
+	}
+}
+
+// HTTPStatusFromCode converts a gRPC status code into the corresponding HTTP status.
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
+
+// splitPath splits a URL path into its non-empty segments.
+func splitPath(path string) []string {
+	var segments []string
+	for _, seg := range strings.Split(path, "/") {
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+
+	return segments
+}
@@ -0,0 +1,188 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/authz"
+)
+
+type mockDiscoveryServer struct {
+	apiv1.UnimplementedDiscoveryServiceServer
+}
+
+func (m *mockDiscoveryServer) RegisterService(ctx context.Context, req *apiv1.RegisterServiceRequest) (*apiv1.RegisterServiceResponse, error) {
+	if req.GetService().GetUuid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing uuid")
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs("registered", req.GetService().GetUuid()))
+
+	return &apiv1.RegisterServiceResponse{Service: req.GetService()}, nil
+}
+
+func (m *mockDiscoveryServer) DeregisterService(_ context.Context, req *apiv1.DeregisterServiceRequest) (*apiv1.DeregisterServiceResponse, error) {
+	return &apiv1.DeregisterServiceResponse{Uuid: req.GetUuid()}, nil
+}
+
+func (m *mockDiscoveryServer) GetServices(_ context.Context, req *apiv1.GetServicesRequest) (*apiv1.GetServicesResponse, error) {
+	return &apiv1.GetServicesResponse{
+		Services: []*apiv1.Service{{Uuid: req.GetName()}},
+	}, nil
+}
+
+func newTestGateway() *Gateway {
+	srv := NewServer(nil)
+	srv.RegisterService(&apiv1.DiscoveryService_ServiceDesc, &mockDiscoveryServer{})
+
+	gw := NewGateway(srv, UnaryInterceptor("gateway_test", 1))
+	gw.Handle(http.MethodPost, "/v1/discovery/services", apiv1.DiscoveryService_RegisterService_FullMethodName)
+	gw.Handle(http.MethodGet, "/v1/discovery/services", apiv1.DiscoveryService_GetServices_FullMethodName)
+	gw.Handle(http.MethodDelete, "/v1/discovery/services/{uuid}", apiv1.DiscoveryService_DeregisterService_FullMethodName)
+
+	return gw
+}
+
+func TestGateway(t *testing.T) {
+	gw := newTestGateway()
+
+	t.Run("TestQueryParams", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/discovery/services?name=eventd", nil)
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"services":[{"uuid":"eventd"}]}`, rec.Body.String())
+	})
+
+	t.Run("TestPathParams", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/v1/discovery/services/eventd-1234", nil)
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"uuid":"eventd-1234"}`, rec.Body.String())
+	})
+
+	t.Run("TestBody", func(t *testing.T) {
+		body := strings.NewReader(`{"service":{"uuid":"eventd-1234","grpcPort":8004}}`)
+		req := httptest.NewRequest(http.MethodPost, "/v1/discovery/services", body)
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"service":{"uuid":"eventd-1234","grpcPort":8004}}`, rec.Body.String())
+
+		// Assert metadata set by the handler is returned as a header.
+		assert.Equal(t, "eventd-1234", rec.Header().Get(MetadataHeaderPrefix+"registered"))
+	})
+
+	t.Run("TestInvalidBody", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/discovery/services", strings.NewReader("{"))
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("TestHandlerError", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/discovery/services", strings.NewReader("{}"))
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+
+		// Assert the error is encoded as a status.
+		var res struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+		assert.Equal(t, int(codes.InvalidArgument), res.Code)
+		assert.Equal(t, "missing uuid", res.Message)
+	})
+
+	t.Run("TestUnknownField", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/discovery/services?unknown=true", nil)
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("TestNotFound", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/v1/discovery/services", nil)
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestGatewayRequestID(t *testing.T) {
+	srv := NewServer(nil)
+	srv.RegisterService(&apiv1.DiscoveryService_ServiceDesc, &mockDiscoveryServer{})
+
+	gw := NewGateway(srv, UnaryRequestIDInterceptor())
+	gw.Handle(http.MethodGet, "/v1/discovery/services", apiv1.DiscoveryService_GetServices_FullMethodName)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/discovery/services", nil)
+	req.Header.Set("X-Request-Id", "1234")
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+
+	// Assert the request ID header is returned.
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1234", rec.Header().Get(MetadataHeaderPrefix+RequestIDMetadataKey))
+}
+
+func TestGatewayIdentity(t *testing.T) {
+	var id authz.Identity
+	capture := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		id = authz.IdentityFromContext(ctx)
+		return handler(ctx, req)
+	}
+
+	srv := NewServer(nil)
+	srv.RegisterService(&apiv1.DiscoveryService_ServiceDesc, &mockDiscoveryServer{})
+
+	gw := NewGateway(srv, capture)
+	gw.Handle(http.MethodGet, "/v1/discovery/services", apiv1.DiscoveryService_GetServices_FullMethodName)
+
+	cert := &x509.Certificate{DNSNames: []string{"trafficd"}}
+	req := httptest.NewRequest(http.MethodGet, "/v1/discovery/services", nil)
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+
+	// Assert interceptors see the identity of the caller's client cert.
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "trafficd", id.Service)
+}
+
+func TestSplitMethodName(t *testing.T) {
+	service, method, ok := splitMethodName(apiv1.DiscoveryService_GetServices_FullMethodName)
+	assert.True(t, ok)
+	assert.Equal(t, "proto.v1.DiscoveryService", service)
+	assert.Equal(t, "GetServices", method)
+
+	_, _, ok = splitMethodName("invalid")
+	assert.False(t, ok)
+}
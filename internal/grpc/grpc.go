@@ -2,9 +2,17 @@ package grpc
 
 import (
 	"context"
+	"io"
+	"math/rand"
+	"sync"
 	"time"
 
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 
 	"github.com/loshz/platform/internal/metrics"
@@ -13,40 +21,218 @@ import (
 var now = time.Now
 
 // StreamInterceptor instruments and logs information about gRPC stream calls.
-func StreamInterceptor(service_id string) grpc.StreamServerInterceptor {
+// Successful calls are logged at the given sample rate, between 0 and 1, whereas
+// failed calls are always logged.
+func StreamInterceptor(service_id string, sampleRate float64) grpc.StreamServerInterceptor {
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		// Time the underlying request.
 		start := now()
 		err := handler(srv, ss)
 		latency := time.Since(start)
 
-		// Get the request status code.
-		code := status.Code(err)
-
-		// Record request metrics.
-		labels := []string{service_id, code.String(), info.FullMethod, "stream"}
-		metrics.GRPCRequestDuration.WithLabelValues(labels...).Observe(latency.Seconds())
-		metrics.GRPCRequestsTotal.WithLabelValues(labels...).Inc()
+		recordServerCall(ss.Context(), service_id, info.FullMethod, "stream", latency, err, sampleRate)
 
 		return err
 	}
 }
 
 // UnaryInterceptor instruments and logs information about gRPC unary calls.
-func UnaryInterceptor(service_id string) grpc.UnaryServerInterceptor {
+// Successful calls are logged at the given sample rate, between 0 and 1, whereas
+// failed calls are always logged.
+func UnaryInterceptor(service_id string, sampleRate float64) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		start := now()
 		res, err := handler(ctx, req)
 		latency := time.Since(start)
 
-		// Get the request status code.
-		code := status.Code(err)
-
-		// Record request metrics.
-		labels := []string{service_id, code.String(), info.FullMethod, "unary"}
-		metrics.GRPCRequestDuration.WithLabelValues(labels...).Observe(latency.Seconds())
-		metrics.GRPCRequestsTotal.WithLabelValues(labels...).Inc()
+		recordServerCall(ctx, service_id, info.FullMethod, "unary", latency, err, sampleRate)
 
 		return res, err
 	}
 }
+
+// ChainUnaryServer creates a single interceptor from many, with the first
+// being the outermost.
+func ChainUnaryServer(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		// Wrap the handler from the innermost interceptor outwards.
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			next, interceptor := handler, interceptors[i]
+			handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// recordServerCall records metrics and logs a single incoming call.
+func recordServerCall(ctx context.Context, service_id, method, callType string, latency time.Duration, err error, sampleRate float64) {
+	// Get the request status code.
+	code := status.Code(err)
+
+	// Record request metrics.
+	labels := []string{service_id, code.String(), method, callType}
+	metrics.GRPCRequestDuration.WithLabelValues(labels...).Observe(latency.Seconds())
+	metrics.GRPCRequestsTotal.WithLabelValues(labels...).Inc()
+
+	// Skip logging successful calls that aren't sampled.
+	if err == nil && !sampled(sampleRate) {
+		return
+	}
+
+	addr, subject := peerInfo(ctx)
+	event := log.WithLevel(levelFromCode(code))
+	if err != nil {
+		event = event.Err(err)
+	}
+
+	event.
+		Ctx(ctx).
+		Str("grpc.method", method).
+		Str("grpc.type", callType).
+		Str("grpc.code", code.String()).
+		Dur("grpc.latency", latency).
+		Str("peer.address", addr).
+		Str("peer.subject", subject).
+		Msg("grpc call")
+}
+
+// peerInfo returns the address and client certificate subject of the peer
+// associated with a context, if known.
+func peerInfo(ctx context.Context) (string, string) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", ""
+	}
+
+	var addr, subject string
+	if p.Addr != nil {
+		addr = p.Addr.String()
+	}
+
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.PeerCertificates) > 0 {
+		subject = info.State.PeerCertificates[0].Subject.String()
+	}
+
+	return addr, subject
+}
+
+// levelFromCode returns the log level of a call with the given status code.
+// Codes that are likely caused by the server are logged as errors.
+func levelFromCode(code codes.Code) zerolog.Level {
+	switch code {
+	case codes.OK:
+		return zerolog.InfoLevel
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return zerolog.ErrorLevel
+	}
+
+	return zerolog.WarnLevel
+}
+
+// sampled reports whether an event should be recorded at the given rate.
+func sampled(rate float64) bool {
+	return rate >= 1 || (rate > 0 && rand.Float64() < rate)
+}
+
+// UnaryClientInterceptor instruments and logs information about outgoing gRPC
+// unary calls.
+func UnaryClientInterceptor(service_id string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		recordClientCall(ctx, service_id, cc.Target(), method, "unary", time.Since(start), err)
+
+		return err
+	}
+}
+
+// StreamClientInterceptor instruments and logs information about outgoing gRPC
+// stream calls. Calls are recorded once the stream has finished.
+func StreamClientInterceptor(service_id string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			recordClientCall(ctx, service_id, cc.Target(), method, "stream", time.Since(start), err)
+			return nil, err
+		}
+
+		return newClientStream(ctx, desc, cs, func(err error) {
+			recordClientCall(ctx, service_id, cc.Target(), method, "stream", time.Since(start), err)
+		}), nil
+	}
+}
+
+// clientStream wraps a grpc.ClientStream in order to record when a stream finishes.
+type clientStream struct {
+	grpc.ClientStream
+
+	serverStreams bool
+	once          sync.Once
+	done          func(error)
+	stop          func() bool
+}
+
+// newClientStream returns a stream that calls done once it finishes: when
+// RecvMsg returns an error or io.EOF, receives the only response of a call that
+// isn't server streaming, or the call's context is done before either.
+func newClientStream(ctx context.Context, desc *grpc.StreamDesc, cs grpc.ClientStream, done func(error)) *clientStream {
+	s := &clientStream{ClientStream: cs, serverStreams: desc.ServerStreams, done: done}
+	s.stop = context.AfterFunc(ctx, func() {
+		s.finish(status.FromContextError(ctx.Err()).Err())
+	})
+
+	return s
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil && s.serverStreams {
+		return nil
+	}
+
+	// The stream has finished with an error, io.EOF, or the only response of a
+	// call that isn't server streaming.
+	s.stop()
+	if err == io.EOF {
+		s.finish(nil)
+	} else {
+		s.finish(err)
+	}
+
+	return err
+}
+
+// finish calls done the first time the stream finishes.
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() { s.done(err) })
+}
+
+// recordClientCall records metrics and logs a single outgoing call.
+func recordClientCall(ctx context.Context, service_id, target, method, callType string, latency time.Duration, err error) {
+	// Get the request status code.
+	code := status.Code(err)
+
+	// Record request metrics.
+	labels := []string{service_id, code.String(), method, callType}
+	metrics.GRPCClientRequestDuration.WithLabelValues(labels...).Observe(latency.Seconds())
+	metrics.GRPCClientRequestsTotal.WithLabelValues(labels...).Inc()
+
+	// Log failed calls as warnings as they are often transient.
+	event := log.Debug()
+	if err != nil {
+		event = log.Warn().Err(err)
+	}
+
+	event.
+		Ctx(ctx).
+		Str("grpc.target", target).
+		Str("grpc.method", method).
+		Str("grpc.type", callType).
+		Str("grpc.code", code.String()).
+		Dur("grpc.latency", latency).
+		Msg("grpc client call")
+}
@@ -2,13 +2,33 @@ package grpc
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
+	"io"
+	"net"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+
+	"github.com/loshz/platform/internal/metrics"
 )
 
+type mockServerStream struct {
+	grpc.ServerStream
+}
+
+func (m *mockServerStream) Context() context.Context { return context.Background() }
+
 func TestStreamInterceptor(t *testing.T) {
 	expected := errors.New("handler error")
 
@@ -23,8 +43,8 @@ func TestStreamInterceptor(t *testing.T) {
 	}
 
 	// Create a new interceptor.
-	interceptor := StreamInterceptor("stream_service")
-	err := interceptor(nil, nil, info, handler)
+	interceptor := StreamInterceptor("stream_service", 1)
+	err := interceptor(nil, &mockServerStream{}, info, handler)
 
 	// Assert that the error from the handler is returned from the
 	// interceptor.
@@ -45,7 +65,7 @@ func TestUnaryInterceptor(t *testing.T) {
 	}
 
 	// Create a new interceptor.
-	interceptor := UnaryInterceptor("stream_service")
+	interceptor := UnaryInterceptor("stream_service", 1)
 	res, err := interceptor(context.Background(), nil, info, handler)
 
 	// Assert that the response and error from the handler are returned from the
@@ -53,3 +73,145 @@ func TestUnaryInterceptor(t *testing.T) {
 	assert.Equal(t, res, 1)
 	assert.ErrorIs(t, err, expected)
 }
+
+func TestChainUnaryServer(t *testing.T) {
+	var calls []string
+
+	// Mock interceptors to record the order they are called.
+	interceptor := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			calls = append(calls, name)
+			return handler(ctx, req)
+		}
+	}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls = append(calls, "handler")
+		return req, nil
+	}
+
+	chain := ChainUnaryServer(interceptor("first"), interceptor("second"))
+	res, err := chain(context.Background(), 1, &grpc.UnaryServerInfo{}, handler)
+
+	// Assert interceptors are called in order before the handler.
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res)
+	assert.Equal(t, []string{"first", "second", "handler"}, calls)
+}
+
+func TestPeerInfo(t *testing.T) {
+	// Assert an unknown peer returns empty values.
+	addr, subject := peerInfo(context.Background())
+	assert.Empty(t, addr)
+	assert.Empty(t, subject)
+
+	// Assert the peer address and certificate subject are returned.
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "eventd"}}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8000},
+		AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+		},
+	})
+	addr, subject = peerInfo(ctx)
+	assert.Equal(t, "127.0.0.1:8000", addr)
+	assert.Equal(t, "CN=eventd", subject)
+}
+
+func TestSampled(t *testing.T) {
+	assert.True(t, sampled(1))
+	assert.False(t, sampled(0))
+	assert.False(t, sampled(-1))
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	expected := errors.New("invoker error")
+
+	conn, err := grpc.Dial("localhost:0", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Mock an invoker to return an error.
+	invoker := func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+		return expected
+	}
+
+	// Create a new interceptor.
+	interceptor := UnaryClientInterceptor("unary_client")
+	err = interceptor(context.Background(), "test", nil, nil, conn, invoker)
+
+	// Assert that the error from the invoker is returned and recorded.
+	assert.ErrorIs(t, err, expected)
+	total := metrics.GRPCClientRequestsTotal.WithLabelValues("unary_client", codes.Unknown.String(), "test", "unary")
+	assert.Equal(t, 1.0, testutil.ToFloat64(total))
+}
+
+type mockClientStream struct {
+	grpc.ClientStream
+
+	err error
+}
+
+func (m *mockClientStream) RecvMsg(interface{}) error { return m.err }
+
+func TestStreamClientInterceptor(t *testing.T) {
+	conn, err := grpc.Dial("localhost:0", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Mock a streamer to return a finished stream.
+	streamer := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &mockClientStream{err: io.EOF}, nil
+	}
+
+	// Create a new interceptor.
+	interceptor := StreamClientInterceptor("stream_client")
+	cs, err := interceptor(context.Background(), &grpc.StreamDesc{}, conn, "test", streamer)
+	require.NoError(t, err)
+
+	// Assert that the call is only recorded once the stream has finished.
+	total := metrics.GRPCClientRequestsTotal.WithLabelValues("stream_client", codes.OK.String(), "test", "stream")
+	assert.Equal(t, 0.0, testutil.ToFloat64(total))
+
+	assert.ErrorIs(t, cs.RecvMsg(nil), io.EOF)
+	assert.ErrorIs(t, cs.RecvMsg(nil), io.EOF)
+	assert.Equal(t, 1.0, testutil.ToFloat64(total))
+}
+
+func TestStreamClientInterceptorFinished(t *testing.T) {
+	conn, err := grpc.Dial("localhost:0", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Mock a streamer to return a stream with pending responses.
+	streamer := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &mockClientStream{}, nil
+	}
+	interceptor := StreamClientInterceptor("stream_client_finished")
+
+	t.Run("TestClientStreaming", func(t *testing.T) {
+		cs, err := interceptor(context.Background(), &grpc.StreamDesc{ClientStreams: true}, conn, "client", streamer)
+		require.NoError(t, err)
+
+		// Assert the call is recorded once its only response is received.
+		require.NoError(t, cs.RecvMsg(nil))
+		total := metrics.GRPCClientRequestsTotal.WithLabelValues("stream_client_finished", codes.OK.String(), "client", "stream")
+		assert.Equal(t, 1.0, testutil.ToFloat64(total))
+	})
+
+	t.Run("TestServerStreaming", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cs, err := interceptor(ctx, &grpc.StreamDesc{ServerStreams: true}, conn, "server", streamer)
+		require.NoError(t, err)
+
+		// Assert the call isn't recorded while responses are received.
+		require.NoError(t, cs.RecvMsg(nil))
+		total := metrics.GRPCClientRequestsTotal.WithLabelValues("stream_client_finished", codes.Canceled.String(), "server", "stream")
+		assert.Equal(t, 0.0, testutil.ToFloat64(total))
+
+		// Assert the call is recorded once its context is done, even if the
+		// stream is never received from again.
+		cancel()
+		assert.Eventually(t, func() bool { return testutil.ToFloat64(total) == 1 }, time.Second, time.Millisecond)
+	})
+}
@@ -0,0 +1,53 @@
+package grpc
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/loshz/platform/internal/metrics"
+)
+
+// UnaryRecoveryInterceptor recovers from panics in gRPC unary handlers, returning
+// an Internal error to the client instead of crashing the process.
+func UnaryRecoveryInterceptor(service_id string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverPanic(service_id, info.FullMethod, r)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecoveryInterceptor recovers from panics in gRPC stream handlers, returning
+// an Internal error to the client instead of crashing the process.
+func StreamRecoveryInterceptor(service_id string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverPanic(service_id, info.FullMethod, r)
+			}
+		}()
+
+		return handler(srv, ss)
+	}
+}
+
+// recoverPanic records and logs a recovered panic, including the stack trace.
+func recoverPanic(service_id, method string, r interface{}) error {
+	metrics.GRPCPanicsTotal.WithLabelValues(service_id, method).Inc()
+
+	log.Error().
+		Str("grpc.method", method).
+		Str("stack", string(debug.Stack())).
+		Msgf("recovered from grpc handler panic: %v", r)
+
+	return status.Error(codes.Internal, "internal server error")
+}
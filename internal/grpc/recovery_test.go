@@ -0,0 +1,59 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/loshz/platform/internal/metrics"
+)
+
+func TestUnaryRecoveryInterceptor(t *testing.T) {
+	// Mock server info.
+	info := &grpc.UnaryServerInfo{
+		FullMethod: "unary_panic",
+	}
+
+	// Mock a handler to panic.
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("handler panic")
+	}
+
+	// Create a new interceptor.
+	interceptor := UnaryRecoveryInterceptor("recovery_service")
+	res, err := interceptor(context.Background(), nil, info, handler)
+
+	// Assert that the panic was recovered and returned as an internal error.
+	assert.Nil(t, res)
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	panics := metrics.GRPCPanicsTotal.WithLabelValues("recovery_service", "unary_panic")
+	assert.Equal(t, 1.0, testutil.ToFloat64(panics))
+}
+
+func TestStreamRecoveryInterceptor(t *testing.T) {
+	// Mock server info.
+	info := &grpc.StreamServerInfo{
+		FullMethod: "stream_panic",
+	}
+
+	// Mock a handler to panic.
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		panic("handler panic")
+	}
+
+	// Create a new interceptor.
+	interceptor := StreamRecoveryInterceptor("recovery_service")
+	err := interceptor(nil, nil, info, handler)
+
+	// Assert that the panic was recovered and returned as an internal error.
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	panics := metrics.GRPCPanicsTotal.WithLabelValues("recovery_service", "stream_panic")
+	assert.Equal(t, 1.0, testutil.ToFloat64(panics))
+}
@@ -0,0 +1,91 @@
+package grpc
+
+import (
+	"context"
+
+	guuid "github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	plog "github.com/loshz/platform/internal/log"
+)
+
+// RequestIDMetadataKey is the metadata key used to propagate request IDs
+// between services and return them to callers.
+const RequestIDMetadataKey = "x-request-id"
+
+// NewRequestID generates a new random request ID.
+func NewRequestID() string { return guuid.NewString() }
+
+// UnaryRequestIDInterceptor stores the request ID of gRPC unary calls in their
+// context, generating one if the caller didn't send one. The ID is returned to
+// the caller in the response header.
+func UnaryRequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id := incomingRequestID(ctx)
+		ctx = plog.WithRequestID(ctx, id)
+
+		// Failing to set the header shouldn't fail the call.
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, id))
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamRequestIDInterceptor stores the request ID of gRPC stream calls in their
+// context, generating one if the caller didn't send one. The ID is returned to
+// the caller in the response header.
+func StreamRequestIDInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id := incomingRequestID(ss.Context())
+		ctx := plog.WithRequestID(ss.Context(), id)
+
+		_ = ss.SetHeader(metadata.Pairs(RequestIDMetadataKey, id))
+
+		return handler(srv, &serverStream{ss, ctx})
+	}
+}
+
+// UnaryClientRequestIDInterceptor propagates the request ID stored in the context
+// of outgoing gRPC unary calls, generating one if it doesn't exist.
+func UnaryClientRequestIDInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoingRequestID(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientRequestIDInterceptor propagates the request ID stored in the context
+// of outgoing gRPC stream calls, generating one if it doesn't exist.
+func StreamClientRequestIDInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingRequestID(ctx), desc, cc, method, opts...)
+	}
+}
+
+// incomingRequestID returns the request ID sent by the caller, or a new one.
+func incomingRequestID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if vals := md.Get(RequestIDMetadataKey); len(vals) > 0 && vals[0] != "" {
+		return vals[0]
+	}
+
+	return NewRequestID()
+}
+
+// outgoingRequestID stores the context's request ID, or a new one, in both the
+// context and its outgoing metadata.
+func outgoingRequestID(ctx context.Context) context.Context {
+	id := plog.RequestID(ctx)
+	if id == "" {
+		id = NewRequestID()
+		ctx = plog.WithRequestID(ctx, id)
+	}
+
+	// Don't overwrite an ID that was explicitly set by the caller.
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if len(md.Get(RequestIDMetadataKey)) > 0 {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, RequestIDMetadataKey, id)
+}
@@ -0,0 +1,58 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	plog "github.com/loshz/platform/internal/log"
+)
+
+func TestRequestIDInterceptors(t *testing.T) {
+	server := UnaryRequestIDInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "test"}
+
+	// Mock an invoker that forwards outgoing metadata to a server interceptor and
+	// returns the request ID seen by the handler.
+	var received string
+	invoker := func(ctx context.Context, _ string, req, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		ctx = metadata.NewIncomingContext(context.Background(), md)
+
+		_, err := server(ctx, req, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			received = plog.RequestID(ctx)
+			return nil, nil
+		})
+
+		return err
+	}
+	client := UnaryClientRequestIDInterceptor()
+
+	t.Run("TestPropagated", func(t *testing.T) {
+		ctx := plog.WithRequestID(context.Background(), "1234")
+		require.NoError(t, client(ctx, "test", nil, nil, nil, invoker))
+
+		// Assert the client request ID is used by the server.
+		assert.Equal(t, "1234", received)
+	})
+
+	t.Run("TestGenerated", func(t *testing.T) {
+		require.NoError(t, client(context.Background(), "test", nil, nil, nil, invoker))
+
+		// Assert a request ID is generated if one isn't set.
+		assert.NotEmpty(t, received)
+	})
+
+	t.Run("TestExplicitMetadata", func(t *testing.T) {
+		ctx := plog.WithRequestID(context.Background(), "1234")
+		ctx = metadata.AppendToOutgoingContext(ctx, RequestIDMetadataKey, "5678")
+		require.NoError(t, client(ctx, "test", nil, nil, nil, invoker))
+
+		// Assert existing metadata isn't overwritten.
+		assert.Equal(t, "5678", received)
+	})
+}
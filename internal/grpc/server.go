@@ -1,45 +1,68 @@
 package grpc
 
 import (
-	"context"
-	"fmt"
 	"net"
+	"strings"
+	"sync"
 
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 )
 
 type ServiceServer interface {
 	RegisterService(sd *grpc.ServiceDesc, svc interface{})
-	Serve(ctx context.Context, port int) error
+	EnableReflection()
+	Serve(lst net.Listener) error
+	OnShutdown(fn func())
 	Shutdown()
+	Stop()
 }
 
 // Server is a wrapper around a *grpc.Server. It provides helper functions
 // for starting the server and registering services.
 type Server struct {
 	srv *grpc.Server
+
+	// Registered service implementations keyed by full service name.
+	mtx      sync.RWMutex
+	services map[string]registeredService
+
+	// Functions called once when the server begins shutting down.
+	hooks    []func()
+	hookOnce sync.Once
+}
+
+// registeredService stores a service description alongside its implementation
+// so that methods can be invoked outside of the gRPC transport.
+type registeredService struct {
+	desc *grpc.ServiceDesc
+	impl interface{}
 }
 
 func NewServer(opts []grpc.ServerOption) *Server {
 	return &Server{
-		srv: grpc.NewServer(opts...),
+		srv:      grpc.NewServer(opts...),
+		services: make(map[string]registeredService),
 	}
 }
 
 // RegisterService registers a gRPC service to the underlying server.
 func (s *Server) RegisterService(sd *grpc.ServiceDesc, svc interface{}) {
 	s.srv.RegisterService(sd, svc)
+
+	s.mtx.Lock()
+	s.services[sd.ServiceName] = registeredService{sd, svc}
+	s.mtx.Unlock()
 }
 
-// Server starts the *grpc.Server on a given port in a goroutine. It waits for the
-// server's context to be done before gracefully shutting down.
-func (s *Server) Serve(ctx context.Context, port int) error {
-	lst, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
-	if err != nil {
-		return err
-	}
+// EnableReflection registers the gRPC server reflection service, allowing
+// clients such as grpcurl to discover registered services at runtime.
+// It must be called before Serve.
+func (s *Server) EnableReflection() { reflection.Register(s.srv) }
 
+// Serve accepts connections on a listener, blocking until the server is stopped.
+func (s *Server) Serve(lst net.Listener) error {
 	log.Info().Msgf("grpc server running on %s", lst.Addr())
 	if err := s.srv.Serve(lst); err != grpc.ErrServerStopped {
 		return err
@@ -48,4 +71,67 @@ func (s *Server) Serve(ctx context.Context, port int) error {
 	return nil
 }
 
-func (s *Server) Shutdown() { s.srv.GracefulStop() }
+// OnShutdown registers a function that is called when the server begins
+// shutting down, e.g. to close long lived streams that would otherwise block
+// a graceful stop. It must be called before Serve.
+func (s *Server) OnShutdown(fn func()) { s.hooks = append(s.hooks, fn) }
+
+// Shutdown gracefully stops the server, blocking until pending calls complete.
+func (s *Server) Shutdown() {
+	s.runHooks()
+	s.srv.GracefulStop()
+}
+
+// Stop immediately stops the server, cancelling pending calls.
+func (s *Server) Stop() {
+	s.runHooks()
+	s.srv.Stop()
+}
+
+// runHooks calls the registered shutdown hooks once.
+func (s *Server) runHooks() {
+	s.hookOnce.Do(func() {
+		for _, fn := range s.hooks {
+			fn()
+		}
+	})
+}
+
+// unaryHandler finds the registered implementation and handler of a unary method
+// by its full name, e.g. /proto.v1.DiscoveryService/GetServices.
+func (s *Server) unaryHandler(fullMethod string) (interface{}, grpc.MethodDesc, bool) {
+	service, method, ok := splitMethodName(fullMethod)
+	if !ok {
+		return nil, grpc.MethodDesc{}, false
+	}
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	svc, ok := s.services[service]
+	if !ok {
+		return nil, grpc.MethodDesc{}, false
+	}
+
+	for _, md := range svc.desc.Methods {
+		if md.MethodName == method {
+			return svc.impl, md, true
+		}
+	}
+
+	return nil, grpc.MethodDesc{}, false
+}
+
+// splitMethodName splits a full gRPC method name into its service and method parts.
+func splitMethodName(fullMethod string) (string, string, bool) {
+	if !strings.HasPrefix(fullMethod, "/") {
+		return "", "", false
+	}
+
+	i := strings.LastIndex(fullMethod, "/")
+	if i <= 0 {
+		return "", "", false
+	}
+
+	return fullMethod[1:i], fullMethod[i+1:], true
+}
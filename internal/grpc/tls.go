@@ -3,14 +3,19 @@ package grpc
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"strings"
 
 	"google.golang.org/grpc/credentials"
+
+	"github.com/loshz/platform/internal/authz"
 )
 
-// NewTLSConfig takes the paths of a server cert/key and returns
-// TLS transport credentials. TLS 1.3 is the minimum version.
+// NewTLSConfig takes the paths of a cert/key and returns a TLS config.
+// TLS 1.3 is the minimum version.
 func NewTLSConfig(crt, key string) (*tls.Config, error) {
 	cert, err := tls.LoadX509KeyPair(crt, key)
 	if err != nil {
@@ -18,10 +23,8 @@ func NewTLSConfig(crt, key string) (*tls.Config, error) {
 	}
 
 	tlsConfig := &tls.Config{
-		Certificates:       []tls.Certificate{cert},
-		ClientAuth:         tls.RequireAndVerifyClientCert,
-		MinVersion:         tls.VersionTLS13,
-		InsecureSkipVerify: true, // TODO: use Let's Encrypt/mkcert?
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS13,
 	}
 
 	return tlsConfig, nil
@@ -37,15 +40,15 @@ func NewCertPool(ca string) (*x509.CertPool, error) {
 
 	capool := x509.NewCertPool()
 	if ok := capool.AppendCertsFromPEM(data); !ok {
-		return nil, err
+		return nil, errors.New("no valid certificates found")
 	}
 
 	return capool, nil
 }
 
-// NewServerTransportCreds creates gRPC Transport Credentials with a client CA
-// configured.
-func NewServerTransportCreds(ca, crt, key string) (credentials.TransportCredentials, error) {
+// NewServerTLSConfig creates a server TLS config that requires and verifies
+// client certs signed by the given CA.
+func NewServerTLSConfig(ca, crt, key string) (*tls.Config, error) {
 	capool, err := NewCertPool(ca)
 	if err != nil {
 		return nil, fmt.Errorf("error loading ca cert pool: %w", err)
@@ -58,16 +61,17 @@ func NewServerTransportCreds(ca, crt, key string) (credentials.TransportCredenti
 
 	// Set the client CA.
 	tlsConfig.ClientCAs = capool
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
 
-	return credentials.NewTLS(tlsConfig), nil
+	return tlsConfig, nil
 }
 
-// NewClientTransportCreds creates gRPC Transport Credentials with a root CA
-// configured.
-func NewClientTransportCreds(ca, crt, key string) (credentials.TransportCredentials, error) {
+// NewClientTLSConfig creates a client TLS config with a root CA configured.
+// Server certs are verified against the dialed host name.
+func NewClientTLSConfig(ca, crt, key string) (*tls.Config, error) {
 	capool, err := NewCertPool(ca)
 	if err != nil {
-		return nil, fmt.Errorf("error loading ca cert pool : %w", err)
+		return nil, fmt.Errorf("error loading ca cert pool: %w", err)
 	}
 
 	tlsConfig, err := NewTLSConfig(crt, key)
@@ -78,5 +82,127 @@ func NewClientTransportCreds(ca, crt, key string) (credentials.TransportCredenti
 	// Set the root CA.
 	tlsConfig.RootCAs = capool
 
+	return tlsConfig, nil
+}
+
+// NewServerTransportCreds creates gRPC Transport Credentials with a client CA
+// configured.
+func NewServerTransportCreds(ca, crt, key string) (credentials.TransportCredentials, error) {
+	tlsConfig, err := NewServerTLSConfig(ca, crt, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// NewClientTransportCreds creates gRPC Transport Credentials with a root CA
+// configured.
+func NewClientTransportCreds(ca, crt, key string) (credentials.TransportCredentials, error) {
+	tlsConfig, err := NewClientTLSConfig(ca, crt, key)
+	if err != nil {
+		return nil, err
+	}
+
 	return credentials.NewTLS(tlsConfig), nil
 }
+
+// CertPoolFunc returns the CA cert pool that peer certs are verified against.
+// This allows the pool to be rotated without recreating TLS configs.
+type CertPoolFunc func() *x509.CertPool
+
+// VerifyServer returns a func that can be used as tls.Config.VerifyConnection
+// to verify that a server presents a given identity, signed by the current roots.
+//
+// SPIFFE IDs, e.g. spiffe://platform/eventd, are matched against the URI SANs
+// of the server cert. An ID matches itself and any child path, so the example
+// accepts any eventd instance. Any other identity is treated as a DNS name or
+// IP address. An empty identity verifies the dialed host name.
+//
+// As the func performs all verification, it must be used with InsecureSkipVerify
+// to disable the default host name verification.
+func VerifyServer(roots CertPoolFunc, identity string) (func(tls.ConnectionState) error, error) {
+	if !strings.HasPrefix(identity, authz.SpiffeScheme+"://") {
+		return VerifyHostname(roots, identity), nil
+	}
+
+	id, err := url.Parse(identity)
+	if err != nil || id.Host == "" {
+		return nil, fmt.Errorf("invalid spiffe id '%s'", identity)
+	}
+
+	return VerifySpiffeID(roots, id), nil
+}
+
+// VerifyHostname returns a func that can be used as tls.Config.VerifyConnection
+// to verify a peer's cert chain against the current roots and ensure it is valid
+// for a host name. An empty name verifies the server name of the connection.
+func VerifyHostname(roots CertPoolFunc, name string) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		host := name
+		if host == "" {
+			host = cs.ServerName
+		}
+
+		_, err := verifyPeer(cs, roots(), host)
+		return err
+	}
+}
+
+// VerifySpiffeID returns a func that can be used as tls.Config.VerifyConnection
+// to verify a peer's cert chain against the current roots and ensure one of its
+// URI SANs matches an expected SPIFFE ID or is a child of it.
+func VerifySpiffeID(roots CertPoolFunc, expected *url.URL) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		leaf, err := verifyPeer(cs, roots(), "")
+		if err != nil {
+			return err
+		}
+
+		for _, uri := range leaf.URIs {
+			if matchSpiffeID(expected, uri) {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("peer certificate does not match spiffe id '%s'", expected)
+	}
+}
+
+// verifyPeer verifies a server's cert chain against the given roots, and host
+// name if not empty, returning the leaf cert. The leaf must be valid for server
+// authentication.
+func verifyPeer(cs tls.ConnectionState, roots *x509.CertPool, name string) (*x509.Certificate, error) {
+	if len(cs.PeerCertificates) == 0 {
+		return nil, errors.New("no peer certificates presented")
+	}
+
+	leaf := cs.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       name,
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error verifying peer certificate: %w", err)
+	}
+
+	return leaf, nil
+}
+
+// matchSpiffeID reports whether a SPIFFE ID equals, or is a child of, an
+// expected ID.
+func matchSpiffeID(expected, id *url.URL) bool {
+	if id.Scheme != authz.SpiffeScheme || !strings.EqualFold(id.Host, expected.Host) {
+		return false
+	}
+
+	path := strings.TrimSuffix(expected.Path, "/")
+	return id.Path == path || strings.HasPrefix(id.Path, path+"/")
+}
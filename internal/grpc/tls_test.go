@@ -0,0 +1,186 @@
+package grpc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCert generates a cert signed by the given parent, or a self-signed CA if
+// parent is nil, and writes the cert and key to a directory.
+func testCert(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, tmpl *x509.Certificate) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.NotBefore = time.Now().Add(-time.Minute)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".key.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return cert, key
+}
+
+// testHandshake performs a TLS handshake between a client and server config.
+func testHandshake(client, server *tls.Config) error {
+	c, s := net.Pipe()
+	defer c.Close()
+	defer s.Close()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- tls.Server(s, server).Handshake() }()
+
+	err := tls.Client(c, client).Handshake()
+	if err != nil {
+		return err
+	}
+
+	return <-errCh
+}
+
+func TestTLSVerification(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	ca, caKey := testCert(t, dir, "ca", nil, nil, &x509.Certificate{Subject: pkix.Name{CommonName: "ca"}})
+	testCert(t, dir, "server", ca, caKey, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "eventd"},
+		DNSNames: []string{"eventd"},
+		URIs:     []*url.URL{{Scheme: "spiffe", Host: "platform", Path: "/eventd/eventd-1234"}},
+	})
+	testCert(t, dir, "client", ca, caKey, &x509.Certificate{Subject: pkix.Name{CommonName: "trafficd"}})
+
+	server, err := NewServerTLSConfig(path("ca.crt.pem"), path("server.crt.pem"), path("server.key.pem"))
+	require.NoError(t, err)
+	client, err := NewClientTLSConfig(path("ca.crt.pem"), path("client.crt.pem"), path("client.key.pem"))
+	require.NoError(t, err)
+
+	// Mock the host name set by grpc when dialing a target.
+	dial := func(conf *tls.Config, host string) error {
+		conf = conf.Clone()
+		conf.ServerName = host
+		return testHandshake(conf, server)
+	}
+
+	// Create a client config that verifies a server identity.
+	withIdentity := func(identity string) (*tls.Config, error) {
+		verify, err := VerifyServer(func() *x509.CertPool { return client.RootCAs }, identity)
+		if err != nil {
+			return nil, err
+		}
+
+		conf := client.Clone()
+		conf.InsecureSkipVerify = true
+		conf.VerifyConnection = verify
+		return conf, nil
+	}
+
+	t.Run("TestHostName", func(t *testing.T) {
+		assert.NoError(t, dial(client, "eventd"))
+		assert.Error(t, dial(client, "discoveryd"))
+
+		// Assert custom verification matches the default.
+		conf, err := withIdentity("")
+		require.NoError(t, err)
+		assert.NoError(t, dial(conf, "eventd"))
+		assert.Error(t, dial(conf, "discoveryd"))
+	})
+
+	t.Run("TestServerName", func(t *testing.T) {
+		conf, err := withIdentity("eventd")
+		require.NoError(t, err)
+
+		// Assert the identity is verified instead of the dialed host.
+		assert.NoError(t, dial(conf, "10.0.0.1"))
+	})
+
+	t.Run("TestSpiffeID", func(t *testing.T) {
+		for identity, valid := range map[string]bool{
+			"spiffe://platform/eventd":             true,
+			"spiffe://platform/eventd/eventd-1234": true,
+			"spiffe://platform/event":              false,
+			"spiffe://platform/trafficd":           false,
+			"spiffe://other/eventd":                false,
+		} {
+			conf, err := withIdentity(identity)
+			require.NoError(t, err)
+
+			err = dial(conf, "10.0.0.1")
+			if valid {
+				assert.NoError(t, err, identity)
+			} else {
+				assert.Error(t, err, identity)
+			}
+		}
+
+		_, err := withIdentity("spiffe:///eventd")
+		assert.Error(t, err)
+	})
+
+	t.Run("TestUntrustedCA", func(t *testing.T) {
+		otherDir := t.TempDir()
+		otherCA, otherKey := testCert(t, otherDir, "ca", nil, nil, &x509.Certificate{Subject: pkix.Name{CommonName: "other"}})
+		testCert(t, otherDir, "server", otherCA, otherKey, &x509.Certificate{
+			DNSNames: []string{"eventd"},
+			URIs:     []*url.URL{{Scheme: "spiffe", Host: "platform", Path: "/eventd"}},
+		})
+		other, err := NewServerTLSConfig(path("ca.crt.pem"), filepath.Join(otherDir, "server.crt.pem"), filepath.Join(otherDir, "server.key.pem"))
+		require.NoError(t, err)
+
+		conf, err := withIdentity("spiffe://platform/eventd")
+		require.NoError(t, err)
+
+		// Assert certs signed by other CAs are rejected.
+		assert.Error(t, testHandshake(conf, other))
+	})
+
+	t.Run("TestClientCert", func(t *testing.T) {
+		otherDir := t.TempDir()
+		testCert(t, otherDir, "server", ca, caKey, &x509.Certificate{
+			DNSNames:    []string{"eventd"},
+			URIs:        []*url.URL{{Scheme: "spiffe", Host: "platform", Path: "/eventd"}},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		other, err := NewServerTLSConfig(path("ca.crt.pem"), filepath.Join(otherDir, "server.crt.pem"), filepath.Join(otherDir, "server.key.pem"))
+		require.NoError(t, err)
+
+		// Assert servers presenting certs only valid for client auth are rejected.
+		for _, identity := range []string{"", "eventd", "spiffe://platform/eventd"} {
+			conf, err := withIdentity(identity)
+			require.NoError(t, err)
+
+			conf.ServerName = "eventd"
+			assert.Error(t, testHandshake(conf, other), identity)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/loshz/platform/internal/authz"
+	"github.com/loshz/platform/internal/metrics"
+	"github.com/loshz/platform/internal/token"
+)
+
+const (
+	// TokenMetadataKey is the metadata key of bearer tokens.
+	TokenMetadataKey = "authorization"
+
+	// tokenPrefix is the auth scheme prefix of bearer tokens.
+	tokenPrefix = "Bearer "
+)
+
+// errMissingToken is returned when a required bearer token isn't sent.
+var errMissingToken = errors.New("missing bearer token")
+
+// TokenCredentials implements credentials.PerRPCCredentials, attaching a bearer
+// token to every call. The audience of each token is the full name of the gRPC
+// service being called, e.g. proto.v1.EventService, so tokens can't be replayed
+// against other APIs. Tokens are signed with the current cert and cached until
+// half their lifetime has passed or the cert changes.
+type TokenCredentials struct {
+	cert func() *tls.Certificate
+	ttl  time.Duration
+
+	mtx    sync.Mutex
+	tokens map[string]cachedToken
+}
+
+// cachedToken is a signed token and when it should be refreshed.
+type cachedToken struct {
+	token   string
+	signer  *tls.Certificate
+	refresh time.Time
+}
+
+// NewTokenCredentials creates per-RPC credentials that attach tokens signed
+// with the cert returned by the given func.
+func NewTokenCredentials(cert func() *tls.Certificate, ttl time.Duration) *TokenCredentials {
+	return &TokenCredentials{
+		cert:   cert,
+		ttl:    ttl,
+		tokens: make(map[string]cachedToken),
+	}
+}
+
+// GetRequestMetadata returns the bearer token metadata for a call. The uri is
+// of the form https://<authority>/<service>.
+func (c *TokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	var audience string
+	if len(uri) > 0 {
+		audience = uri[0][strings.LastIndex(uri[0], "/")+1:]
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	cert := c.cert()
+	cached := c.tokens[audience]
+	if cached.token == "" || cert != cached.signer || time.Now().After(cached.refresh) {
+		tok, err := token.Sign(cert, audience, c.ttl)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		cached = cachedToken{token: tok, signer: cert, refresh: time.Now().Add(c.ttl / 2)}
+		c.tokens[audience] = cached
+	}
+
+	return map[string]string{TokenMetadataKey: tokenPrefix + cached.token}, nil
+}
+
+// RequireTransportSecurity reports that tokens must only be sent over TLS.
+func (c *TokenCredentials) RequireTransportSecurity() bool { return true }
+
+// TokenVerifier verifies a bearer token for an audience, returning the identity
+// of its signer.
+type TokenVerifier func(token, audience string) (authz.Identity, error)
+
+// UnaryTokenInterceptor verifies bearer tokens of gRPC unary calls, storing the
+// identity of valid tokens in the call context so it is used for authorization.
+// Calls with invalid tokens, or without a token if required, are audited and
+// return an Unauthenticated error.
+func UnaryTokenInterceptor(service_id string, verify TokenVerifier, required bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := verifyToken(ctx, service_id, verify, required, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamTokenInterceptor verifies bearer tokens of gRPC stream calls.
+// See UnaryTokenInterceptor.
+func StreamTokenInterceptor(service_id string, verify TokenVerifier, required bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := verifyToken(ss.Context(), service_id, verify, required, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ss, ctx})
+	}
+}
+
+// verifyToken verifies the bearer token of a call, if present, auditing any
+// rejected calls.
+func verifyToken(ctx context.Context, service_id string, verify TokenVerifier, required bool, method string) (context.Context, error) {
+	tok := incomingToken(ctx)
+	if tok == "" && !required {
+		return ctx, nil
+	}
+
+	err := errMissingToken
+	if tok != "" {
+		// Tokens are intended for the called gRPC service.
+		audience, _, _ := splitMethodName(method)
+		var id authz.Identity
+		if id, err = verify(tok, audience); err == nil {
+			return authz.WithIdentity(ctx, id), nil
+		}
+	}
+
+	metrics.GRPCTokenRejectedTotal.WithLabelValues(service_id, method).Inc()
+	addr, subject := peerInfo(ctx)
+	log.Warn().
+		Ctx(ctx).
+		Err(err).
+		Bool("audit", true).
+		Str("grpc.method", method).
+		Str("peer.address", addr).
+		Str("peer.subject", subject).
+		Msg("grpc call unauthenticated")
+
+	return nil, status.Error(codes.Unauthenticated, err.Error())
+}
+
+// incomingToken returns the bearer token of an incoming call, if any.
+func incomingToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	for _, val := range md.Get(TokenMetadataKey) {
+		if len(val) > len(tokenPrefix) && strings.EqualFold(val[:len(tokenPrefix)], tokenPrefix) {
+			return val[len(tokenPrefix):]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,94 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/authz"
+	"github.com/loshz/platform/internal/metrics"
+)
+
+func TestUnaryTokenInterceptor(t *testing.T) {
+	method := apiv1.EventService_Event_FullMethodName
+	id := authz.Identity{Service: "trafficd", Instance: "trafficd-00000000-0000-0000-0000-000000000001"}
+
+	// Mock a verifier that accepts a single token for the event service.
+	verify := func(tok, audience string) (authz.Identity, error) {
+		if tok != "valid" || audience != "proto.v1.EventService" {
+			return authz.Identity{}, errors.New("invalid token")
+		}
+		return id, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return authz.IdentityFromContext(ctx), nil
+	}
+	withToken := func(tok string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(TokenMetadataKey, "Bearer "+tok))
+	}
+
+	t.Run("TestValid", func(t *testing.T) {
+		res, err := UnaryTokenInterceptor("token_test", verify, true)(withToken("valid"), nil, info, handler)
+
+		// Assert the token identity is used for the call.
+		require.NoError(t, err)
+		assert.Equal(t, id, res)
+	})
+
+	t.Run("TestInvalid", func(t *testing.T) {
+		rejected := metrics.GRPCTokenRejectedTotal.WithLabelValues("token_test", method)
+		before := testutil.ToFloat64(rejected)
+
+		_, err := UnaryTokenInterceptor("token_test", verify, false)(withToken("invalid"), nil, info, handler)
+
+		// Assert invalid tokens are rejected even if not required.
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Equal(t, before+1, testutil.ToFloat64(rejected))
+	})
+
+	t.Run("TestMissing", func(t *testing.T) {
+		_, err := UnaryTokenInterceptor("token_test", verify, true)(context.Background(), nil, info, handler)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("TestOptional", func(t *testing.T) {
+		res, err := UnaryTokenInterceptor("token_test", verify, false)(context.Background(), nil, info, handler)
+		require.NoError(t, err)
+		assert.True(t, res.(authz.Identity).IsAnonymous())
+	})
+}
+
+func TestTokenCredentials(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := testCert(t, dir, "ca", nil, nil, &x509.Certificate{})
+	leaf, key := testCert(t, dir, "trafficd", ca, caKey, &x509.Certificate{DNSNames: []string{"trafficd"}})
+	cert := &tls.Certificate{Certificate: [][]byte{leaf.Raw}, PrivateKey: key, Leaf: leaf}
+	creds := NewTokenCredentials(func() *tls.Certificate { return cert }, time.Minute)
+
+	md, err := creds.GetRequestMetadata(context.Background(), "https://eventd:8004/proto.v1.EventService")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(md[TokenMetadataKey], "Bearer "))
+
+	// Assert tokens are cached per audience.
+	cached, err := creds.GetRequestMetadata(context.Background(), "https://eventd:8004/proto.v1.EventService")
+	require.NoError(t, err)
+	assert.Equal(t, md, cached)
+
+	other, err := creds.GetRequestMetadata(context.Background(), "https://discoveryd:8000/proto.v1.DiscoveryService")
+	require.NoError(t, err)
+	assert.NotEqual(t, md, other)
+}
@@ -0,0 +1,164 @@
+package grpc
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/loshz/platform/internal/tracing"
+)
+
+// UnaryTracingInterceptor creates a server span for gRPC unary calls, continuing
+// any trace propagated in the incoming metadata.
+func UnaryTracingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := startServerSpan(ctx, info.FullMethod)
+		defer span.End()
+
+		res, err := handler(ctx, req)
+		endSpan(span, err)
+
+		return res, err
+	}
+}
+
+// StreamTracingInterceptor creates a server span for gRPC stream calls, continuing
+// any trace propagated in the incoming metadata.
+func StreamTracingInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startServerSpan(ss.Context(), info.FullMethod)
+		defer span.End()
+
+		err := handler(srv, &serverStream{ss, ctx})
+		endSpan(span, err)
+
+		return err
+	}
+}
+
+// UnaryClientTracingInterceptor creates a client span for outgoing gRPC unary
+// calls, propagating the trace in the outgoing metadata.
+func UnaryClientTracingInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := startClientSpan(ctx, method)
+		defer span.End()
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		endSpan(span, err)
+
+		return err
+	}
+}
+
+// StreamClientTracingInterceptor creates a client span for outgoing gRPC stream
+// calls, propagating the trace in the outgoing metadata. The span is ended once
+// the stream has finished.
+func StreamClientTracingInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := startClientSpan(ctx, method)
+
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			endSpan(span, err)
+			span.End()
+			return nil, err
+		}
+
+		return newClientStream(ctx, desc, cs, func(err error) {
+			endSpan(span, err)
+			span.End()
+		}), nil
+	}
+}
+
+// startServerSpan extracts the trace context from incoming metadata and starts
+// a new server span.
+func startServerSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier{md: md})
+
+	return tracing.Tracer().Start(ctx, fullMethod,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(rpcAttributes(fullMethod)...),
+	)
+}
+
+// startClientSpan starts a new client span and injects its trace context into
+// the outgoing metadata.
+func startClientSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	ctx, span := tracing.Tracer().Start(ctx, fullMethod,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(rpcAttributes(fullMethod)...),
+	)
+
+	// Copy existing metadata so it isn't modified concurrently.
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, metadataCarrier{md: md})
+
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+// endSpan records the status of a call on a span.
+func endSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+}
+
+// rpcAttributes returns the semantic span attributes of a gRPC method.
+func rpcAttributes(fullMethod string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.RPCSystemGRPC}
+	if service, method, ok := splitMethodName(fullMethod); ok {
+		attrs = append(attrs, semconv.RPCService(service), semconv.RPCMethod(method))
+	}
+
+	return attrs
+}
+
+// metadataCarrier adapts gRPC metadata to a propagation.TextMapCarrier.
+type metadataCarrier struct {
+	md metadata.MD
+}
+
+func (c metadataCarrier) Get(key string) string {
+	if vals := c.md.Get(key); len(vals) > 0 {
+		return vals[0]
+	}
+
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) { c.md.Set(key, value) }
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.md))
+	for key := range c.md {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// serverStream wraps a grpc.ServerStream in order to override its context.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
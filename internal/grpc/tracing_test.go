@@ -0,0 +1,102 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+)
+
+func TestTracingInterceptors(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	method := apiv1.EventService_Event_FullMethodName
+
+	// Mock a client call that forwards outgoing metadata to a server interceptor.
+	server := UnaryTracingInterceptor()
+	invoker := func(ctx context.Context, method string, req, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		ctx = metadata.NewIncomingContext(context.Background(), md)
+
+		_, err := server(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			// Assert the server span is available to the handler.
+			assert.True(t, trace.SpanContextFromContext(ctx).IsValid())
+			return nil, status.Error(codes.NotFound, "not found")
+		})
+
+		return err
+	}
+
+	err := UnaryClientTracingInterceptor()(context.Background(), method, nil, nil, nil, invoker)
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	srv, client := spans[0], spans[1]
+
+	// Assert the server span continues the client trace.
+	assert.Equal(t, trace.SpanKindClient, client.SpanKind())
+	assert.Equal(t, trace.SpanKindServer, srv.SpanKind())
+	assert.Equal(t, client.SpanContext().TraceID(), srv.SpanContext().TraceID())
+	assert.Equal(t, client.SpanContext().SpanID(), srv.Parent().SpanID())
+
+	// Assert errors are recorded on both spans.
+	assert.Equal(t, method, srv.Name())
+	assert.Equal(t, otelcodes.Error, srv.Status().Code)
+	assert.Equal(t, otelcodes.Error, client.Status().Code)
+}
+
+func TestStreamClientTracingInterceptor(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	streamer := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &mockClientStream{}, nil
+	}
+	interceptor := StreamClientTracingInterceptor()
+
+	// Assert the span of a call that isn't server streaming ends with its
+	// only response.
+	cs, err := interceptor(context.Background(), &grpc.StreamDesc{ClientStreams: true}, nil, "client", streamer)
+	require.NoError(t, err)
+	require.NoError(t, cs.RecvMsg(nil))
+	require.Len(t, recorder.Ended(), 1)
+
+	// Assert the span of a server stream ends, with an error, once the call's
+	// context is cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	cs, err = interceptor(ctx, &grpc.StreamDesc{ServerStreams: true}, nil, "server", streamer)
+	require.NoError(t, err)
+	require.NoError(t, cs.RecvMsg(nil))
+	assert.Len(t, recorder.Ended(), 1)
+
+	cancel()
+	require.Eventually(t, func() bool { return len(recorder.Ended()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, otelcodes.Error, recorder.Ended()[1].Status().Code)
+}
+
+func TestMetadataCarrier(t *testing.T) {
+	c := metadataCarrier{md: metadata.MD{}}
+	c.Set("Traceparent", "value")
+
+	// Assert keys are normalised by metadata.
+	assert.Equal(t, "value", c.Get("traceparent"))
+	assert.Equal(t, []string{"traceparent"}, c.Keys())
+	assert.Empty(t, c.Get("missing"))
+}
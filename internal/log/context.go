@@ -0,0 +1,45 @@
+package log
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of a context that stores a request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in a context, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Ctx returns a child of the global logger whose events include the request
+// and trace IDs stored in a context.
+func Ctx(ctx context.Context) *zerolog.Logger {
+	l := log.Logger.With().Ctx(ctx).Logger()
+	return &l
+}
+
+// contextHook adds the request ID and trace and span IDs stored in an event's
+// context.
+type contextHook struct{}
+
+func (contextHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	ctx := e.GetCtx()
+
+	if id := RequestID(ctx); id != "" {
+		e.Str("request_id", id)
+	}
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		e.Str("trace_id", sc.TraceID().String()).Str("span_id", sc.SpanID().String())
+	}
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRequestID(t *testing.T) {
+	// Assert an empty ID is returned if one isn't set.
+	assert.Empty(t, RequestID(context.Background()))
+
+	ctx := WithRequestID(context.Background(), "1234")
+	assert.Equal(t, "1234", RequestID(ctx))
+}
+
+func TestContextHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Hook(contextHook{})
+
+	t.Run("TestEmptyContext", func(t *testing.T) {
+		buf.Reset()
+		logger.Info().Ctx(context.Background()).Msg("test")
+
+		// Assert no context fields are added.
+		assert.NotContains(t, buf.String(), "request_id")
+		assert.NotContains(t, buf.String(), "trace_id")
+	})
+
+	t.Run("TestContext", func(t *testing.T) {
+		buf.Reset()
+		sc := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{1},
+			SpanID:  trace.SpanID{2},
+		})
+		ctx := trace.ContextWithSpanContext(context.Background(), sc)
+		ctx = WithRequestID(ctx, "1234")
+		logger.Info().Ctx(ctx).Msg("test")
+
+		var line map[string]string
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+
+		// Assert the context IDs are added to the log line.
+		assert.Equal(t, "1234", line["request_id"])
+		assert.Equal(t, sc.TraceID().String(), line["trace_id"])
+		assert.Equal(t, sc.SpanID().String(), line["span_id"])
+	})
+}
+
+func TestCtx(t *testing.T) {
+	var buf bytes.Buffer
+	global := log.Logger
+	log.Logger = zerolog.New(&buf).Hook(contextHook{})
+	t.Cleanup(func() { log.Logger = global })
+
+	Ctx(WithRequestID(context.Background(), "1234")).Info().Msg("test")
+
+	// Assert events from the context logger include the request ID.
+	assert.Contains(t, buf.String(), `"request_id":"1234"`)
+}
@@ -2,24 +2,46 @@
 package log
 
 import (
+	"os"
+	"sync"
+
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
+// configureOnce ensures the global logger is only configured once.
+var configureOnce sync.Once
+
 // ConfigureGlobalLogging parses a given log level and sets it globally.
+//
+// The global logger is only configured by the first call, as a process usually
+// runs a single service. Services run in-process, e.g. by tests, share it.
 func ConfigureGlobalLogging(level, service, build string) {
 	// Parse and set the global log level.
+	if err := SetLevel(level); err != nil {
+		panic(err)
+	}
+
+	// Configure global logger defaults, writing to stderr and any log subscribers.
+	// Events with a context are annotated with its request and trace IDs.
+	configureOnce.Do(func() {
+		log.Logger = log.Output(zerolog.MultiLevelWriter(os.Stderr, tail)).With().Fields(map[string]interface{}{
+			"service": service,
+			"version": build,
+		}).Logger().Hook(contextHook{})
+	})
+}
+
+// SetLevel parses a given log level and sets it globally. It is safe to call
+// while logging.
+func SetLevel(level string) error {
 	lvl, err := zerolog.ParseLevel(level)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	// NOTE: global logger settings can be found here: https://github.com/rs/zerolog#global-settings
 	zerolog.SetGlobalLevel(lvl)
 
-	// Configure global logger defaults.
-	log.Logger = log.Logger.With().Fields(map[string]interface{}{
-		"service": service,
-		"version": build,
-	}).Logger()
+	return nil
 }
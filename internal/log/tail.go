@@ -0,0 +1,61 @@
+package log
+
+import "sync"
+
+// Number of log lines buffered per subscriber before lines are dropped.
+const tailBufferSize = 256
+
+// tail is the global broadcaster that all log lines are written to.
+var tail = &broadcaster{
+	subs: make(map[chan []byte]struct{}),
+}
+
+// Subscribe returns a channel that receives a copy of every log line written
+// after subscribing, and a function that must be called to unsubscribe.
+// Lines are dropped if the subscriber cannot keep up.
+func Subscribe() (<-chan []byte, func()) { return tail.subscribe() }
+
+// broadcaster is an io.Writer that fans out writes to any number of subscribers.
+type broadcaster struct {
+	mtx  sync.RWMutex
+	subs map[chan []byte]struct{}
+}
+
+func (b *broadcaster) Write(p []byte) (int, error) {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	if len(b.subs) == 0 {
+		return len(p), nil
+	}
+
+	// Writers may reuse p, so copy it before sending.
+	line := make([]byte, len(p))
+	copy(line, p)
+
+	for sub := range b.subs {
+		select {
+		case sub <- line:
+		default:
+		}
+	}
+
+	return len(p), nil
+}
+
+func (b *broadcaster) subscribe() (<-chan []byte, func()) {
+	ch := make(chan []byte, tailBufferSize)
+
+	b.mtx.Lock()
+	b.subs[ch] = struct{}{}
+	b.mtx.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mtx.Lock()
+			delete(b.subs, ch)
+			b.mtx.Unlock()
+		})
+	}
+}
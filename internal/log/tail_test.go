@@ -0,0 +1,22 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribe(t *testing.T) {
+	lines, unsubscribe := Subscribe()
+
+	// Assert written lines are received by the subscriber.
+	_, err := tail.Write([]byte("line"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("line"), <-lines)
+
+	// Assert lines are no longer received after unsubscribing.
+	unsubscribe()
+	_, err = tail.Write([]byte("line"))
+	assert.NoError(t, err)
+	assert.Empty(t, lines)
+}
@@ -25,3 +25,54 @@ var GRPCRequestDuration = promauto.NewHistogramVec(
 	},
 	[]string{"service_id", "code", "method", "type"},
 )
+
+// GRPCClientRequestsTotal represents the total number of gRPC client requests.
+var GRPCClientRequestsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "grpc_client_requests_total",
+		Help:      "Total number of gRPC client requests.",
+	},
+	[]string{"service_id", "code", "method", "type"},
+)
+
+// GRPCClientRequestDuration represents the duration of gRPC client requests in seconds.
+var GRPCClientRequestDuration = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "grpc_client_request_duration_seconds",
+		Help:      "Duration of gRPC client requests in seconds.",
+		Buckets:   prometheus.LinearBuckets(0.01, 0.01, 10),
+	},
+	[]string{"service_id", "code", "method", "type"},
+)
+
+// GRPCPanicsTotal represents the total number of panics recovered from gRPC handlers.
+var GRPCPanicsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "grpc_panics_total",
+		Help:      "Total number of panics recovered from gRPC handlers.",
+	},
+	[]string{"service_id", "method"},
+)
+
+// GRPCAuthzDeniedTotal represents the total number of gRPC calls denied by an authorization policy.
+var GRPCAuthzDeniedTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "grpc_authz_denied_total",
+		Help:      "Total number of gRPC calls denied by an authorization policy.",
+	},
+	[]string{"service_id", "method"},
+)
+
+// GRPCTokenRejectedTotal represents the total number of gRPC calls rejected due to invalid or missing bearer tokens.
+var GRPCTokenRejectedTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "grpc_token_rejected_total",
+		Help:      "Total number of gRPC calls rejected due to invalid or missing bearer tokens.",
+	},
+	[]string{"service_id", "method"},
+)
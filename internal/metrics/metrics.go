@@ -18,3 +18,33 @@ var ServiceInfo = promauto.NewCounterVec(
 	},
 	[]string{"service_id", "version"},
 )
+
+// ConfigReloadsTotal represents the total number of config reloads.
+var ConfigReloadsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "config_reloads_total",
+		Help:      "Total number of config reloads.",
+	},
+	[]string{"result"},
+)
+
+// ComponentFailuresTotal represents the total number of supervised component failures.
+var ComponentFailuresTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "component_failures_total",
+		Help:      "Total number of supervised component failures.",
+	},
+	[]string{"service_id", "component", "policy"},
+)
+
+// ComponentRestartsTotal represents the total number of supervised component restarts.
+var ComponentRestartsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "component_restarts_total",
+		Help:      "Total number of supervised component restarts.",
+	},
+	[]string{"service_id", "component", "policy"},
+)
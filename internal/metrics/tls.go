@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// TLSCertExpiry represents the expiry time of loaded TLS certificates as a unix timestamp.
+var TLSCertExpiry = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "tls_cert_expiry_timestamp_seconds",
+		Help:      "Expiry time of loaded TLS certificates as a unix timestamp.",
+	},
+	[]string{"credential", "cert"},
+)
+
+// TLSReloadsTotal represents the total number of TLS certificate reloads.
+var TLSReloadsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "tls_reloads_total",
+		Help:      "Total number of TLS certificate reloads.",
+	},
+	[]string{"credential", "result"},
+)
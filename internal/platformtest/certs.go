@@ -0,0 +1,160 @@
+package platformtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/loshz/platform/internal/authz"
+	"github.com/loshz/platform/internal/ca"
+)
+
+// certTTL is the lifetime of ephemeral certs, which only need to outlive a
+// test run.
+const certTTL = 24 * time.Hour
+
+// authority is an ephemeral CA that issues certs to service instances and
+// test clients, writing them to a directory so they can be loaded from config.
+type authority struct {
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+// certFiles are the paths of a PEM encoded cert and key.
+type certFiles struct {
+	cert string
+	key  string
+}
+
+// newAuthority generates a self-signed CA cert, written to a directory as
+// ca.crt.pem.
+func newAuthority(dir string) (*authority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating ca key: %w", err)
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "platformtest"},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(certTTL),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("error signing ca cert: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ca cert: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ca.crt.pem"), ca.EncodeCert(der), 0o600); err != nil {
+		return nil, fmt.Errorf("error writing ca cert: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &authority{dir: dir, cert: cert, key: key, pool: pool}, nil
+}
+
+// caFile returns the path of the CA cert.
+func (a *authority) caFile() string { return filepath.Join(a.dir, "ca.crt.pem") }
+
+// issue signs a cert for an identity, which can be used for both client and
+// server authentication. Like certs issued by the platform CA, it contains the
+// identity's SPIFFE ID and names as SANs, and it is also valid for localhost
+// so in-process servers can be dialed directly.
+func (a *authority) issue(id authz.Identity) (certFiles, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return certFiles{}, fmt.Errorf("error generating key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return certFiles{}, fmt.Errorf("error generating serial number: %w", err)
+	}
+
+	name := id.Service
+	dnsNames := []string{id.Service, "localhost"}
+	var uris []*url.URL
+	if id.Instance != "" {
+		name = id.Instance
+		dnsNames = []string{id.Service, id.Instance, "localhost"}
+		uris = []*url.URL{ca.SpiffeID(ca.DefaultTrustDomain, id)}
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     dnsNames,
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		URIs:         uris,
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(certTTL),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		return certFiles{}, fmt.Errorf("error signing cert: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return certFiles{}, fmt.Errorf("error encoding key: %w", err)
+	}
+
+	files := certFiles{
+		cert: filepath.Join(a.dir, name+".crt.pem"),
+		key:  filepath.Join(a.dir, name+".key.pem"),
+	}
+	if err := os.WriteFile(files.cert, ca.EncodeCert(der), 0o600); err != nil {
+		return certFiles{}, fmt.Errorf("error writing cert: %w", err)
+	}
+	if err := os.WriteFile(files.key, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return certFiles{}, fmt.Errorf("error writing key: %w", err)
+	}
+
+	return files, nil
+}
+
+// clientTLS returns the TLS config of a test client presenting a cert for an
+// identity, which verifies servers against the CA.
+func (a *authority) clientTLS(id authz.Identity) (*tls.Config, error) {
+	files, err := a.issue(id)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.LoadX509KeyPair(files.cert, files.key)
+	if err != nil {
+		return nil, fmt.Errorf("error loading client cert: %w", err)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      a.pool,
+		MinVersion:   tls.VersionTLS13,
+	}, nil
+}
@@ -0,0 +1,219 @@
+// Package platformtest runs platform services in-process for end-to-end tests.
+//
+// A Platform generates an ephemeral CA, starts discoveryd on random ports and
+// starts other services registered with it, all communicating over gRPC with
+// mTLS. Clients authenticated with a platformtest cert are exposed so tests can
+// call the services directly.
+package platformtest
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/authz"
+	"github.com/loshz/platform/internal/config"
+	"github.com/loshz/platform/internal/discoveryd"
+	"github.com/loshz/platform/internal/eventd"
+	pgrpc "github.com/loshz/platform/internal/grpc"
+	plog "github.com/loshz/platform/internal/log"
+	"github.com/loshz/platform/internal/service"
+	"github.com/loshz/platform/internal/trafficd"
+	"github.com/loshz/platform/internal/version"
+)
+
+// ClientIdentity is the identity presented by platformtest clients.
+const ClientIdentity = "platformtest"
+
+// App describes how a service is configured and run, as by its main package.
+type App struct {
+	Name string
+
+	// Configure loads the credentials, config and dependencies required to run
+	// the service, once config sources are loaded.
+	Configure func(*service.Service)
+
+	// Run initializes the service.
+	Run service.RunFunc
+}
+
+// Platform services.
+var (
+	Discoveryd = App{discoveryd.Name, discoveryd.Configure, discoveryd.Run}
+	Eventd     = App{eventd.Name, eventd.Configure, eventd.Run}
+	Trafficd   = App{trafficd.Name, trafficd.Configure, trafficd.Run}
+)
+
+// Platform is a set of services running in-process, sharing an ephemeral CA
+// and a discovery service.
+type Platform struct {
+	t  testing.TB
+	ca *authority
+
+	// TLS config of platformtest clients.
+	client *tls.Config
+
+	discoveryd *Instance
+	discovery  *grpc.ClientConn
+}
+
+// New starts discoveryd with optional config flags, e.g.
+// --discovery.eviction.window=1s. Services are stopped when the test and all
+// its subtests complete.
+func New(t testing.TB, flags ...string) *Platform {
+	t.Helper()
+
+	ca, err := newAuthority(t.TempDir())
+	require.NoError(t, err)
+	client, err := ca.clientTLS(authz.Identity{Service: ClientIdentity})
+	require.NoError(t, err)
+
+	p := &Platform{t: t, ca: ca, client: client}
+
+	// Services share the global logger, so logs are annotated with platformtest
+	// rather than the first service started.
+	plog.ConfigureGlobalLogging("warn", "platformtest", version.Build)
+
+	// The discovery service can't discover itself before it is serving.
+	p.discoveryd = p.Start(Discoveryd, append([]string{"--service.discovery.enabled=false"}, flags...)...)
+	p.discovery = p.Dial(p.discoveryd)
+
+	return p
+}
+
+// Start starts a service with optional config flags, which take precedence
+// over the platform's defaults. Services are registered with the platform's
+// discovery service at localhost. The test fails if the service doesn't start,
+// or sends an internal error before it is stopped.
+func (p *Platform) Start(app App, flags ...string) *Instance {
+	p.t.Helper()
+
+	s := service.New(app.Name)
+	files, err := p.ca.issue(authz.Identity{Service: s.Name(), Instance: s.ID()})
+	require.NoError(p.t, err)
+
+	defaults := []string{
+		"--service.log.level=warn",
+		"--service.register.addr=localhost",
+		"--service.register.interval=1s",
+		"--service.shutdown.drain=0s",
+		"--service.restart.backoff=100ms",
+		"--service.dependency.timeout=10s",
+		"--config.reload.interval=0s",
+		"--http.server.port=0",
+		"--grpc.server.port=0",
+		"--grpc.tls.ca=" + p.ca.caFile(),
+		"--grpc.server.cert=" + files.cert,
+		"--grpc.server.key=" + files.key,
+		"--grpc.client.cert=" + files.cert,
+		"--grpc.client.key=" + files.key,
+	}
+	if p.discoveryd != nil {
+		defaults = append(defaults, "--service.discovery.addr="+p.discoveryd.GrpcAddr())
+	}
+	s.LoadConfigSources(append(defaults, flags...))
+
+	app.Configure(s)
+	require.NoError(p.t, s.Start(app.Run), "error starting %s", app.Name)
+
+	i := &Instance{Service: s, done: make(chan struct{}), watched: make(chan struct{})}
+	go i.watchErrors(p.t)
+	p.t.Cleanup(func() {
+		if err := i.Stop(); err != nil {
+			p.t.Errorf("error stopping %s: %v", s.ID(), err)
+		}
+	})
+
+	return i
+}
+
+// Discoveryd returns the platform's discovery service instance.
+func (p *Platform) Discoveryd() *Instance { return p.discoveryd }
+
+// Dial returns a client connection to a service instance's gRPC server,
+// authenticated with a platformtest client cert. The connection is closed when
+// the test completes.
+func (p *Platform) Dial(i *Instance) *grpc.ClientConn {
+	p.t.Helper()
+
+	conn, err := pgrpc.Dial(context.Background(), i.GrpcAddr(), credentials.NewTLS(p.client), pgrpc.ClientPolicy{})
+	require.NoError(p.t, err)
+	p.t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+// Discovery returns a client of the platform's discovery service.
+func (p *Platform) Discovery() apiv1.DiscoveryServiceClient {
+	return apiv1.NewDiscoveryServiceClient(p.discovery)
+}
+
+// KV returns a client of the platform's discovery KV store.
+func (p *Platform) KV() apiv1.KVServiceClient {
+	return apiv1.NewKVServiceClient(p.discovery)
+}
+
+// Lookup returns the registered instances of a service, failing the test if
+// the lookup fails.
+func (p *Platform) Lookup(name string) []*apiv1.Service {
+	p.t.Helper()
+
+	res, err := p.Discovery().GetServices(context.Background(), &apiv1.GetServicesRequest{Name: name})
+	require.NoError(p.t, err)
+
+	return res.GetServices()
+}
+
+// Instance is a service running in-process.
+type Instance struct {
+	*service.Service
+
+	stopOnce sync.Once
+	stopErr  error
+
+	// Closed when the instance is stopped, and once errors are no longer
+	// watched, so the test isn't failed after it completes.
+	done    chan struct{}
+	watched chan struct{}
+}
+
+// GrpcAddr returns the address of the instance's gRPC server.
+func (i *Instance) GrpcAddr() string {
+	return fmt.Sprintf("localhost:%d", i.Config().Int(config.KeyGrpcServerPort))
+}
+
+// HTTPAddr returns the address of the instance's HTTP server.
+func (i *Instance) HTTPAddr() string {
+	return fmt.Sprintf("localhost:%d", i.Config().Int(config.KeyHttpServerPort))
+}
+
+// Stop gracefully stops the instance, as on shutdown. It is safe to call more
+// than once.
+func (i *Instance) Stop() error {
+	i.stopOnce.Do(func() {
+		close(i.done)
+		<-i.watched
+		i.stopErr = i.Service.Stop()
+	})
+
+	return i.stopErr
+}
+
+// watchErrors fails the test if the instance sends an internal error before
+// it is stopped.
+func (i *Instance) watchErrors(t testing.TB) {
+	defer close(i.watched)
+
+	select {
+	case err := <-i.Errors():
+		t.Errorf("internal service error %s: %v", i.ID(), err)
+	case <-i.done:
+	}
+}
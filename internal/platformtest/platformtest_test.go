@@ -0,0 +1,100 @@
+package platformtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/config"
+	"github.com/loshz/platform/internal/metrics"
+	"github.com/loshz/platform/internal/service"
+)
+
+func TestRegistration(t *testing.T) {
+	p := New(t)
+	eventd := p.Start(Eventd)
+
+	// Assert services register their servers for discovery once started.
+	require.Eventually(t, func() bool { return len(p.Lookup("eventd")) == 1 }, 5*time.Second, 10*time.Millisecond)
+	svc := p.Lookup("eventd")[0]
+	assert.Equal(t, eventd.ID(), svc.GetUuid())
+	assert.Equal(t, "localhost", svc.GetAddress())
+	assert.EqualValues(t, eventd.Config().Int(config.KeyGrpcServerPort), svc.GetGrpcPort())
+	assert.EqualValues(t, eventd.Config().Int(config.KeyHttpServerPort), svc.GetHttpPort())
+
+	// Assert services with discovery disabled aren't registered.
+	assert.Nil(t, p.Discoveryd().LastRegistration())
+
+	// Assert services are deregistered on shutdown.
+	require.NoError(t, eventd.Stop())
+	assert.Empty(t, p.Lookup("eventd"))
+}
+
+func TestEviction(t *testing.T) {
+	p := New(t, "--discovery.eviction.window=1s", "--discovery.eviction.interval=10ms")
+
+	// Assert services that haven't re-registered within the window are evicted.
+	_, err := p.Discovery().RegisterService(context.Background(), &apiv1.RegisterServiceRequest{
+		Service: &apiv1.Service{Uuid: "expired-1", LastSeen: time.Now().Add(-time.Hour).Unix()},
+	})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return len(p.Lookup("expired")) == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestEvents(t *testing.T) {
+	p := New(t)
+	eventd := p.Start(Eventd)
+
+	// Assert events can be sent directly.
+	res, err := apiv1.NewEventServiceClient(p.Dial(eventd)).Event(context.Background(), &apiv1.EventRequest{Hostname: "platformtest"})
+	require.NoError(t, err)
+	assert.Equal(t, "test", res.GetUuid())
+
+	// Assert trafficd waits for eventd, then sends it events.
+	trafficd := p.Start(Trafficd, "--traffic.event.interval=10ms")
+	assert.Equal(t, []service.Dependency{{Service: "eventd", Min: 1, Instances: 1, Ready: true}}, trafficd.Dependencies())
+
+	events := metrics.GRPCRequestsTotal.WithLabelValues(eventd.ID(), "OK", apiv1.EventService_Event_FullMethodName, "unary")
+	assert.Eventually(t, func() bool { return testutil.ToFloat64(events) >= 3 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRemoteConfig(t *testing.T) {
+	p := New(t)
+	eventd := p.Start(Eventd, "--config.remote.enabled")
+
+	// Assert config values put in the KV store are applied to running services.
+	_, err := p.KV().Put(context.Background(), &apiv1.PutKeyRequest{
+		Key:   service.RemoteServicePrefix + "eventd/" + config.KeyGrpcServerLogSample,
+		Value: "0.5",
+	})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return eventd.Config().Float64(config.KeyGrpcServerLogSample) == 0.5
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Assert security sensitive values put in the KV store are ignored. Values
+	// of a prefix are applied in order, so they have been applied once the
+	// later value is.
+	for key, value := range map[string]string{
+		config.KeyGrpcServerAuthz:      "true",
+		config.KeyServiceDiscoveryAddr: "localhost:1",
+	} {
+		_, err := p.KV().Put(context.Background(), &apiv1.PutKeyRequest{Key: service.RemoteServicePrefix + "eventd/" + key, Value: value})
+		require.NoError(t, err)
+	}
+	_, err = p.KV().Put(context.Background(), &apiv1.PutKeyRequest{
+		Key:   service.RemoteServicePrefix + "eventd/" + config.KeyGrpcServerLogSample,
+		Value: "0.25",
+	})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return eventd.Config().Float64(config.KeyGrpcServerLogSample) == 0.25
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, config.SourceDefault, eventd.Config().Source(config.KeyGrpcServerAuthz))
+	assert.NotEqual(t, config.SourceRemote, eventd.Config().Source(config.KeyServiceDiscoveryAddr))
+}
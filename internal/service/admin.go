@@ -0,0 +1,279 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/loshz/platform/internal/authz"
+	"github.com/loshz/platform/internal/config"
+	plog "github.com/loshz/platform/internal/log"
+)
+
+// Errors returned when admin callers aren't allowed.
+var (
+	errNoClientCert = errors.New("admin api requires a verified client certificate")
+	errNotLoopback  = errors.New("admin api only allows loopback callers without mtls")
+)
+
+// adminOnly restricts access to admin endpoints. Callers must present a client
+// certificate verified by the mTLS credentials, which must also be allowed by
+// the service's authz policy if configured. If the HTTP server isn't using
+// mTLS, only loopback callers are allowed.
+func (s *Service) adminOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := s.adminIdentity(r)
+		if err != nil {
+			log.Warn().Err(err).Str("path", r.URL.Path).Str("remote_addr", r.RemoteAddr).Msg("admin request denied")
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		// Record the caller of mutating requests.
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			log.Info().Bool("audit", true).Str("caller", id.String()).Str("method", r.Method).Str("path", r.URL.Path).Msg("admin request")
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// adminIdentity returns the identity of an admin caller, or an error if the
+// caller isn't allowed.
+func (s *Service) adminIdentity(r *http.Request) (authz.Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		if s.Config().String(config.KeyHttpServerTLS) == config.HttpMTLS {
+			return authz.Identity{}, errNoClientCert
+		}
+
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+			return authz.Identity{}, errNotLoopback
+		}
+		return authz.Identity{Service: "localhost"}, nil
+	}
+
+	id := authz.IdentityFromCert(r.TLS.VerifiedChains[0][0])
+	if s.authz != nil {
+		if err := s.authz.Authorize(id, r.URL.Path, nil); err != nil {
+			return id, err
+		}
+	}
+
+	return id, nil
+}
+
+// configHandler returns the effective service config and the source of each
+// value as JSON, with secrets redacted.
+func (s *Service) configHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.Config().Effective()); err != nil {
+		log.Error().Err(err).Msg("error encoding config response data")
+	}
+}
+
+// logsHandler streams service log lines as newline delimited JSON until either
+// the client disconnects or the service shuts down.
+func (s *Service) logsHandler(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Streams are long lived, so remove the server's write deadline.
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			log.Error().Err(err).Msg("error removing log stream write deadline")
+		}
+
+		lines, unsubscribe := plog.Subscribe()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		_ = rc.Flush()
+
+		for {
+			select {
+			case line := <-lines:
+				if _, err := w.Write(line); err != nil {
+					return
+				}
+				_ = rc.Flush()
+			case <-r.Context().Done():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// logLevel represents the request and response data of the log level handler.
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// logLevelHandler returns the current global log level, or sets it at runtime
+// on PUT. Levels set here take precedence over reloaded config.
+func (s *Service) logLevelHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req logLevel
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := config.ParseLogLevel(req.Level); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// The log level watcher applies the change.
+		s.Config().Set(config.KeyServiceLogLevel, strings.ToLower(req.Level))
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(logLevel{zerolog.GlobalLevel().String()}); err != nil {
+		log.Error().Err(err).Msg("error encoding log level response data")
+	}
+}
+
+// goroutineGroup represents goroutines with the same top function and state.
+type goroutineGroup struct {
+	Function string `json:"function"`
+	State    string `json:"state"`
+	Count    int    `json:"count"`
+}
+
+// goroutineSummary represents the response data of the goroutines handler.
+type goroutineSummary struct {
+	Total  int              `json:"total"`
+	Groups []goroutineGroup `json:"groups"`
+}
+
+// goroutinesHandler returns the number of running goroutines grouped by their
+// top function and state, largest groups first.
+func (s *Service) goroutinesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summarizeGoroutines(goroutineStacks())); err != nil {
+		log.Error().Err(err).Msg("error encoding goroutines response data")
+	}
+}
+
+// goroutineStacks returns the stack traces of all goroutines.
+func goroutineStacks() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// summarizeGoroutines groups goroutine stack traces, as formatted by
+// runtime.Stack, by their top function and state.
+func summarizeGoroutines(stacks []byte) goroutineSummary {
+	counts := make(map[goroutineGroup]int)
+	total := 0
+	for _, stack := range bytes.Split(bytes.TrimSpace(stacks), []byte("\n\n")) {
+		// E.g., goroutine 1 [chan receive, 5 minutes]:
+		//       main.main()
+		header, frames, _ := strings.Cut(string(stack), "\n")
+		_, state, ok := strings.Cut(header, "[")
+		if !ok {
+			continue
+		}
+		state, _, _ = strings.Cut(strings.TrimSuffix(state, "]:"), ",")
+
+		fn, _, _ := strings.Cut(frames, "\n")
+		if i := strings.LastIndex(fn, "("); i > 0 {
+			fn = fn[:i]
+		}
+
+		counts[goroutineGroup{Function: fn, State: state}]++
+		total++
+	}
+
+	groups := make([]goroutineGroup, 0, len(counts))
+	for g, n := range counts {
+		g.Count = n
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Function < groups[j].Function
+	})
+
+	return goroutineSummary{Total: total, Groups: groups}
+}
+
+// drainHandler drains the service, deregistering it from discovery and failing
+// readiness checks.
+func (s *Service) drainHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.Drain(r.Context()); err != nil {
+		log.Error().Err(err).Msg("error draining service")
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// discoveryHandler returns the result of the last discovery registration
+// attempt.
+func (s *Service) discoveryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	res := struct {
+		Enabled      bool          `json:"enabled"`
+		Draining     bool          `json:"draining"`
+		Registration *Registration `json:"registration"`
+	}{
+		Enabled:      s.Config().Bool(config.KeyServiceDiscoveryEnabled) && s.Config().Duration(config.KeyServiceRegisterInt) > 0,
+		Draining:     s.IsDraining(),
+		Registration: s.LastRegistration(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Error().Err(err).Msg("error encoding discovery response data")
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/loshz/platform/internal/authz"
+	"github.com/loshz/platform/internal/config"
+)
+
+func TestAdminOnly(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	cert := &x509.Certificate{DNSNames: []string{"platformctl"}}
+
+	tests := []struct {
+		name   string
+		mode   string
+		remote string
+		tls    *tls.ConnectionState
+		policy *authz.Policy
+		status int
+	}{
+		{"TestLoopback", config.HttpTLSNone, "127.0.0.1:1234", nil, nil, http.StatusOK},
+		{"TestRemote", config.HttpTLSNone, "10.0.0.1:1234", nil, nil, http.StatusForbidden},
+		{"TestMTLSNoCert", config.HttpMTLS, "127.0.0.1:1234", &tls.ConnectionState{}, nil, http.StatusForbidden},
+		{"TestMTLS", config.HttpMTLS, "10.0.0.1:1234", &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}, nil, http.StatusOK},
+		{
+			"TestPolicyAllowed", config.HttpMTLS, "10.0.0.1:1234",
+			&tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+			&authz.Policy{Rules: []authz.Rule{{Methods: []string{"/admin/*"}, Allow: []string{"platformctl"}}}},
+			http.StatusOK,
+		},
+		{
+			"TestPolicyDenied", config.HttpMTLS, "10.0.0.1:1234",
+			&tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+			&authz.Policy{Rules: []authz.Rule{{Methods: []string{"/admin/*"}, Allow: []string{"eventd"}}}},
+			http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := New("admin")
+			s.Config().Set(config.KeyHttpServerTLS, tc.mode)
+			s.authz = tc.policy
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/config", nil)
+			req.RemoteAddr = tc.remote
+			req.TLS = tc.tls
+			w := httptest.NewRecorder()
+			s.adminOnly(ok).ServeHTTP(w, req)
+			assert.Equal(t, tc.status, w.Code)
+		})
+	}
+}
+
+func TestLogLevelHandler(t *testing.T) {
+	level := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(level) })
+
+	s := New("admin")
+	s.Config().Set(config.KeyServiceLogLevel, "info")
+	s.watchLogLevel()
+
+	// Assert valid levels are applied.
+	w := httptest.NewRecorder()
+	s.logLevelHandler(w, httptest.NewRequest(http.MethodPut, "/admin/loglevel", strings.NewReader(`{"level":"DEBUG"}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"debug"}`, w.Body.String())
+	assert.Equal(t, zerolog.DebugLevel, zerolog.GlobalLevel())
+	assert.Equal(t, "debug", s.Config().String(config.KeyServiceLogLevel))
+
+	// Assert invalid levels are rejected.
+	w = httptest.NewRecorder()
+	s.logLevelHandler(w, httptest.NewRequest(http.MethodPut, "/admin/loglevel", strings.NewReader(`{"level":"verbose"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, zerolog.DebugLevel, zerolog.GlobalLevel())
+
+	w = httptest.NewRecorder()
+	s.logLevelHandler(w, httptest.NewRequest(http.MethodPost, "/admin/loglevel", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestSummarizeGoroutines(t *testing.T) {
+	stacks := `goroutine 1 [chan receive, 5 minutes]:
+main.main()
+	/src/main.go:10 +0x1d
+
+goroutine 7 [select]:
+net/http.(*persistConn).readLoop(0xc000123)
+	/go/src/net/http/transport.go:2 +0x1
+
+goroutine 8 [select]:
+net/http.(*persistConn).readLoop(0xc000456)
+	/go/src/net/http/transport.go:2 +0x1
+`
+
+	assert.Equal(t, goroutineSummary{
+		Total: 3,
+		Groups: []goroutineGroup{
+			{Function: "net/http.(*persistConn).readLoop", State: "select", Count: 2},
+			{Function: "main.main", State: "chan receive", Count: 1},
+		},
+	}, summarizeGoroutines([]byte(stacks)))
+
+	// Assert the current goroutines can be summarized.
+	summary := summarizeGoroutines(goroutineStacks())
+	assert.Positive(t, summary.Total)
+	assert.NotEmpty(t, summary.Groups)
+}
+
+func TestDrain(t *testing.T) {
+	s := New("admin")
+	s.Config().Set(config.KeyServiceDiscoveryEnabled, false)
+
+	ready := func() int {
+		w := httptest.NewRecorder()
+		s.readyHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return w.Code
+	}
+
+	// Assert services aren't ready until started.
+	assert.Equal(t, http.StatusServiceUnavailable, ready())
+	s.started.Store(true)
+	assert.Equal(t, http.StatusOK, ready())
+
+	w := httptest.NewRecorder()
+	s.drainHandler(w, httptest.NewRequest(http.MethodPost, "/admin/drain", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// Assert readiness fails once drained.
+	assert.True(t, s.IsDraining())
+	assert.Equal(t, http.StatusServiceUnavailable, ready())
+
+	w = httptest.NewRecorder()
+	s.discoveryHandler(w, httptest.NewRequest(http.MethodGet, "/admin/discovery", nil))
+	var res struct {
+		Enabled      bool          `json:"enabled"`
+		Draining     bool          `json:"draining"`
+		Registration *Registration `json:"registration"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+	assert.False(t, res.Enabled)
+	assert.True(t, res.Draining)
+	assert.Nil(t, res.Registration)
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Component is a long running part of a service, such as a server or a
+// background process, whose lifecycle is managed by the Service.
+type Component interface {
+	// Name uniquely identifies the component within a service.
+	Name() string
+
+	// Start starts the component, returning once it is running. Long running
+	// work should continue in the background until Stop is called.
+	Start(ctx context.Context) error
+
+	// Stop stops the component, returning once it has stopped or the context
+	// is done.
+	Stop(ctx context.Context) error
+}
+
+// registeredComponent stores a component alongside the names of the
+// components it depends on.
+type registeredComponent struct {
+	Component
+	deps []string
+}
+
+// lifecycle starts and stops components in dependency order.
+type lifecycle struct {
+	mtx        sync.Mutex
+	registered []registeredComponent
+	started    []Component
+}
+
+// AddComponent registers a component that is started once the service has run,
+// after the components it depends on, and is stopped on shutdown before them.
+// It panics if a component with the same name is already registered.
+func (s *Service) AddComponent(c Component, dependsOn ...string) {
+	s.components.mtx.Lock()
+	defer s.components.mtx.Unlock()
+
+	for _, r := range s.components.registered {
+		if r.Name() == c.Name() {
+			panic(fmt.Sprintf("component '%s' already registered", c.Name()))
+		}
+	}
+	s.components.registered = append(s.components.registered, registeredComponent{c, dependsOn})
+}
+
+// HasComponent reports whether a component with a given name is registered.
+func (s *Service) HasComponent(name string) bool {
+	s.components.mtx.Lock()
+	defer s.components.mtx.Unlock()
+
+	for _, r := range s.components.registered {
+		if r.Name() == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// start starts registered components in dependency order, skipping those
+// already started. If a component fails to start, all started components are
+// stopped.
+func (l *lifecycle) start(ctx context.Context) error {
+	l.mtx.Lock()
+	ordered, err := sortComponents(l.registered)
+	started := make(map[string]bool, len(l.started))
+	for _, c := range l.started {
+		started[c.Name()] = true
+	}
+	l.mtx.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range ordered {
+		if started[c.Name()] {
+			continue
+		}
+
+		start := time.Now()
+		if err := c.Start(ctx); err != nil {
+			err = fmt.Errorf("error starting component '%s': %w", c.Name(), err)
+			return errors.Join(err, l.stop(ctx))
+		}
+		log.Info().Str("component", c.Name()).Dur("duration", time.Since(start)).Msg("component started")
+
+		l.mtx.Lock()
+		l.started = append(l.started, c)
+		l.mtx.Unlock()
+	}
+
+	return nil
+}
+
+// stop stops all started components in the reverse order they were started.
+// Every component is stopped, even if others fail, and errors name the
+// components that failed or didn't stop before the context was done.
+func (l *lifecycle) stop(ctx context.Context) error {
+	l.mtx.Lock()
+	started := l.started
+	l.started = nil
+	l.mtx.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		c := started[i]
+		start := time.Now()
+		if err := stopComponent(ctx, c); err != nil {
+			log.Error().Err(err).Str("component", c.Name()).Dur("duration", time.Since(start)).Msg("error stopping component")
+			errs = append(errs, err)
+			continue
+		}
+		log.Info().Str("component", c.Name()).Dur("duration", time.Since(start)).Msg("component stopped")
+	}
+
+	return errors.Join(errs...)
+}
+
+// stopComponent stops a component, returning an error naming it if it doesn't
+// stop before the context is done, even if it ignores the context.
+func stopComponent(ctx context.Context, c Component) error {
+	errCh := make(chan error, 1)
+	go func() { errCh <- c.Stop(ctx) }()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("error stopping component '%s': %w", c.Name(), err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("component '%s' didn't stop: %w", c.Name(), ctx.Err())
+	}
+}
+
+// sortComponents orders components so each is after the components it depends
+// on. Otherwise, registration order is kept.
+func sortComponents(registered []registeredComponent) ([]Component, error) {
+	byName := make(map[string]registeredComponent, len(registered))
+	for _, r := range registered {
+		byName[r.Name()] = r
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int, len(registered))
+	ordered := make([]Component, 0, len(registered))
+
+	var visit func(r registeredComponent) error
+	visit = func(r registeredComponent) error {
+		switch state[r.Name()] {
+		case visiting:
+			return fmt.Errorf("error: component '%s' has a dependency cycle", r.Name())
+		case visited:
+			return nil
+		}
+		state[r.Name()] = visiting
+
+		for _, dep := range r.deps {
+			d, ok := byName[dep]
+			if !ok {
+				return fmt.Errorf("error: component '%s' depends on unknown component '%s'", r.Name(), dep)
+			}
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+
+		state[r.Name()] = visited
+		ordered = append(ordered, r.Component)
+		return nil
+	}
+
+	for _, r := range registered {
+		if err := visit(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeComponent records the order components are started and stopped in.
+type fakeComponent struct {
+	name     string
+	events   *[]string
+	mtx      *sync.Mutex
+	startErr error
+	hang     bool
+}
+
+func (f *fakeComponent) Name() string { return f.name }
+
+func (f *fakeComponent) Start(context.Context) error {
+	f.record("start " + f.name)
+	return f.startErr
+}
+
+func (f *fakeComponent) Stop(ctx context.Context) error {
+	if f.hang {
+		select {}
+	}
+	f.record("stop " + f.name)
+	return nil
+}
+
+func (f *fakeComponent) record(event string) {
+	if f.events == nil {
+		return
+	}
+	f.mtx.Lock()
+	*f.events = append(*f.events, event)
+	f.mtx.Unlock()
+}
+
+func TestComponents(t *testing.T) {
+	var (
+		events []string
+		mtx    sync.Mutex
+	)
+	component := func(name string) *fakeComponent {
+		return &fakeComponent{name: name, events: &events, mtx: &mtx}
+	}
+
+	t.Run("TestOrder", func(t *testing.T) {
+		events = nil
+		s := New("components")
+		s.AddComponent(component("register"), "grpc", "http")
+		s.AddComponent(component("grpc"), "http")
+		s.AddComponent(component("http"))
+		s.AddComponent(component("watch"))
+
+		// Assert dependencies are started first and stopped last.
+		require.NoError(t, s.components.start(context.Background()))
+		require.NoError(t, s.components.stop(context.Background()))
+		assert.Equal(t, []string{
+			"start http", "start grpc", "start register", "start watch",
+			"stop watch", "stop register", "stop grpc", "stop http",
+		}, events)
+
+		assert.True(t, s.HasComponent("grpc"))
+		assert.False(t, s.HasComponent("eventd"))
+		assert.Panics(t, func() { s.AddComponent(component("http")) })
+	})
+
+	t.Run("TestStartAgain", func(t *testing.T) {
+		events = nil
+		s := New("components")
+		s.AddComponent(component("http"))
+		require.NoError(t, s.components.start(context.Background()))
+
+		// Assert only components registered since the last start are started.
+		s.AddComponent(component("grpc"), "http")
+		require.NoError(t, s.components.start(context.Background()))
+		assert.Equal(t, []string{"start http", "start grpc"}, events)
+	})
+
+	t.Run("TestStartError", func(t *testing.T) {
+		events = nil
+		s := New("components")
+		s.AddComponent(component("http"))
+		failed := component("grpc")
+		failed.startErr = errors.New("address in use")
+		s.AddComponent(failed, "http")
+
+		// Assert started components are stopped if another fails to start.
+		err := s.components.start(context.Background())
+		assert.ErrorContains(t, err, "error starting component 'grpc': address in use")
+		assert.Equal(t, []string{"start http", "start grpc", "stop http"}, events)
+	})
+
+	t.Run("TestInvalidDependencies", func(t *testing.T) {
+		s := New("components")
+		s.AddComponent(component("grpc"), "http")
+		assert.ErrorContains(t, s.components.start(context.Background()), "unknown component 'http'")
+
+		s = New("components")
+		s.AddComponent(component("a"), "b")
+		s.AddComponent(component("b"), "a")
+		assert.ErrorContains(t, s.components.start(context.Background()), "dependency cycle")
+	})
+
+	t.Run("TestStopTimeout", func(t *testing.T) {
+		events = nil
+		s := New("components")
+		s.AddComponent(component("http"))
+		hung := component("grpc")
+		hung.hang = true
+		s.AddComponent(hung, "http")
+		require.NoError(t, s.components.start(context.Background()))
+
+		// Assert hung components are named, and others are still stopped.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := s.components.stop(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, "component 'grpc' didn't stop")
+		assert.Eventually(t, func() bool {
+			mtx.Lock()
+			defer mtx.Unlock()
+			return assert.ObjectsAreEqual([]string{"start http", "start grpc", "stop http"}, events)
+		}, time.Second, time.Millisecond)
+	})
+}
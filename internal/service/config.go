@@ -1,36 +1,178 @@
 package service
 
-import "github.com/loshz/platform/internal/config"
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/loshz/platform/internal/config"
+)
+
+// PrintSchemaFlag prints the config schema in the given format, json (default)
+// or markdown, and exits.
+const PrintSchemaFlag = "--print-config-schema"
+
+// LoadConfigSources loads the config file and command-line flags given by args,
+// e.g. os.Args[1:], so they take precedence over defaults when config is loaded.
+// As this method is intended to be ran before any config is loaded, errors are
+// treated as fatal.
+//
+// If args contain PrintSchemaFlag, the config schema is printed and the process
+// exits instead.
+func (s *Service) LoadConfigSources(args []string) {
+	if format, ok := schemaFormat(args); ok {
+		if err := config.WriteSchema(os.Stdout, format); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(ExitStartup)
+		}
+		os.Exit(ExitOK)
+	}
+
+	if err := s.Config().LoadSources(args); err != nil {
+		panic(fmt.Errorf("error loading config sources: %w", err))
+	}
+}
+
+// schemaFormat returns the config schema format requested by args, if any.
+func schemaFormat(args []string) (string, bool) {
+	for i, arg := range args {
+		if format, ok := strings.CutPrefix(arg, PrintSchemaFlag+"="); ok {
+			return format, true
+		}
+		if arg != PrintSchemaFlag {
+			continue
+		}
+
+		if i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+			return args[i+1], true
+		}
+		return config.SchemaFormatJSON, true
+	}
+
+	return "", false
+}
+
+// warnUnknownEnv logs PLAT_* env vars that don't match any config key, as they
+// are usually typos.
+func (s *Service) warnUnknownEnv() {
+	for _, env := range s.Config().UnknownEnv() {
+		log.Warn().Str("env", env).Msg("unknown config env var, check for typos")
+	}
+}
 
 // LoadRequiredConfig is a helper function for loading config required by
 // a service.
 func (s *Service) LoadRequiredConfig() {
-	s.Config().MustLoad(config.KeyServiceLogLevel, "info", config.ParseLogLevel)
-	s.Config().MustLoad(config.KeyServiceShutdownTimeout, "10s", config.ParseDuration)
-	s.Config().MustLoad(config.KeyHttpServerPort, 0, config.ParseInt)
+	s.Config().MustLoadKeys(
+		config.KeyServiceLogLevel,
+		config.KeyServiceShutdownTimeout,
+		config.KeyServiceShutdownDrain,
+		config.KeyServiceRestartIntensity,
+		config.KeyServiceRestartPeriod,
+		config.KeyServiceRestartBackoff,
+		config.KeyServiceRestartBackoffMax,
+		config.KeyServiceDependencyTimeout,
+		config.KeyHttpServerPort,
+		config.KeyConfigReloadInterval,
+		config.KeyConfigSecretKeyFile,
+	)
+}
+
+// LoadHttpServerConfig is a helper function for loading HTTP server config.
+func (s *Service) LoadHttpServerConfig() {
+	s.Config().MustLoadKeys(
+		config.KeyHttpReadTimeout,
+		config.KeyHttpWriteTimeout,
+		config.KeyHttpIdleTimeout,
+		config.KeyHttpServerTLS,
+		config.KeyHttpPprofLocal,
+		config.KeyHttpPprofPort,
+	)
 }
 
 // LoadDiscoveryConfig is a helper function for loading service discovery config.
 func (s *Service) LoadDiscoveryConfig() {
-	s.Config().MustLoad(config.KeyServiceDiscoveryEnabled, true, config.ParseBool)
-	s.Config().MustLoad(config.KeyServiceDiscoveryAddr, "discoveryd:8000", config.ParseString)
-	s.Config().MustLoad(config.KeyServiceRegisterInt, "300s", config.ParseDuration)
+	s.Config().MustLoadKeys(
+		config.KeyServiceDiscoveryEnabled,
+		config.KeyServiceDiscoveryAddr,
+		config.KeyServiceRegisterInt,
+		config.KeyConfigRemoteEnabled,
+	)
+
+	// The registered address defaults to the service name.
+	s.Config().MustLoad(config.KeyServiceRegisterAddr, s.Name(), config.ParseString)
+}
+
+// LoadDiscoveryServerConfig is a helper function for loading config required to
+// run the discovery server.
+func (s *Service) LoadDiscoveryServerConfig() {
+	s.Config().MustLoadKeys(config.KeyDiscoveryEvictionWindow, config.KeyDiscoveryEvictionInterval)
+}
+
+// LoadTrafficConfig is a helper function for loading traffic generation config.
+func (s *Service) LoadTrafficConfig() {
+	s.Config().MustLoadKeys(config.KeyTrafficEventInterval)
 }
 
 // LoadGrpcServerConfig is a helper function for loading required gRPC
 // server config.
 func (s *Service) LoadGrpcServerConfig() {
-	s.Config().MustLoad(config.KeyGrpcTLSCA, "/usr/local/share/ca-certificates/ca.crt.pem", config.ParseString)
-	s.Config().MustLoad(config.KeyGrpcServerPort, 0, config.ParseInt)
-	s.Config().MustLoad(config.KeyGrpcServerCert, "/usr/local/share/ca-certificates/server.crt.pem", config.ParseString)
-	s.Config().MustLoad(config.KeyGrpcServerKey, "/usr/local/share/ca-certificates/server.key.pem", config.ParseString)
-	s.Config().MustLoad(config.KeyGrpcServerConnTimeout, "10s", config.ParseDuration)
+	s.Config().MustLoadKeys(
+		config.KeyGrpcTLSCA,
+		config.KeyGrpcTLSReloadInterval,
+		config.KeyGrpcServerPort,
+		config.KeyGrpcServerCert,
+		config.KeyGrpcServerKey,
+		config.KeyGrpcServerConnTimeout,
+		config.KeyGrpcServerReflection,
+		config.KeyGrpcServerStopTimeout,
+		config.KeyGrpcServerLogSample,
+		config.KeyGrpcServerAuthz,
+		config.KeyGrpcServerAuthzPolicy,
+		config.KeyGrpcServerTokenReq,
+	)
 }
 
 // LoadGrpcClientConfig is a helper function for loading required gRPC
-// server config.
+// client config.
 func (s *Service) LoadGrpcClientConfig() {
-	s.Config().MustLoad(config.KeyGrpcTLSCA, "/usr/local/share/ca-certificates/ca.crt.pem", config.ParseString)
-	s.Config().MustLoad(config.KeyGrpcClientCert, "/usr/local/share/ca-certificates/client.crt.pem", config.ParseString)
-	s.Config().MustLoad(config.KeyGrpcClientKey, "/usr/local/share/ca-certificates/client.key.pem", config.ParseString)
+	s.Config().MustLoadKeys(
+		config.KeyGrpcTLSCA,
+		config.KeyGrpcTLSReloadInterval,
+		config.KeyGrpcClientCert,
+		config.KeyGrpcClientKey,
+		config.KeyGrpcClientTimeout,
+		config.KeyGrpcClientMaxAttempts,
+		config.KeyGrpcClientHedgingDelay,
+		config.KeyGrpcClientIdentities,
+	)
+}
+
+// LoadGrpcTokenConfig is a helper function for loading gRPC bearer token config.
+func (s *Service) LoadGrpcTokenConfig() {
+	s.Config().MustLoadKeys(config.KeyGrpcClientTokenTTL)
+}
+
+// LoadCAConfig is a helper function for loading config required to request
+// certs from the platform CA. A bootstrap token is required if enabled.
+func (s *Service) LoadCAConfig() {
+	s.Config().MustLoadKeys(config.KeyCAEnabled)
+	if !s.Config().Bool(config.KeyCAEnabled) {
+		return
+	}
+
+	s.Config().MustLoadKeys(config.KeyCAAddr, config.KeyCAToken)
+}
+
+// LoadTracingConfig is a helper function for loading distributed tracing config.
+func (s *Service) LoadTracingConfig() {
+	s.Config().MustLoadKeys(
+		config.KeyTracingExporter,
+		config.KeyTracingOtlpEndpoint,
+		config.KeyTracingOtlpInsecure,
+		config.KeyTracingFile,
+		config.KeyTracingSampleRatio,
+	)
 }
@@ -2,10 +2,13 @@ package service
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/loshz/platform/internal/config"
+	"github.com/loshz/platform/internal/tracing"
 )
 
 func TestLoadRequiredConfig(t *testing.T) {
@@ -13,6 +16,8 @@ func TestLoadRequiredConfig(t *testing.T) {
 	t.Setenv("PLAT_SERVICE_LOG_LEVEL", "debug")
 	t.Setenv("PLAT_SERVICE_SHUTDOWN_TIMEOUT", "20s")
 	t.Setenv("PLAT_HTTP_SERVER_PORT", "8888")
+	t.Setenv("PLAT_CONFIG_RELOAD_INTERVAL", "1m")
+	t.Setenv("PLAT_CONFIG_SECRET_KEY_FILE", "/run/secrets/platform.key")
 
 	// Create a new service and load required config.
 	s := New("required")
@@ -22,6 +27,34 @@ func TestLoadRequiredConfig(t *testing.T) {
 	assert.Equal(t, s.Config().Get(config.KeyServiceLogLevel), "debug")
 	assert.Equal(t, s.Config().Get(config.KeyServiceShutdownTimeout), "20s")
 	assert.Equal(t, s.Config().Get(config.KeyHttpServerPort), "8888")
+	assert.Equal(t, s.Config().Get(config.KeyConfigReloadInterval), "1m")
+	assert.Equal(t, s.Config().Get(config.KeyConfigSecretKeyFile), "/run/secrets/platform.key")
+}
+
+func TestLoadHttpServerConfig(t *testing.T) {
+	// Set HTTP server env vars.
+	t.Setenv("PLAT_HTTP_READ_TIMEOUT", "5s")
+	t.Setenv("PLAT_HTTP_WRITE_TIMEOUT", "15s")
+	t.Setenv("PLAT_HTTP_IDLE_TIMEOUT", "1m")
+	t.Setenv("PLAT_HTTP_SERVER_TLS", "mtls")
+	t.Setenv("PLAT_HTTP_PPROF_LOCALHOST", "true")
+	t.Setenv("PLAT_HTTP_PPROF_PORT", "6060")
+
+	// Create a new service and load HTTP server config.
+	s := New("http")
+	s.LoadHttpServerConfig()
+
+	// Assert loaded config is as expected.
+	assert.Equal(t, 5*time.Second, s.Config().Duration(config.KeyHttpReadTimeout))
+	assert.Equal(t, 15*time.Second, s.Config().Duration(config.KeyHttpWriteTimeout))
+	assert.Equal(t, time.Minute, s.Config().Duration(config.KeyHttpIdleTimeout))
+	assert.Equal(t, config.HttpMTLS, s.Config().String(config.KeyHttpServerTLS))
+	assert.True(t, s.Config().Bool(config.KeyHttpPprofLocal))
+	assert.Equal(t, 6060, s.Config().Int(config.KeyHttpPprofPort))
+
+	// Assert unknown TLS modes are rejected.
+	t.Setenv("PLAT_HTTP_SERVER_TLS", "on")
+	assert.Panics(t, func() { New("http").LoadHttpServerConfig() })
 }
 
 func TestLoadDiscoveryConfig(t *testing.T) {
@@ -29,6 +62,7 @@ func TestLoadDiscoveryConfig(t *testing.T) {
 	t.Setenv("PLAT_SERVICE_DISCOVERY_ENABLED", "false")
 	t.Setenv("PLAT_SERVICE_DISCOVERY_ADDR", "discoveryd:8888")
 	t.Setenv("PLAT_SERVICE_REGISTER_INTERVAL", "30s")
+	t.Setenv("PLAT_CONFIG_REMOTE_ENABLED", "true")
 
 	// Create a new service and load required config.
 	s := New("discovery")
@@ -38,15 +72,53 @@ func TestLoadDiscoveryConfig(t *testing.T) {
 	assert.Equal(t, s.Config().Get(config.KeyServiceDiscoveryEnabled), "false")
 	assert.Equal(t, s.Config().Get(config.KeyServiceDiscoveryAddr), "discoveryd:8888")
 	assert.Equal(t, s.Config().Get(config.KeyServiceRegisterInt), "30s")
+	assert.Equal(t, s.Config().Get(config.KeyConfigRemoteEnabled), "true")
+
+	// Assert the registered address defaults to the service name.
+	assert.Equal(t, s.Config().Get(config.KeyServiceRegisterAddr), "discovery")
+	t.Setenv("PLAT_SERVICE_REGISTER_ADDR", "localhost")
+	s.LoadDiscoveryConfig()
+	assert.Equal(t, s.Config().Get(config.KeyServiceRegisterAddr), "localhost")
+}
+
+func TestLoadDiscoveryServerConfig(t *testing.T) {
+	// Set discovery server env vars.
+	t.Setenv("PLAT_DISCOVERY_EVICTION_WINDOW", "10m")
+	t.Setenv("PLAT_DISCOVERY_EVICTION_INTERVAL", "30s")
+
+	// Create a new service and load discovery server config.
+	s := New("discovery-server")
+	s.LoadDiscoveryServerConfig()
+
+	// Assert loaded config is as expected.
+	assert.Equal(t, s.Config().Get(config.KeyDiscoveryEvictionWindow), "10m")
+	assert.Equal(t, s.Config().Get(config.KeyDiscoveryEvictionInterval), "30s")
+}
+
+func TestLoadTrafficConfig(t *testing.T) {
+	// Set traffic env vars.
+	t.Setenv("PLAT_TRAFFIC_EVENT_INTERVAL", "1s")
+
+	// Create a new service and load traffic config.
+	s := New("traffic")
+	s.LoadTrafficConfig()
+
+	// Assert loaded config is as expected.
+	assert.Equal(t, s.Config().Get(config.KeyTrafficEventInterval), "1s")
 }
 
 func TestLoadGrpcServerConfig(t *testing.T) {
 	// Set gRPC server env vars.
 	t.Setenv("PLAT_GRPC_TLS_CA", "/path/to/ca")
+	t.Setenv("PLAT_GRPC_TLS_RELOAD_INTERVAL", "30s")
 	t.Setenv("PLAT_GRPC_SERVER_PORT", "8002")
 	t.Setenv("PLAT_GRPC_SERVER_CERT", "/path/to/cert")
 	t.Setenv("PLAT_GRPC_SERVER_KEY", "/path/to/key")
 	t.Setenv("PLAT_GRPC_SERVER_CONN_TIMEOUT", "10s")
+	t.Setenv("PLAT_GRPC_SERVER_REFLECTION", "true")
+	t.Setenv("PLAT_GRPC_SERVER_LOG_SAMPLE", "0.1")
+	t.Setenv("PLAT_GRPC_SERVER_AUTHZ", "true")
+	t.Setenv("PLAT_GRPC_SERVER_AUTHZ_POLICY", "/path/to/policy")
 
 	// Create a new service and load grpc server config.
 	s := New("grpc-server")
@@ -54,17 +126,27 @@ func TestLoadGrpcServerConfig(t *testing.T) {
 
 	// Assert loaded config is as expected.
 	assert.Equal(t, s.Config().Get(config.KeyGrpcTLSCA), "/path/to/ca")
+	assert.Equal(t, s.Config().Get(config.KeyGrpcTLSReloadInterval), "30s")
 	assert.Equal(t, s.Config().Get(config.KeyGrpcServerPort), "8002")
 	assert.Equal(t, s.Config().Get(config.KeyGrpcServerCert), "/path/to/cert")
 	assert.Equal(t, s.Config().Get(config.KeyGrpcServerKey), "/path/to/key")
 	assert.Equal(t, s.Config().Get(config.KeyGrpcServerConnTimeout), "10s")
+	assert.Equal(t, s.Config().Get(config.KeyGrpcServerReflection), "true")
+	assert.Equal(t, s.Config().Get(config.KeyGrpcServerLogSample), "0.1")
+	assert.Equal(t, s.Config().Get(config.KeyGrpcServerAuthz), "true")
+	assert.Equal(t, s.Config().Get(config.KeyGrpcServerAuthzPolicy), "/path/to/policy")
 }
 
 func TestLoadGrpcClientConfig(t *testing.T) {
 	// Set gRPC client env vars.
 	t.Setenv("PLAT_GRPC_TLS_CA", "/path/to/ca")
+	t.Setenv("PLAT_GRPC_TLS_RELOAD_INTERVAL", "30s")
 	t.Setenv("PLAT_GRPC_CLIENT_CERT", "/path/to/cert")
 	t.Setenv("PLAT_GRPC_CLIENT_KEY", "/path/to/key")
+	t.Setenv("PLAT_GRPC_CLIENT_TIMEOUT", "5s")
+	t.Setenv("PLAT_GRPC_CLIENT_MAX_ATTEMPTS", "4")
+	t.Setenv("PLAT_GRPC_CLIENT_HEDGING_DELAY", "50ms")
+	t.Setenv("PLAT_GRPC_CLIENT_SERVER_IDENTITIES", "eventd:8004=spiffe://platform/eventd")
 
 	// Create a new service and load grpc client config.
 	s := New("grpc-client")
@@ -72,6 +154,60 @@ func TestLoadGrpcClientConfig(t *testing.T) {
 
 	// Assert loaded config is as expected.
 	assert.Equal(t, s.Config().Get(config.KeyGrpcTLSCA), "/path/to/ca")
+	assert.Equal(t, s.Config().Get(config.KeyGrpcTLSReloadInterval), "30s")
 	assert.Equal(t, s.Config().Get(config.KeyGrpcClientCert), "/path/to/cert")
 	assert.Equal(t, s.Config().Get(config.KeyGrpcClientKey), "/path/to/key")
+	assert.Equal(t, s.Config().Get(config.KeyGrpcClientTimeout), "5s")
+	assert.Equal(t, s.Config().Get(config.KeyGrpcClientMaxAttempts), "4")
+	assert.Equal(t, s.Config().Get(config.KeyGrpcClientHedgingDelay), "50ms")
+	assert.Equal(t, s.Config().StringMap(config.KeyGrpcClientIdentities), map[string]string{"eventd:8004": "spiffe://platform/eventd"})
+}
+
+func TestLoadTracingConfig(t *testing.T) {
+	// Set tracing env vars.
+	t.Setenv("PLAT_TRACING_EXPORTER", "otlp")
+	t.Setenv("PLAT_TRACING_OTLP_ENDPOINT", "collector:4317")
+	t.Setenv("PLAT_TRACING_OTLP_INSECURE", "false")
+	t.Setenv("PLAT_TRACING_FILE", "/path/to/traces")
+	t.Setenv("PLAT_TRACING_SAMPLE_RATIO", "0.5")
+
+	// Create a new service and load tracing config.
+	s := New("tracing")
+	s.LoadTracingConfig()
+
+	// Assert loaded config is as expected.
+	assert.Equal(t, s.Config().Get(config.KeyTracingExporter), "otlp")
+	assert.Equal(t, s.Config().Get(config.KeyTracingOtlpEndpoint), "collector:4317")
+	assert.Equal(t, s.Config().Get(config.KeyTracingOtlpInsecure), "false")
+	assert.Equal(t, s.Config().Get(config.KeyTracingFile), "/path/to/traces")
+	assert.Equal(t, s.Config().Get(config.KeyTracingSampleRatio), "0.5")
+
+	// Assert all exporters are valid config values.
+	schema, ok := config.Lookup(config.KeyTracingExporter)
+	require.True(t, ok)
+	for _, exporter := range tracing.Exporters {
+		for _, fn := range schema.Parse {
+			assert.NoError(t, fn(exporter), exporter)
+		}
+	}
+}
+
+func TestSchemaFormat(t *testing.T) {
+	tests := []struct {
+		args   []string
+		format string
+		ok     bool
+	}{
+		{[]string{"--config", "config.yaml"}, "", false},
+		{[]string{"--print-config-schema"}, config.SchemaFormatJSON, true},
+		{[]string{"--print-config-schema", "--config", "config.yaml"}, config.SchemaFormatJSON, true},
+		{[]string{"--print-config-schema", "markdown"}, config.SchemaFormatMarkdown, true},
+		{[]string{"--grpc.server.port=8000", "--print-config-schema=markdown"}, config.SchemaFormatMarkdown, true},
+	}
+
+	for _, tc := range tests {
+		format, ok := schemaFormat(tc.args)
+		assert.Equal(t, tc.ok, ok, tc.args)
+		assert.Equal(t, tc.format, format, tc.args)
+	}
 }
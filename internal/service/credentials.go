@@ -1,26 +1,62 @@
 package service
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/loshz/platform/internal/authz"
+	"github.com/loshz/platform/internal/config"
 	"github.com/loshz/platform/internal/credentials"
 )
 
 // LoadCredentials attempts to load service credentials from config values into
 // the credentials store.
 // As this method is intended to be ran before service startup, errors are treated
-// as fatal.
+// as fatal. Loading gRPC server credentials also loads the authorization policy,
+// if enabled.
+//
+// Token credentials sign bearer tokens with the client cert, so must be loaded
+// after gRPC client credentials.
+//
+// If the platform CA is enabled, a single cert is issued to the service instance
+// and used for both client and server credentials instead of certs from file.
 func (s *Service) LoadCredentials(creds ...credentials.Credential) {
+	s.LoadCAConfig()
+
 	for _, cred := range creds {
 		var err error
 
 		switch cred {
 		case credentials.GrpcClient:
 			s.LoadGrpcClientConfig()
-			err = s.Creds().LoadGrpcClientCreds(s.Config())
+			if s.Config().Bool(config.KeyCAEnabled) {
+				err = s.loadIssuedCreds()
+			} else {
+				err = s.Creds().LoadGrpcClientCreds(s.Config())
+			}
 		case credentials.GrpcServer:
 			s.LoadGrpcServerConfig()
-			err = s.Creds().LoadGrpcServerCreds(s.Config())
+			if s.Config().Bool(config.KeyCAEnabled) {
+				err = s.loadIssuedCreds()
+			} else {
+				err = s.Creds().LoadGrpcServerCreds(s.Config())
+			}
+			if err == nil && s.Config().Bool(config.KeyGrpcServerAuthz) {
+				s.authz, err = authz.LoadPolicy(s.Config().String(config.KeyGrpcServerAuthzPolicy))
+			}
+		case credentials.GrpcToken:
+			s.LoadGrpcTokenConfig()
+			err = s.Creds().LoadGrpcTokenCreds(s.Config())
+		case credentials.HTTPServer:
+			// The HTTP server reuses the gRPC server cert.
+			if s.Config().Bool(config.KeyCAEnabled) {
+				err = s.loadIssuedCreds()
+			} else {
+				s.Config().MustLoadKeys(config.KeyGrpcTLSCA, config.KeyGrpcServerCert, config.KeyGrpcServerKey)
+			}
+			if err == nil {
+				err = s.Creds().LoadHTTPServerCreds(s.Config())
+			}
 		}
 
 		if err != nil {
@@ -28,3 +64,14 @@ func (s *Service) LoadCredentials(creds ...credentials.Credential) {
 		}
 	}
 }
+
+// loadIssuedCreds requests a cert for the service instance from the platform CA.
+func (s *Service) loadIssuedCreds() error {
+	// Issue requests use the gRPC client config.
+	s.LoadGrpcClientConfig()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.Config().Duration(config.KeyGrpcClientTimeout))
+	defer cancel()
+
+	return s.Creds().LoadIssuedCreds(ctx, s.Config(), s.ID())
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/config"
+)
+
+const (
+	// Min and max delays between dependency lookups.
+	dependencyMinBackoff = 500 * time.Millisecond
+	dependencyMaxBackoff = 10 * time.Second
+)
+
+// Dependency represents the progress of waiting for instances of a service
+// that must be registered for discovery before the service runs. Instances
+// counts live instances: those that have registered recently, rather than
+// instances whose health has been checked.
+type Dependency struct {
+	Service   string `json:"service"`
+	Min       int    `json:"min"`
+	Instances int    `json:"instances"`
+	Ready     bool   `json:"ready"`
+	Error     string `json:"error,omitempty"`
+}
+
+// dependencies stores the declared dependencies of a service.
+type dependencies struct {
+	mtx  sync.Mutex
+	deps []Dependency
+}
+
+// DependsOn declares that at least min live instances of a service must be
+// registered for discovery before the service runs. It must be called before
+// Run.
+func (s *Service) DependsOn(service string, min int) {
+	s.dependencies.mtx.Lock()
+	defer s.dependencies.mtx.Unlock()
+
+	s.dependencies.deps = append(s.dependencies.deps, Dependency{Service: service, Min: min})
+}
+
+// Dependencies returns the progress of waiting for each declared dependency.
+func (s *Service) Dependencies() []Dependency {
+	s.dependencies.mtx.Lock()
+	defer s.dependencies.mtx.Unlock()
+
+	return append([]Dependency(nil), s.dependencies.deps...)
+}
+
+// waitDependencies blocks until enough instances of every declared dependency
+// are registered for discovery, or the dependency timeout expires.
+func (s *Service) waitDependencies(ctx context.Context) error {
+	deps := s.Dependencies()
+	if len(deps) == 0 {
+		return nil
+	}
+
+	if !s.Config().Bool(config.KeyServiceDiscoveryEnabled) {
+		return errors.New("error: service dependencies require discovery to be enabled")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.Config().Duration(config.KeyServiceDependencyTimeout))
+	defer cancel()
+
+	for i := range deps {
+		if err := s.waitDependency(ctx, i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// waitDependency looks up instances of a dependency with backoff until there
+// are enough live instances, recording progress.
+func (s *Service) waitDependency(ctx context.Context, i int) error {
+	dep := s.Dependencies()[i]
+
+	backoff := dependencyMinBackoff
+	for {
+		svcs, err := s.Discovery().Lookup(ctx, dep.Service)
+		dep = s.updateDependency(i, liveInstances(svcs, s.Config().Duration(config.KeyServiceRegisterInt)), err)
+		if dep.Ready {
+			log.Info().Str("dependency", dep.Service).Int("instances", dep.Instances).Msg("dependency ready")
+			return nil
+		}
+
+		log.Info().Str("dependency", dep.Service).Int("instances", dep.Instances).Int("min", dep.Min).Dur("backoff", backoff).Msg("waiting for dependency")
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("error waiting for dependency '%s', %d/%d instances: %w", dep.Service, dep.Instances, dep.Min, ctx.Err())
+		}
+		backoff = min(2*backoff, dependencyMaxBackoff)
+	}
+}
+
+// liveInstances returns the number of instances that have registered within
+// the last two register intervals, allowing for a missed registration. Stale
+// instances are likely to have stopped without deregistering, but are only
+// evicted by discovery after its eviction window. Every instance is counted if
+// the interval is 0, as instances don't re-register.
+func liveInstances(svcs []*apiv1.Service, interval time.Duration) int {
+	if interval == 0 {
+		return len(svcs)
+	}
+
+	stale := time.Now().Add(-2 * interval)
+	var live int
+	for _, svc := range svcs {
+		if !time.Unix(svc.GetLastSeen(), 0).Before(stale) {
+			live++
+		}
+	}
+
+	return live
+}
+
+// updateDependency records the result of a dependency lookup.
+func (s *Service) updateDependency(i, instances int, err error) Dependency {
+	s.dependencies.mtx.Lock()
+	defer s.dependencies.mtx.Unlock()
+
+	dep := &s.dependencies.deps[i]
+	dep.Instances = instances
+	dep.Ready = err == nil && instances >= dep.Min
+	dep.Error = ""
+	if err != nil {
+		dep.Error = err.Error()
+	}
+
+	return *dep
+}
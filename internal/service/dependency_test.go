@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/config"
+	pgrpc "github.com/loshz/platform/internal/grpc"
+)
+
+// fakeDiscovery registers an instance of every looked up service after a
+// number of lookups, along with any stale instances.
+type fakeDiscovery struct {
+	apiv1.UnimplementedDiscoveryServiceServer
+	lookups atomic.Int32
+	after   int32
+	stale   int
+}
+
+func (f *fakeDiscovery) GetServices(_ context.Context, req *apiv1.GetServicesRequest) (*apiv1.GetServicesResponse, error) {
+	var svcs []*apiv1.Service
+	for i := 0; i < f.stale; i++ {
+		svcs = append(svcs, &apiv1.Service{Uuid: fmt.Sprintf("%s-stale-%d", req.GetName(), i), LastSeen: time.Now().Add(-time.Hour).Unix()})
+	}
+
+	if f.lookups.Add(1) > f.after {
+		svcs = append(svcs, &apiv1.Service{Uuid: req.GetName() + "-1", LastSeen: time.Now().Unix()})
+	}
+
+	return &apiv1.GetServicesResponse{Services: svcs}, nil
+}
+
+// startFakeDiscovery serves a fake discovery service, returning a service
+// connected to it.
+func startFakeDiscovery(t *testing.T, fake *fakeDiscovery) *Service {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	apiv1.RegisterDiscoveryServiceServer(srv, fake)
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(srv.Stop)
+
+	s := New("dependency")
+	s.Config().Set(config.KeyServiceDiscoveryEnabled, true)
+	s.Config().Set(config.KeyServiceDependencyTimeout, 5*time.Second)
+	require.NoError(t, s.Discovery().Start(context.Background(), ln.Addr().String(), insecure.NewCredentials(), pgrpc.ClientPolicy{}))
+
+	return s
+}
+
+func TestWaitDependencies(t *testing.T) {
+	t.Run("TestReady", func(t *testing.T) {
+		s := startFakeDiscovery(t, &fakeDiscovery{after: 2})
+		s.DependsOn("eventd", 1)
+
+		// Assert lookups are retried until enough instances are registered.
+		require.NoError(t, s.waitDependencies(context.Background()))
+		assert.Equal(t, []Dependency{{Service: "eventd", Min: 1, Instances: 1, Ready: true}}, s.Dependencies())
+	})
+
+	t.Run("TestTimeout", func(t *testing.T) {
+		s := startFakeDiscovery(t, &fakeDiscovery{after: 100})
+		s.Config().Set(config.KeyServiceDependencyTimeout, 100*time.Millisecond)
+		s.DependsOn("eventd", 1)
+
+		// Assert timeouts name the dependency and its progress.
+		err := s.waitDependencies(context.Background())
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, "dependency 'eventd', 0/1 instances")
+
+		// Assert progress is visible in readiness checks.
+		w := httptest.NewRecorder()
+		s.readyHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		var res struct {
+			Dependencies []Dependency `json:"dependencies"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+		assert.Equal(t, []Dependency{{Service: "eventd", Min: 1}}, res.Dependencies)
+	})
+
+	t.Run("TestStale", func(t *testing.T) {
+		s := startFakeDiscovery(t, &fakeDiscovery{after: 2, stale: 2})
+		s.Config().Set(config.KeyServiceRegisterInt, time.Minute)
+		s.DependsOn("eventd", 1)
+
+		// Assert instances that haven't registered recently aren't counted.
+		require.NoError(t, s.waitDependencies(context.Background()))
+		assert.Equal(t, []Dependency{{Service: "eventd", Min: 1, Instances: 1, Ready: true}}, s.Dependencies())
+	})
+
+	t.Run("TestDiscoveryDisabled", func(t *testing.T) {
+		s := New("dependency")
+		assert.NoError(t, s.waitDependencies(context.Background()))
+
+		s.Config().Set(config.KeyServiceDiscoveryEnabled, false)
+		s.DependsOn("eventd", 1)
+		assert.ErrorContains(t, s.waitDependencies(context.Background()), "require discovery")
+	})
+}
@@ -16,6 +16,35 @@ const (
 	MaxDiscoveryRetries = 3
 )
 
+// Registration represents the result of the last discovery registration, or
+// deregistration, attempt. Registered reports whether the service is registered
+// after the attempt.
+type Registration struct {
+	Service    *apiv1.Service `json:"service"`
+	Time       time.Time      `json:"time"`
+	Registered bool           `json:"registered"`
+	Error      string         `json:"error,omitempty"`
+	Retries    int            `json:"retries"`
+}
+
+// LastRegistration returns the result of the last discovery registration
+// attempt, or nil if registration hasn't been attempted.
+func (s *Service) LastRegistration() *Registration { return s.registration.Load() }
+
+// recordRegistration stores the result of a discovery registration attempt.
+func (s *Service) recordRegistration(service *apiv1.Service, registered bool, retries int, err error) {
+	r := &Registration{
+		Service:    service,
+		Time:       time.Now(),
+		Registered: registered,
+		Retries:    retries,
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	s.registration.Store(r)
+}
+
 // StartDiscovery attempts to establish a new connection to the discovery server.
 func (s *Service) StartDiscovery(ctx context.Context) error {
 	s.LoadDiscoveryConfig()
@@ -26,62 +55,135 @@ func (s *Service) StartDiscovery(ctx context.Context) error {
 	}
 
 	// Start the discovery service with given credentials.
-	return s.Discovery().Start(ctx, s.Config().String(config.KeyServiceDiscoveryAddr), s.Creds().GrpcClient())
+	addr := s.Config().String(config.KeyServiceDiscoveryAddr)
+	creds, err := s.GrpcClientCreds(addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Discovery().Start(ctx, addr, creds, s.ClientPolicy(), s.grpcClientOptions()...)
 }
 
-// RegisterDiscovery attempts to periodically register a service with the discovery service.
-func (s *Service) RegisterDiscovery(ctx context.Context) {
-	// Return early if registration not enabled.
-	if s.Config().Duration(config.KeyServiceRegisterInt) == 0 {
+// addRegistration registers a component that registers the service for
+// discovery, if enabled, once its servers are started.
+func (s *Service) addRegistration() {
+	if !s.Config().Bool(config.KeyServiceDiscoveryEnabled) || s.Config().Duration(config.KeyServiceRegisterInt) == 0 {
 		return
 	}
 
-	s.Scheduler().Add(1)
-	defer s.Scheduler().Done()
+	deps := []string{"http"}
+	if s.HasComponent("grpc") {
+		deps = append(deps, "grpc")
+	}
+	s.Go("discovery.register", s.RegisterDiscovery, deps...)
+}
 
-	// Create a timer with a small initial tick to allow service processes to start
-	// before registering for discovery.
-	t := time.NewTimer(5 * time.Second)
+// RegisterDiscovery attempts to periodically register a service with the
+// discovery service until the context is done. An error is returned if
+// registration fails too many times in a row, so the registration component is
+// restarted by the supervisor.
+func (s *Service) RegisterDiscovery(ctx context.Context) error {
+	// Return early if registration not enabled.
+	if s.Config().Duration(config.KeyServiceRegisterInt) == 0 {
+		return nil
+	}
+
+	// Register immediately, as the component is only started once the service's
+	// servers are.
+	t := time.NewTimer(0)
 	defer t.Stop()
 
 	// Get service details from config.
 	interval := s.Config().Duration(config.KeyServiceRegisterInt)
 	httpPort := s.Config().Uint(config.KeyHttpServerPort)
 	grpcPort := s.Config().Uint(config.KeyGrpcServerPort)
+	addr := s.Config().String(config.KeyServiceRegisterAddr)
 
 	// Keep track of failed retries.
 	retries := 0
 	for {
 		select {
 		case <-t.C:
-			// Reset the timer to the larger periodic interval.
+			// Drained services are no longer registered.
+			if s.IsDraining() {
+				return nil
+			}
+
+			// Reset the timer to the larger periodic interval, which may have
+			// been changed by a config reload. Registration can't be disabled
+			// while running, so non-positive intervals are ignored.
+			if d := s.Config().Duration(config.KeyServiceRegisterInt); d > 0 {
+				interval = d
+			}
 			t.Reset(interval)
 
 			service := &apiv1.Service{
 				Uuid:     s.ID(),
-				Address:  s.Name(), // TODO: this won't work if we run more than 1 replica.
+				Address:  addr,
 				HttpPort: uint32(httpPort),
 				GrpcPort: uint32(grpcPort),
 				LastSeen: time.Now().Unix(),
 			}
-			if err := s.Discovery().Register(context.TODO(), service); err != nil {
+			if err := s.Discovery().Register(ctx, service); err != nil {
 				retries++
+				s.recordRegistration(service, false, retries, err)
 				if retries == MaxDiscoveryRetries {
-					s.Error(fmt.Errorf("failed to register for discovery: %w", err))
-					return
+					return fmt.Errorf("failed to register for discovery: %w", err)
 				}
 
 				log.Error().Err(err).Msg("error registering service for discovery, retrying")
 				continue
 			}
+			retries = 0
+			s.recordRegistration(service, true, retries, nil)
 		case <-ctx.Done():
-			// Attempt to deregister the service on shutdown.
+			// Attempt to deregister the service on shutdown, unless it has
+			// already been drained.
+			if s.IsDraining() {
+				return nil
+			}
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			if err := s.Discovery().Deregister(ctx, s.ID()); err != nil {
+			if err := s.deregister(ctx); err != nil {
 				log.Error().Err(err).Msg("error deregistering service from discovery")
 			}
 			cancel()
-			return
+			return nil
 		}
 	}
 }
+
+// Drain marks the service as not ready and stops it being registered for
+// discovery, so clients stop routing requests to it. The service is
+// deregistered if registration is enabled. Draining can't be undone.
+func (s *Service) Drain(ctx context.Context) error {
+	if s.draining.Swap(true) {
+		return nil
+	}
+	log.Info().Msg("draining service")
+
+	if !s.Config().Bool(config.KeyServiceDiscoveryEnabled) || s.Config().Duration(config.KeyServiceRegisterInt) == 0 {
+		return nil
+	}
+
+	if err := s.deregister(ctx); err != nil {
+		return fmt.Errorf("error deregistering service from discovery: %w", err)
+	}
+
+	return nil
+}
+
+// deregister removes the service from discovery and records the result.
+func (s *Service) deregister(ctx context.Context) error {
+	var service *apiv1.Service
+	if r := s.LastRegistration(); r != nil {
+		service = r.Service
+	}
+
+	err := s.Discovery().Deregister(ctx, s.ID())
+
+	// The service is still registered, until evicted, if deregistering failed.
+	registered := err != nil
+	s.recordRegistration(service, registered, 0, err)
+
+	return err
+}
@@ -2,28 +2,226 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"time"
 
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/loshz/platform/internal/config"
-	"github.com/loshz/platform/internal/grpc"
+	pgrpc "github.com/loshz/platform/internal/grpc"
 )
 
-func (s *Service) ServeGRPC(ctx context.Context, srv grpc.ServiceServer) {
-	s.Scheduler().Add(1)
-	defer s.Scheduler().Done()
+// GrpcServerOptions returns the default gRPC server options including credentials,
+// interceptors and timeouts.
+func (s *Service) GrpcServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.Creds(s.Creds().GrpcServer()),
+		grpc.ChainUnaryInterceptor(s.grpcUnaryInterceptors()...),
+		grpc.ChainStreamInterceptor(s.grpcStreamInterceptors()...),
+		grpc.ConnectionTimeout(s.Config().Duration(config.KeyGrpcServerConnTimeout)),
+	}
+}
 
-	// Start the gRPC server in the background.
-	go func() {
-		if err := srv.Serve(ctx, s.Config().Int(config.KeyGrpcServerPort)); err != nil {
-			s.Error(fmt.Errorf("grpc server error: %w", err))
+// GrpcGateway creates a JSON/HTTP gateway for a gRPC server that runs the same
+// unary interceptors as the server.
+func (s *Service) GrpcGateway(srv *pgrpc.Server) *pgrpc.Gateway {
+	return pgrpc.NewGateway(srv, pgrpc.ChainUnaryServer(s.grpcUnaryInterceptors()...))
+}
+
+// HandleGateway serves a gRPC gateway on the service router. Gateway calls are
+// authorized like gRPC calls, by the caller's client cert, so the gateway is
+// only served if the HTTP server requires verified client certs. Otherwise, a
+// warning is logged and it isn't served.
+func (s *Service) HandleGateway(pattern string, gw *pgrpc.Gateway) {
+	if s.Config().String(config.KeyHttpServerTLS) != config.HttpMTLS {
+		log.Warn().Str("pattern", pattern).Msg("grpc gateway requires http.server.tls=mtls, not serving")
+		return
+	}
+
+	s.Router().Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			log.Warn().Str("path", r.URL.Path).Str("remote_addr", r.RemoteAddr).Msg("grpc gateway request denied")
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 			return
 		}
+
+		gw.ServeHTTP(w, r)
+	}))
+}
+
+// grpcUnaryInterceptors returns the default gRPC server unary interceptors.
+// Spans are started and request IDs stored first so call logs include them,
+// and panics are recovered before calls are recorded so they are logged as errors.
+// Bearer tokens are verified and calls authorized last so rejected calls are
+// also recorded.
+func (s *Service) grpcUnaryInterceptors() []grpc.UnaryServerInterceptor {
+	interceptors := []grpc.UnaryServerInterceptor{
+		pgrpc.UnaryTracingInterceptor(),
+		pgrpc.UnaryRequestIDInterceptor(),
+		pgrpc.UnaryInterceptor(s.ID(), s.Config().Float64(config.KeyGrpcServerLogSample)),
+		pgrpc.UnaryRecoveryInterceptor(s.ID()),
+	}
+	if s.Creds().GrpcServer() != nil {
+		interceptors = append(interceptors, pgrpc.UnaryTokenInterceptor(s.ID(), s.Creds().VerifyGrpcToken, s.Config().Bool(config.KeyGrpcServerTokenReq)))
+	}
+	if s.authz != nil {
+		interceptors = append(interceptors, pgrpc.UnaryAuthzInterceptor(s.ID(), s.authz))
+	}
+
+	return interceptors
+}
+
+// grpcStreamInterceptors returns the default gRPC server stream interceptors.
+func (s *Service) grpcStreamInterceptors() []grpc.StreamServerInterceptor {
+	interceptors := []grpc.StreamServerInterceptor{
+		pgrpc.StreamTracingInterceptor(),
+		pgrpc.StreamRequestIDInterceptor(),
+		pgrpc.StreamInterceptor(s.ID(), s.Config().Float64(config.KeyGrpcServerLogSample)),
+		pgrpc.StreamRecoveryInterceptor(s.ID()),
+	}
+	if s.Creds().GrpcServer() != nil {
+		interceptors = append(interceptors, pgrpc.StreamTokenInterceptor(s.ID(), s.Creds().VerifyGrpcToken, s.Config().Bool(config.KeyGrpcServerTokenReq)))
+	}
+	if s.authz != nil {
+		interceptors = append(interceptors, pgrpc.StreamAuthzInterceptor(s.ID(), s.authz))
+	}
+
+	return interceptors
+}
+
+// ServeGRPC registers a gRPC server as a component, which is started after the
+// HTTP server and gracefully stopped before it on shutdown.
+func (s *Service) ServeGRPC(srv pgrpc.ServiceServer) {
+	// Optionally expose registered services via server reflection.
+	if s.Config().Bool(config.KeyGrpcServerReflection) {
+		srv.EnableReflection()
+	}
+
+	s.AddComponent(&grpcServer{
+		srv:         srv,
+		addr:        fmt.Sprintf(":%d", s.Config().Int(config.KeyGrpcServerPort)),
+		stopTimeout: s.Config().Duration(config.KeyGrpcServerStopTimeout),
+		onError:     s.Error,
+		setPort:     func(port int) { s.Config().Set(config.KeyGrpcServerPort, port) },
+	}, "http")
+}
+
+// errForcedStop is returned when a gRPC server doesn't gracefully stop in time.
+var errForcedStop = errors.New("grpc server forced to stop")
+
+// grpcServer is a component that serves gRPC until stopped.
+type grpcServer struct {
+	srv         pgrpc.ServiceServer
+	addr        string
+	stopTimeout time.Duration
+	onError     func(error)
+
+	// Updates config with the actual tcp listener port.
+	setPort func(int)
+}
+
+func (g *grpcServer) Name() string { return "grpc" }
+
+func (g *grpcServer) Start(context.Context) error {
+	ln, err := net.Listen("tcp", g.addr)
+	if err != nil {
+		return fmt.Errorf("grpc server tcp error: %w", err)
+	}
+	g.setPort(ln.Addr().(*net.TCPAddr).Port)
+
+	go func() {
+		if err := g.srv.Serve(ln); err != nil {
+			g.onError(fmt.Errorf("grpc server error: %w", err))
+		}
 	}()
 
-	// Stop the gRPC server on exit.
-	<-ctx.Done()
-	log.Info().Msg("stopping grpc server")
-	srv.Shutdown()
+	return nil
+}
+
+// Stop gracefully stops the server, waiting for pending calls to complete. The
+// server is forced to stop if calls don't complete within the stop timeout.
+func (g *grpcServer) Stop(ctx context.Context) error {
+	if g.stopTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.stopTimeout)
+		defer cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		g.srv.Shutdown()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		g.srv.Stop()
+		return errForcedStop
+	}
+}
+
+// ClientPolicy returns the default gRPC client call policy loaded from config.
+func (s *Service) ClientPolicy() pgrpc.ClientPolicy {
+	return pgrpc.ClientPolicy{
+		Timeout:      s.Config().Duration(config.KeyGrpcClientTimeout),
+		MaxAttempts:  s.Config().Int(config.KeyGrpcClientMaxAttempts),
+		HedgingDelay: s.Config().Duration(config.KeyGrpcClientHedgingDelay),
+	}
+}
+
+// DialGrpc creates a gRPC client connection to a target using the service's
+// client credentials and default call policy. Methods describes how calls to
+// individual methods may be safely repeated.
+func (s *Service) DialGrpc(ctx context.Context, target string, methods map[string]pgrpc.CallType) (*grpc.ClientConn, error) {
+	policy := s.ClientPolicy()
+	policy.Methods = methods
+
+	creds, err := s.GrpcClientCreds(target)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgrpc.Dial(ctx, target, creds, policy, s.grpcClientOptions()...)
+}
+
+// GrpcClientCreds returns the client credentials used to dial a target. Servers
+// must present the identity configured for the target, or the dialed host name.
+func (s *Service) GrpcClientCreds(target string) (credentials.TransportCredentials, error) {
+	identity := s.Config().StringMap(config.KeyGrpcClientIdentities)[target]
+
+	creds, err := s.Creds().GrpcClientFor(identity)
+	if err != nil {
+		return nil, fmt.Errorf("error creating grpc client credentials for '%s': %w", target, err)
+	}
+
+	return creds, nil
+}
+
+// grpcClientOptions returns the default dial options used by all service clients,
+// including bearer token credentials if loaded.
+func (s *Service) grpcClientOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(
+			pgrpc.UnaryClientTracingInterceptor(),
+			pgrpc.UnaryClientRequestIDInterceptor(),
+			pgrpc.UnaryClientInterceptor(s.ID()),
+		),
+		grpc.WithChainStreamInterceptor(
+			pgrpc.StreamClientTracingInterceptor(),
+			pgrpc.StreamClientRequestIDInterceptor(),
+			pgrpc.StreamClientInterceptor(s.ID()),
+		),
+	}
+	if creds := s.Creds().GrpcToken(); creds != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(creds))
+	}
+
+	return opts
 }
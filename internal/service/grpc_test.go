@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/config"
+	pgrpc "github.com/loshz/platform/internal/grpc"
+)
+
+// gatewayDiscovery returns no services for any lookup.
+type gatewayDiscovery struct {
+	apiv1.UnimplementedDiscoveryServiceServer
+}
+
+func (gatewayDiscovery) GetServices(context.Context, *apiv1.GetServicesRequest) (*apiv1.GetServicesResponse, error) {
+	return &apiv1.GetServicesResponse{}, nil
+}
+
+func TestHandleGateway(t *testing.T) {
+	newGateway := func(s *Service) *pgrpc.Gateway {
+		srv := pgrpc.NewServer(nil)
+		srv.RegisterService(&apiv1.DiscoveryService_ServiceDesc, gatewayDiscovery{})
+		gw := s.GrpcGateway(srv)
+		gw.Handle(http.MethodGet, "/v1/discovery/services", apiv1.DiscoveryService_GetServices_FullMethodName)
+		return gw
+	}
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{DNSNames: []string{"platformctl"}}}}}
+
+	tests := []struct {
+		name   string
+		mode   string
+		tls    *tls.ConnectionState
+		status int
+	}{
+		{"TestNoTLS", config.HttpTLSNone, nil, http.StatusNotFound},
+		{"TestTLS", config.HttpTLS, &tls.ConnectionState{}, http.StatusNotFound},
+		{"TestMTLSNoCert", config.HttpMTLS, &tls.ConnectionState{}, http.StatusForbidden},
+		{"TestMTLS", config.HttpMTLS, verified, http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := New("gateway")
+			s.Config().Set(config.KeyHttpServerTLS, tc.mode)
+			s.HandleGateway("/v1/", newGateway(s))
+
+			// Assert the gateway is only served to verified callers over mTLS.
+			req := httptest.NewRequest(http.MethodGet, "/v1/discovery/services?name=eventd", nil)
+			req.TLS = tc.tls
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, req)
+			assert.Equal(t, tc.status, w.Code)
+		})
+	}
+}
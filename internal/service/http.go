@@ -7,7 +7,6 @@ import (
 	"net"
 	"net/http"
 	"net/http/pprof"
-	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog/log"
@@ -15,21 +14,26 @@ import (
 	"github.com/loshz/platform/internal/config"
 )
 
-// serveHTTP configures and starts the local webserver.
+// addHTTPServer configures the local webserver and registers it as a component.
 //
-// By default, it will register pprof, metrics and health endpoints.
-func (s *Service) serveHTTP(ctx context.Context) {
-	s.Scheduler().Add(1)
-	defer s.Scheduler().Done()
-
-	router := http.NewServeMux()
+// By default, it will register pprof, metrics, health and admin endpoints alongside
+// any handlers added to the service router. The server is served over TLS if
+// enabled, and pprof endpoints are moved to a separate localhost server if
+// enabled.
+func (s *Service) addHTTPServer(ctx context.Context) {
+	router := s.Router()
 
 	// Configure debug endpoints.
-	router.HandleFunc("/debug/pprof/", pprof.Index)
-	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-	router.HandleFunc("/debug/pprof/profile", pprof.Profile)
-	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-	router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	debug := router
+	if s.Config().Bool(config.KeyHttpPprofLocal) {
+		debug = http.NewServeMux()
+		s.addPprofServer(debug)
+	}
+	debug.HandleFunc("/debug/pprof/", pprof.Index)
+	debug.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	debug.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	debug.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	debug.HandleFunc("/debug/pprof/trace", pprof.Trace)
 
 	// Expose the registered metrics via HTTP.
 	router.Handle("/metrics", promhttp.Handler())
@@ -47,35 +51,135 @@ func (s *Service) serveHTTP(ctx context.Context) {
 		}
 	})
 
-	// Configure HTTP server with sane defaults.
-	timeout := 10 * time.Second
+	// Expose readiness check, which fails until the service has started and
+	// once it is draining.
+	router.HandleFunc("/readyz", s.readyHandler)
+
+	// Log streams are closed when the server begins shutting down, so they
+	// don't block a graceful shutdown.
+	streams, closeStreams := context.WithCancel(ctx)
+
+	// Configure admin endpoints, which are only served to allowed callers.
+	admin := http.NewServeMux()
+	admin.HandleFunc("/admin/config", s.configHandler)
+	admin.HandleFunc("/admin/logs", s.logsHandler(streams))
+	admin.HandleFunc("/admin/loglevel", s.logLevelHandler)
+	admin.HandleFunc("/admin/goroutines", s.goroutinesHandler)
+	admin.HandleFunc("/admin/drain", s.drainHandler)
+	admin.HandleFunc("/admin/discovery", s.discoveryHandler)
+	router.Handle("/admin/", s.adminOnly(admin))
+
+	// Configure HTTP server with configured timeouts, and TLS if enabled.
+	read := s.Config().Duration(config.KeyHttpReadTimeout)
+	mode := s.Config().String(config.KeyHttpServerTLS)
 	srv := &http.Server{
 		Handler:           router,
-		ReadTimeout:       timeout,
-		ReadHeaderTimeout: timeout,
-		WriteTimeout:      timeout,
-		IdleTimeout:       timeout,
+		ReadTimeout:       read,
+		ReadHeaderTimeout: read,
+		WriteTimeout:      s.Config().Duration(config.KeyHttpWriteTimeout),
+		IdleTimeout:       s.Config().Duration(config.KeyHttpIdleTimeout),
+	}
+	if mode != config.HttpTLSNone {
+		srv.TLSConfig = s.Creds().HTTPServer(mode == config.HttpMTLS)
 	}
+	srv.RegisterOnShutdown(closeStreams)
 
-	go func() {
-		ln, err := net.Listen("tcp", fmt.Sprintf(":%d", s.Config().Int(config.KeyHttpServerPort)))
-		if err != nil {
-			s.Error(fmt.Errorf("http server tcp error: %w", err))
-			return
-		}
+	s.AddComponent(&httpServer{
+		name:    "http",
+		srv:     srv,
+		addr:    fmt.Sprintf(":%d", s.Config().Int(config.KeyHttpServerPort)),
+		tls:     mode,
+		onError: s.Error,
+		setPort: func(port int) { s.Config().Set(config.KeyHttpServerPort, port) },
+	})
+}
+
+// addPprofServer registers a plain HTTP server bound to localhost that serves
+// pprof endpoints, so they can only be reached from the same host.
+func (s *Service) addPprofServer(handler http.Handler) {
+	// CPU profiles and traces run for longer than a typical write timeout, so
+	// only reads are limited.
+	read := s.Config().Duration(config.KeyHttpReadTimeout)
+	srv := &http.Server{
+		Handler:           handler,
+		ReadTimeout:       read,
+		ReadHeaderTimeout: read,
+		IdleTimeout:       s.Config().Duration(config.KeyHttpIdleTimeout),
+	}
+
+	s.AddComponent(&httpServer{
+		name:    "pprof",
+		srv:     srv,
+		addr:    fmt.Sprintf("127.0.0.1:%d", s.Config().Int(config.KeyHttpPprofPort)),
+		tls:     config.HttpTLSNone,
+		onError: s.Error,
+		setPort: func(port int) { s.Config().Set(config.KeyHttpPprofPort, port) },
+	})
+}
 
-		// Update config with the actual tcp listener port.
-		s.Config().Set(config.KeyHttpServerPort, ln.Addr().(*net.TCPAddr).Port)
+// httpServer is a component that serves HTTP until stopped.
+type httpServer struct {
+	name    string
+	srv     *http.Server
+	addr    string
+	tls     string
+	onError func(error)
 
-		log.Info().Msgf("http server running on %s", ln.Addr())
-		if err := srv.Serve(ln); err != http.ErrServerClosed {
-			s.Error(fmt.Errorf("local http server error: %w", err))
-			return
+	// Updates config with the actual tcp listener port.
+	setPort func(int)
+}
+
+func (h *httpServer) Name() string { return h.name }
+
+func (h *httpServer) Start(context.Context) error {
+	ln, err := net.Listen("tcp", h.addr)
+	if err != nil {
+		return fmt.Errorf("%s server tcp error: %w", h.name, err)
+	}
+	h.setPort(ln.Addr().(*net.TCPAddr).Port)
+
+	go func() {
+		var err error
+		if h.srv.TLSConfig != nil {
+			log.Info().Str("tls", h.tls).Msgf("https server running on %s", ln.Addr())
+			err = h.srv.ServeTLS(ln, "", "")
+		} else {
+			log.Info().Msgf("%s server running on %s", h.name, ln.Addr())
+			err = h.srv.Serve(ln)
+		}
+		if err != http.ErrServerClosed {
+			h.onError(fmt.Errorf("%s server error: %w", h.name, err))
 		}
 	}()
 
-	// Wait for service to exit and shutdown.
-	<-ctx.Done()
-	log.Info().Msg("stopping http server")
-	_ = srv.Shutdown(context.Background())
+	return nil
+}
+
+// Stop gracefully shuts down the server, waiting for active requests to
+// complete. It is closed if requests don't complete before the context is done.
+func (h *httpServer) Stop(ctx context.Context) error {
+	if err := h.srv.Shutdown(ctx); err != nil {
+		_ = h.srv.Close()
+		return err
+	}
+
+	return nil
+}
+
+// readyHandler reports whether the service is ready to receive requests.
+func (s *Service) readyHandler(w http.ResponseWriter, r *http.Request) {
+	res := struct {
+		Service      string       `json:"service"`
+		Ready        bool         `json:"ready"`
+		Draining     bool         `json:"draining"`
+		Dependencies []Dependency `json:"dependencies,omitempty"`
+	}{s.ID(), s.IsReady(), s.IsDraining(), s.Dependencies()}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !res.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Error().Err(err).Msg("error encoding readiness check response data")
+	}
 }
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/loshz/platform/internal/config"
+	plog "github.com/loshz/platform/internal/log"
+	"github.com/loshz/platform/internal/metrics"
+)
+
+// WatchConfig reloads the service config when a SIGHUP is received, or when the
+// config file or a referenced secret file is modified. Files are checked for
+// changes at the configured interval, or never if the interval is 0.
+func (s *Service) WatchConfig(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// A nil channel blocks forever, disabling file checks.
+	var tick <-chan time.Time
+	if interval := s.Config().Duration(config.KeyConfigReloadInterval); interval > 0 {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	for {
+		select {
+		case <-hup:
+			log.Info().Msg("reload signal received, reloading config")
+			_ = s.ReloadConfig()
+		case <-tick:
+			modified, err := s.Config().FileModified()
+			if err != nil {
+				log.Error().Err(err).Msg("error checking config file for changes")
+				continue
+			}
+			if modified {
+				log.Info().Msg("config or secret file modified, reloading config")
+				_ = s.ReloadConfig()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ReloadConfig reloads the service config from its sources. If any value is
+// invalid, the current config is kept and an error is returned.
+func (s *Service) ReloadConfig() error {
+	changed, err := s.Config().Reload()
+	if err != nil {
+		metrics.ConfigReloadsTotal.WithLabelValues("error").Inc()
+		log.Error().Err(err).Msg("error reloading config, keeping current config")
+		return err
+	}
+	metrics.ConfigReloadsTotal.WithLabelValues("success").Inc()
+
+	// Values without watchers are only applied after a restart.
+	for _, key := range changed {
+		log.Info().Str("key", key).Bool("restart_required", !s.Config().Watched(key)).Msg("config value changed")
+	}
+	log.Info().Int("changed", len(changed)).Msg("config reloaded")
+
+	return nil
+}
+
+// watchLogLevel applies changes to the log level while running.
+func (s *Service) watchLogLevel() {
+	s.Config().Watch(config.KeyServiceLogLevel, func(key string) {
+		if err := plog.SetLevel(s.Config().String(key)); err != nil {
+			log.Error().Err(err).Msg("error setting log level")
+			return
+		}
+		log.Info().Str("log_level", s.Config().String(key)).Msg("log level changed")
+	})
+}
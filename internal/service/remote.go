@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/config"
+)
+
+// Prefixes of KV keys holding remote config values, e.g.
+// service/eventd/service.log.level. Values under RemotePlatformPrefix apply to
+// all services, and are overridden by values under RemoteServicePrefix for a
+// single named service.
+const (
+	RemotePlatformPrefix = "platform/"
+	RemoteServicePrefix  = "service/"
+)
+
+// Backoff between attempts to watch remote config values.
+const (
+	remoteConfigMinBackoff = 1 * time.Second
+	remoteConfigMaxBackoff = 30 * time.Second
+)
+
+// remoteConfig merges config values watched from multiple KV prefixes.
+type remoteConfig struct {
+	mtx sync.Mutex
+
+	// Watched prefixes in order of increasing precedence.
+	prefixes []string
+
+	// Config values keyed by prefix, then config key.
+	values map[string]map[string]string
+}
+
+func newRemoteConfig(prefixes ...string) *remoteConfig {
+	rc := &remoteConfig{
+		prefixes: prefixes,
+		values:   make(map[string]map[string]string, len(prefixes)),
+	}
+	for _, prefix := range prefixes {
+		rc.values[prefix] = make(map[string]string)
+	}
+
+	return rc
+}
+
+// update applies watched key events for a prefix, returning all merged values.
+func (rc *remoteConfig) update(prefix string, snapshot bool, events []*apiv1.KeyEvent) map[string]string {
+	if snapshot {
+		rc.values[prefix] = make(map[string]string, len(events))
+	}
+
+	for _, event := range events {
+		key := strings.TrimPrefix(event.GetKv().GetKey(), prefix)
+		if event.GetDeleted() {
+			delete(rc.values[prefix], key)
+			continue
+		}
+		rc.values[prefix][key] = event.GetKv().GetValue()
+	}
+
+	merged := make(map[string]string)
+	for _, p := range rc.prefixes {
+		for key, value := range rc.values[p] {
+			merged[key] = value
+		}
+	}
+
+	return merged
+}
+
+// applyRemoteConfig updates the remote config values of a prefix and reloads config.
+func (s *Service) applyRemoteConfig(rc *remoteConfig, prefix string, snapshot bool, events []*apiv1.KeyEvent) {
+	// Values are set while locked, so the latest are always reloaded last.
+	rc.mtx.Lock()
+	err := s.Config().SetRemote(rc.update(prefix, snapshot, events))
+	rc.mtx.Unlock()
+	if err != nil {
+		log.Warn().Err(err).Str("prefix", prefix).Msg("ignoring remote config values")
+	}
+
+	_ = s.ReloadConfig()
+}
+
+// StartRemoteConfig loads config values from the discovery KV store, if
+// enabled, and registers components that watch them for changes. Remote values take
+// precedence over env vars but not flags, and are applied by reloading config.
+// Values of keys that can't be set remotely are logged and ignored.
+//
+// Values are loaded before the service runs, but after credentials and
+// discovery config are loaded, so they can't configure how the service
+// connects to the discovery service.
+func (s *Service) StartRemoteConfig(ctx context.Context) {
+	if !s.Config().Bool(config.KeyServiceDiscoveryEnabled) || !s.Config().Bool(config.KeyConfigRemoteEnabled) {
+		return
+	}
+
+	rc := newRemoteConfig(RemotePlatformPrefix, RemoteServicePrefix+s.Name()+"/")
+
+	// Errors aren't fatal, as values are applied once watching succeeds.
+	for _, prefix := range rc.prefixes {
+		res, err := s.Discovery().KV().List(ctx, &apiv1.ListKeysRequest{Prefix: prefix})
+		if err != nil {
+			log.Warn().Err(err).Str("prefix", prefix).Msg("error loading remote config")
+			continue
+		}
+
+		events := make([]*apiv1.KeyEvent, 0, len(res.GetKvs()))
+		for _, kv := range res.GetKvs() {
+			events = append(events, &apiv1.KeyEvent{Kv: kv})
+		}
+		s.applyRemoteConfig(rc, prefix, true, events)
+	}
+
+	for _, prefix := range rc.prefixes {
+		s.Go("config.remote:"+prefix, func(ctx context.Context) error {
+			s.watchRemoteConfig(ctx, rc, prefix)
+			return nil
+		})
+	}
+}
+
+// watchRemoteConfig watches remote config values with a given prefix until the
+// context is cancelled, retrying with backoff on failure.
+func (s *Service) watchRemoteConfig(ctx context.Context, rc *remoteConfig, prefix string) {
+	backoff := remoteConfigMinBackoff
+	for {
+		err := s.watchRemoteConfigStream(ctx, rc, prefix, func() { backoff = remoteConfigMinBackoff })
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warn().Err(err).Str("prefix", prefix).Dur("backoff", backoff).Msg("error watching remote config, retrying")
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(2*backoff, remoteConfigMaxBackoff)
+	}
+}
+
+// watchRemoteConfigStream applies remote config values received from a single
+// watch stream, calling reset once the initial snapshot is received.
+func (s *Service) watchRemoteConfigStream(ctx context.Context, rc *remoteConfig, prefix string, reset func()) error {
+	stream, err := s.Discovery().KV().Watch(ctx, &apiv1.WatchKeysRequest{Prefix: prefix})
+	if err != nil {
+		return err
+	}
+
+	for {
+		res, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if res.GetSnapshot() {
+			reset()
+		}
+
+		s.applyRemoteConfig(rc, prefix, res.GetSnapshot(), res.GetEvents())
+	}
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+)
+
+func TestRemoteConfigUpdate(t *testing.T) {
+	rc := newRemoteConfig(RemotePlatformPrefix, RemoteServicePrefix+"eventd/")
+	put := func(key, value string) *apiv1.KeyEvent {
+		return &apiv1.KeyEvent{Kv: &apiv1.KeyValue{Key: key, Value: value}}
+	}
+
+	rc.update("platform/", true, []*apiv1.KeyEvent{
+		put("platform/service.log.level", "warn"),
+		put("platform/grpc.server.log.sample", "0.5"),
+	})
+	merged := rc.update("service/eventd/", true, []*apiv1.KeyEvent{
+		put("service/eventd/service.log.level", "debug"),
+	})
+
+	// Assert service values take precedence over platform values.
+	assert.Equal(t, map[string]string{
+		"service.log.level":      "debug",
+		"grpc.server.log.sample": "0.5",
+	}, merged)
+
+	// Assert deleted service values fall back to platform values.
+	merged = rc.update("service/eventd/", false, []*apiv1.KeyEvent{
+		{Kv: &apiv1.KeyValue{Key: "service/eventd/service.log.level"}, Deleted: true},
+	})
+	assert.Equal(t, "warn", merged["service.log.level"])
+
+	// Assert snapshots replace all values of a prefix.
+	merged = rc.update("platform/", true, nil)
+	assert.Empty(t, merged)
+}
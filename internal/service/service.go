@@ -4,20 +4,22 @@ package service
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
-	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/loshz/platform/internal/authz"
 	"github.com/loshz/platform/internal/config"
 	"github.com/loshz/platform/internal/credentials"
 	"github.com/loshz/platform/internal/discovery"
 	plog "github.com/loshz/platform/internal/log"
 	"github.com/loshz/platform/internal/metrics"
+	"github.com/loshz/platform/internal/tracing"
 	"github.com/loshz/platform/internal/uuid"
 	"github.com/loshz/platform/internal/version"
 )
@@ -28,6 +30,14 @@ const (
 	ExitStartup
 )
 
+// Maximum time to flush remaining spans once the service is stopped.
+const tracingStopTimeout = 5 * time.Second
+
+// Time allowed after the shutdown timeout before the process is forced to
+// exit, so shutdown phases can return their deadline errors and spans are
+// flushed.
+const forceExitGrace = tracingStopTimeout + time.Second
+
 // RunFunc is a function that will be called by Run to initialize a service.
 // If this function returns an error then the server will immediately shut down.
 type RunFunc func(context.Context, *Service) error
@@ -44,28 +54,53 @@ type Service struct {
 	// Channel for sending/receiving internal service errors.
 	errCh chan error
 
-	// WaitGroup used to control shutdown of key goroutines.
-	wg *sync.WaitGroup
+	// Components started after the service has run and stopped on shutdown.
+	components lifecycle
+
+	// Limits the restart intensity of supervised components.
+	supervisor supervisor
+
+	// Services that must be registered for discovery before the service runs.
+	dependencies dependencies
 
 	// Store the current leadership status.
 	leader atomic.Bool
 
+	// Readiness status: services are ready once started, until drained.
+	started  atomic.Bool
+	draining atomic.Bool
+
+	// Result of the last discovery registration attempt.
+	registration atomic.Pointer[Registration]
+
 	// Service for storing credentials.
 	creds *credentials.Store
 
 	// Service used to register/deregister services for discovery.
 	ds *discovery.Service
+
+	// Router used by the local http server.
+	router *http.ServeMux
+
+	// Optional policy used to authorize gRPC calls.
+	authz *authz.Policy
+
+	// Flushes and stops the trace exporter on exit.
+	stopTracing tracing.ShutdownFunc
+
+	// Cancels the service's context once components have stopped.
+	cancel context.CancelFunc
 }
 
 // New creates a named Service with configurable dependencies.
 func New(name string) *Service {
 	return &Service{
-		conf:  config.New(),
-		id:    uuid.New(name),
-		errCh: make(chan error, 1),
-		wg:    new(sync.WaitGroup),
-		creds: new(credentials.Store),
-		ds:    new(discovery.Service),
+		conf:   config.New(),
+		id:     uuid.New(name),
+		errCh:  make(chan error, 1),
+		creds:  new(credentials.Store),
+		ds:     new(discovery.Service),
+		router: http.NewServeMux(),
 	}
 }
 
@@ -75,44 +110,112 @@ func (s *Service) Creds() *credentials.Store     { return s.creds }
 func (s *Service) Discovery() *discovery.Service { return s.ds }
 func (s *Service) ID() string                    { return s.id.String() }
 func (s *Service) IsLeader() bool                { return s.leader.Load() }
+func (s *Service) IsDraining() bool              { return s.draining.Load() }
+func (s *Service) IsReady() bool                 { return s.started.Load() && !s.draining.Load() }
 func (s *Service) Name() string                  { return s.id.Name() }
-func (s *Service) Scheduler() *sync.WaitGroup    { return s.wg }
+func (s *Service) Router() *http.ServeMux        { return s.router }
 
 // Run starts the Service and ensures all dependencies are initialised.
 //
 // By default, it will start the local web server and wait for a stop
-// signal to be received before attempting to gracefully shutdown.
+// signal to be received before attempting to gracefully shutdown. The service's
+// context is only cancelled once its components have stopped, see Exit.
 func (s *Service) Run(run RunFunc) {
-	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	sig, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	// Attempt to start the service.
+	if err := s.Start(run); err != nil {
+		log.Error().Err(err).Msg("service startup error")
+		s.Exit(ExitStartup)
+	}
+
+	// Wait for an exit signal or service error. Further signals are no longer
+	// caught, so they force the process to exit.
+	status := s.waitSignal(sig)
+	stop()
+
+	// Attempt to gracefully shutdown.
+	s.Exit(status)
+}
+
+// Start initialises and runs the service, returning once its components have
+// started. Unlike Run, it doesn't wait for signals or exit the process, so
+// services can be run in-process, e.g. by tests. Stop must be called to shut
+// the service down, and internal errors are received from Errors.
+//
+// If startup fails, any started components are stopped.
+func (s *Service) Start(run RunFunc) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
 
 	// Initialize required service config.
 	s.LoadRequiredConfig()
+	s.LoadHttpServerConfig()
+	s.LoadTracingConfig()
+
+	// HTTP config is loaded here rather than by each service, so HTTP server
+	// credentials are too if TLS is enabled.
+	if s.Config().String(config.KeyHttpServerTLS) != config.HttpTLSNone {
+		s.LoadCredentials(credentials.HTTPServer)
+	}
 
 	// Configure global logger.
 	plog.ConfigureGlobalLogging(s.Config().String(config.KeyServiceLogLevel), s.ID(), version.Build)
+	s.watchLogLevel()
+	s.warnUnknownEnv()
 
-	// Attempt to start the service.
 	if err := s.start(ctx, run); err != nil {
-		log.Error().Err(err).Msg("service startup error")
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), s.Config().Duration(config.KeyServiceShutdownTimeout))
+		defer stopCancel()
+		if err := s.components.stop(stopCtx); err != nil {
+			log.Error().Err(err).Msg("error stopping components after startup error")
+		}
 		cancel()
-		s.Exit(ExitStartup)
+		return err
 	}
 
-	// Wait for an exit signal or service error.
-	status := s.waitSignal(ctx)
-	cancel()
-
-	// Attempt to gracefully shutdown.
-	s.Exit(status)
+	return nil
 }
 
+// Errors returns the channel internal service errors are sent to, see Error.
+func (s *Service) Errors() <-chan error { return s.errCh }
+
 // Error sends a given error to the Service's error channel.
 // Services should prefer calling this method instead of manually calling s.Exit()
-// so shutdown can be handled gracefully.
-func (s *Service) Error(err error) { s.errCh <- err }
+// so shutdown can be handled gracefully. Only the first error triggers a
+// shutdown, later errors are logged without blocking.
+func (s *Service) Error(err error) {
+	select {
+	case s.errCh <- err:
+	default:
+		log.Error().Err(err).Msg("internal service error")
+	}
+}
+
+// Stop stops the service's components in phases, then cancels the service's
+// context in order to signal a shutdown to child processes, and flushes any
+// remaining spans. Shutdown is bounded by the configured timeout.
+func (s *Service) Stop() error {
+	err := s.shutdown(time.Now().Add(s.Config().Duration(config.KeyServiceShutdownTimeout)))
+	if s.cancel != nil {
+		s.cancel()
+	}
 
-// Exit cancels the service's context in order to signal a shutdown to child processes.
-// It sleeps for a configurable time before signalling the process to exit.
+	// Flush any remaining spans.
+	if s.stopTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingStopTimeout)
+		if err := s.stopTracing(ctx); err != nil {
+			log.Error().Err(err).Msg("error stopping tracing")
+		}
+		cancel()
+	}
+
+	return err
+}
+
+// Exit gracefully stops the service, see Stop, and exits the process. The
+// process is forced to exit with ExitError if shutdown doesn't complete within
+// the configured timeout and a grace period.
 func (s *Service) Exit(status int) {
 	// Exit early if startup error.
 	if status == ExitStartup {
@@ -120,13 +223,14 @@ func (s *Service) Exit(status int) {
 	}
 
 	// Force exit after deadline.
-	time.AfterFunc(s.Config().Duration(config.KeyServiceShutdownTimeout), func() {
+	time.AfterFunc(s.Config().Duration(config.KeyServiceShutdownTimeout)+forceExitGrace, func() {
 		log.Error().Msg("service shutdown timeout expired")
-		os.Exit(status)
+		os.Exit(ExitError)
 	})
 
-	// Wait for individual service goroutine shutdown.
-	s.Scheduler().Wait()
+	if err := s.Stop(); err != nil && status == ExitOK {
+		status = ExitError
+	}
 
 	os.Exit(status)
 }
@@ -148,27 +252,72 @@ func (s *Service) waitSignal(ctx context.Context) int {
 	return ExitOK
 }
 
-// start attempts to run the service with an initial timeout.
-// If the deadline exceeds the time taken to run the service, it is treated
-// as a failed start.
+// start starts tracing, discovery and remote config, then the HTTP server
+// component, so health and readiness are served while the service waits for
+// its dependencies to be registered. Once they are, the run func is called to
+// add the service's servers and processes, and the remaining components are
+// started in dependency order. Any error is treated as a failed start.
 func (s *Service) start(ctx context.Context, run RunFunc) error {
+	// Configure tracing before any calls are made.
+	stop, err := tracing.Start(ctx, s.Name(), s.ID(), version.Build, tracing.Config{
+		Exporter:    s.Config().String(config.KeyTracingExporter),
+		Endpoint:    s.Config().String(config.KeyTracingOtlpEndpoint),
+		Insecure:    s.Config().Bool(config.KeyTracingOtlpInsecure),
+		File:        s.Config().String(config.KeyTracingFile),
+		SampleRatio: s.Config().Float64(config.KeyTracingSampleRatio),
+	})
+	if err != nil {
+		return fmt.Errorf("error starting tracing: %w", err)
+	}
+	s.stopTracing = stop
+
 	// Start the discovery service.
 	if err := s.StartDiscovery(ctx); err != nil {
 		return err
 	}
 
+	// Apply remote config before running the service.
+	s.StartRemoteConfig(ctx)
+
+	// Start the local http server, so health, readiness and admin endpoints are
+	// served while waiting for dependencies.
+	s.addHTTPServer(ctx)
+	if err := s.components.start(ctx); err != nil {
+		return err
+	}
+
+	// Wait for dependencies to be registered for discovery.
+	if err := s.waitDependencies(ctx); err != nil {
+		return err
+	}
+
 	// Attempt to run the main service func and record error.
 	if err := run(ctx, s); err != nil {
 		return fmt.Errorf("error running service: %w", err)
 	}
 
-	// Start the local http server.
-	go s.serveHTTP(ctx)
+	// Reload rotated TLS credentials.
+	s.Go("credentials.reload", func(ctx context.Context) error {
+		s.Creds().Watch(ctx, s.Config().Duration(config.KeyGrpcTLSReloadInterval))
+		return nil
+	})
+
+	// Reload config on SIGHUP or config file changes.
+	s.Go("config.reload", func(ctx context.Context) error {
+		s.WatchConfig(ctx)
+		return nil
+	})
 
-	// Register service for discovery if enabled.
-	go s.RegisterDiscovery(ctx)
+	// Register service for discovery if enabled, once its servers are started.
+	s.addRegistration()
+
+	// Start the remaining components in dependency order.
+	if err := s.components.start(ctx); err != nil {
+		return err
+	}
 
 	metrics.ServiceInfo.WithLabelValues(s.ID(), version.Build).Inc()
+	s.started.Store(true)
 	log.Info().Msg("service started")
 
 	return nil
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/loshz/platform/internal/config"
+)
+
+// shutdownPhase is a single step of the shutdown sequence.
+type shutdownPhase struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// shutdown stops the service in phases, so clients stop routing requests to it
+// before its servers stop accepting them:
+//
+//  1. drain: deregister from discovery and start failing readiness checks.
+//  2. wait: wait for the drain period, so clients observe the change.
+//  3. stop: stop components in reverse dependency order, so gRPC servers are
+//     gracefully stopped before the HTTP server.
+//
+// All phases are run, even if one fails, and are bounded by the deadline. Errors
+// are aggregated, naming any component that didn't stop.
+func (s *Service) shutdown(deadline time.Time) error {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	start := time.Now()
+	var errs []error
+	for _, p := range s.shutdownPhases() {
+		phaseStart := time.Now()
+		log.Info().Str("phase", p.name).Msg("starting shutdown phase")
+
+		if err := p.fn(ctx); err != nil {
+			log.Error().Err(err).Str("phase", p.name).Dur("duration", time.Since(phaseStart)).Msg("shutdown phase failed")
+			errs = append(errs, fmt.Errorf("shutdown phase '%s' failed: %w", p.name, err))
+			continue
+		}
+		log.Info().Str("phase", p.name).Dur("duration", time.Since(phaseStart)).Msg("shutdown phase complete")
+	}
+
+	log.Info().Dur("duration", time.Since(start)).Msg("shutdown complete")
+
+	return errors.Join(errs...)
+}
+
+// shutdownPhases returns the phases of the shutdown sequence in order.
+func (s *Service) shutdownPhases() []shutdownPhase {
+	return []shutdownPhase{
+		{"drain", s.Drain},
+		{"wait", s.waitDrain},
+		{"stop", s.components.stop},
+	}
+}
+
+// waitDrain waits for the configured drain period, or until the shutdown
+// deadline.
+func (s *Service) waitDrain(ctx context.Context) error {
+	t := time.NewTimer(s.Config().Duration(config.KeyServiceShutdownDrain))
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("drain period interrupted: %w", ctx.Err())
+	}
+}
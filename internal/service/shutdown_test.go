@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/loshz/platform/internal/config"
+)
+
+// blockingServer is a gRPC server that only stops when forced to.
+type blockingServer struct {
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func (b *blockingServer) RegisterService(*grpc.ServiceDesc, interface{}) {}
+func (b *blockingServer) EnableReflection()                              {}
+func (b *blockingServer) Serve(ln net.Listener) error                    { return ln.Close() }
+func (b *blockingServer) OnShutdown(func())                              {}
+func (b *blockingServer) Shutdown()                                      { <-b.stopped }
+func (b *blockingServer) Stop()                                          { b.once.Do(func() { close(b.stopped) }) }
+
+func TestShutdown(t *testing.T) {
+	s := New("shutdown")
+	s.Config().Set(config.KeyServiceDiscoveryEnabled, false)
+	s.Config().Set(config.KeyServiceShutdownDrain, 10*time.Millisecond)
+	s.Config().Set(config.KeyGrpcServerStopTimeout, 50*time.Millisecond)
+
+	srv := &blockingServer{stopped: make(chan struct{})}
+	s.AddComponent(&fakeComponent{name: "http"})
+	s.ServeGRPC(srv)
+	require.NoError(t, s.components.start(context.Background()))
+	s.started.Store(true)
+
+	// Assert servers that don't gracefully stop are forced to stop after the
+	// stop timeout, rather than the shutdown deadline.
+	start := time.Now()
+	err := s.shutdown(time.Now().Add(5 * time.Second))
+	assert.ErrorIs(t, err, errForcedStop)
+	assert.ErrorContains(t, err, "component 'grpc'")
+	assert.Less(t, time.Since(start), time.Second)
+	assert.GreaterOrEqual(t, time.Since(start), 60*time.Millisecond)
+	assert.False(t, s.IsReady())
+
+	select {
+	case <-srv.stopped:
+	default:
+		t.Fatal("expected grpc server to be stopped")
+	}
+
+	t.Run("TestDeadline", func(t *testing.T) {
+		s.Config().Set(config.KeyServiceShutdownDrain, time.Minute)
+
+		// Assert the drain period is bounded by the shutdown deadline.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, s.waitDrain(ctx), context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/loshz/platform/internal/config"
+	"github.com/loshz/platform/internal/metrics"
+)
+
+// RestartPolicy describes when a supervised component is restarted after its
+// function returns.
+type RestartPolicy int
+
+const (
+	// Permanent components are always restarted.
+	Permanent RestartPolicy = iota
+
+	// Transient components are only restarted if they fail, i.e. return an
+	// error or panic.
+	Transient
+
+	// Temporary components are never restarted.
+	Temporary
+)
+
+func (p RestartPolicy) String() string {
+	switch p {
+	case Permanent:
+		return "permanent"
+	case Transient:
+		return "transient"
+	case Temporary:
+		return "temporary"
+	}
+
+	return fmt.Sprintf("RestartPolicy(%d)", int(p))
+}
+
+// errExited is returned when a permanent component returns without error.
+var errExited = errors.New("component exited")
+
+// supervisor limits the restart intensity of a service's components. If more
+// than the configured number of restarts happen within the restart period, the
+// supervisor gives up and the service exits.
+type supervisor struct {
+	mtx      sync.Mutex
+	restarts []time.Time
+}
+
+// allow records a restart, reporting whether it is within the intensity limit.
+func (sv *supervisor) allow(now time.Time, intensity int, period time.Duration) bool {
+	sv.mtx.Lock()
+	defer sv.mtx.Unlock()
+
+	// Forget restarts outside of the period.
+	recent := sv.restarts[:0]
+	for _, t := range sv.restarts {
+		if now.Sub(t) < period {
+			recent = append(recent, t)
+		}
+	}
+	sv.restarts = recent
+
+	if len(sv.restarts) >= intensity {
+		return false
+	}
+	sv.restarts = append(sv.restarts, now)
+
+	return true
+}
+
+// Go registers a transient component that runs a function in the background
+// until the service shuts down, see Supervise.
+func (s *Service) Go(name string, fn func(context.Context) error, dependsOn ...string) {
+	s.Supervise(name, Transient, fn, dependsOn...)
+}
+
+// Supervise registers a component that runs a function in the background until
+// the service shuts down. The function should return once its context is done.
+// If it returns before then, it is restarted with exponential backoff according
+// to the restart policy. Panics are recovered and treated as errors.
+//
+// Restarts are limited by the service's restart intensity. Once exceeded, the
+// error is sent to the service's error channel so the service exits.
+func (s *Service) Supervise(name string, policy RestartPolicy, fn func(context.Context) error, dependsOn ...string) {
+	s.AddComponent(&supervisedComponent{s: s, name: name, policy: policy, fn: fn}, dependsOn...)
+}
+
+// supervisedComponent runs a function in the background until stopped,
+// restarting it according to its policy.
+type supervisedComponent struct {
+	s      *Service
+	name   string
+	policy RestartPolicy
+	fn     func(context.Context) error
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (c *supervisedComponent) Name() string { return c.name }
+
+func (c *supervisedComponent) Start(ctx context.Context) error {
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+
+	go func() {
+		defer close(c.done)
+		if err := c.supervise(ctx); err != nil {
+			c.s.Error(err)
+		}
+	}()
+
+	return nil
+}
+
+func (c *supervisedComponent) Stop(ctx context.Context) error {
+	c.cancel()
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// supervise runs the component's function until the context is done, or it
+// shouldn't be restarted. An error is returned if the restart intensity is
+// exceeded.
+func (c *supervisedComponent) supervise(ctx context.Context) error {
+	conf := c.s.Config()
+	backoff := conf.Duration(config.KeyServiceRestartBackoff)
+
+	for {
+		start := time.Now()
+		err := c.run(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err != nil {
+			metrics.ComponentFailuresTotal.WithLabelValues(c.s.ID(), c.name, c.policy.String()).Inc()
+		}
+
+		switch {
+		case c.policy == Temporary, c.policy == Transient && err == nil:
+			if err != nil {
+				log.Error().Err(err).Str("component", c.name).Str("policy", c.policy.String()).Msg("component failed, not restarting")
+				return nil
+			}
+			log.Info().Str("component", c.name).Msg("component exited")
+			return nil
+		case err == nil:
+			err = errExited
+		}
+
+		// Components that ran for longer than the max backoff are considered
+		// to have recovered, so are restarted quickly.
+		maxBackoff := conf.Duration(config.KeyServiceRestartBackoffMax)
+		if time.Since(start) > maxBackoff {
+			backoff = conf.Duration(config.KeyServiceRestartBackoff)
+		}
+
+		if !c.s.supervisor.allow(time.Now(), conf.Int(config.KeyServiceRestartIntensity), conf.Duration(config.KeyServiceRestartPeriod)) {
+			return fmt.Errorf("component '%s' failed, restart intensity exceeded: %w", c.name, err)
+		}
+
+		log.Error().Err(err).Str("component", c.name).Str("policy", c.policy.String()).Dur("backoff", backoff).Msg("component failed, restarting")
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil
+		}
+
+		metrics.ComponentRestartsTotal.WithLabelValues(c.s.ID(), c.name, c.policy.String()).Inc()
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// run calls the component's function, recovering panics as errors.
+func (c *supervisedComponent) run(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().Str("component", c.name).Str("stack", string(debug.Stack())).Msg("recovered component panic")
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return c.fn(ctx)
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/loshz/platform/internal/config"
+)
+
+func TestSupervise(t *testing.T) {
+	// newService returns a service that allows 3 restarts a minute.
+	newService := func() *Service {
+		s := New("supervisor")
+		s.Config().Set(config.KeyServiceRestartIntensity, 3)
+		s.Config().Set(config.KeyServiceRestartPeriod, time.Minute)
+		s.Config().Set(config.KeyServiceRestartBackoff, time.Millisecond)
+		s.Config().Set(config.KeyServiceRestartBackoffMax, 5*time.Millisecond)
+		return s
+	}
+
+	tests := []struct {
+		name   string
+		policy RestartPolicy
+		err    error
+		panics bool
+		calls  int32
+		exits  bool
+	}{
+		{"TestPermanentExit", Permanent, nil, false, 4, true},
+		{"TestPermanentError", Permanent, errors.New("boom"), false, 4, true},
+		{"TestTransientExit", Transient, nil, false, 1, false},
+		{"TestTransientError", Transient, errors.New("boom"), false, 4, true},
+		{"TestTransientPanic", Transient, nil, true, 4, true},
+		{"TestTemporaryError", Temporary, errors.New("boom"), false, 1, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newService()
+
+			var calls atomic.Int32
+			s.Supervise("test", tc.policy, func(ctx context.Context) error {
+				calls.Add(1)
+				if tc.panics {
+					panic("boom")
+				}
+				return tc.err
+			})
+			require.NoError(t, s.components.start(context.Background()))
+
+			// Assert the service only exits once the restart intensity is
+			// exceeded, naming the component.
+			select {
+			case err := <-s.errCh:
+				assert.True(t, tc.exits, "unexpected error: %v", err)
+				assert.ErrorContains(t, err, "component 'test' failed, restart intensity exceeded")
+			case <-time.After(100 * time.Millisecond):
+				assert.False(t, tc.exits, "expected restart intensity to be exceeded")
+			}
+
+			require.NoError(t, s.components.stop(context.Background()))
+			assert.Equal(t, tc.calls, calls.Load())
+		})
+	}
+
+	t.Run("TestStop", func(t *testing.T) {
+		s := newService()
+		s.Go("blocks", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		require.NoError(t, s.components.start(context.Background()))
+
+		// Assert functions returning due to a stop aren't restarted or
+		// reported.
+		require.NoError(t, s.components.stop(context.Background()))
+		assert.Empty(t, s.errCh)
+
+		// Assert further errors don't block.
+		s.Error(errors.New("first"))
+		s.Error(errors.New("second"))
+	})
+}
+
+func TestSupervisorAllow(t *testing.T) {
+	var sv supervisor
+	now := time.Now()
+
+	// Assert restarts are limited within the period.
+	assert.True(t, sv.allow(now, 2, time.Minute))
+	assert.True(t, sv.allow(now.Add(30*time.Second), 2, time.Minute))
+	assert.False(t, sv.allow(now.Add(31*time.Second), 2, time.Minute))
+
+	// Assert restarts outside of the period are forgotten.
+	assert.True(t, sv.allow(now.Add(time.Minute+time.Second), 2, time.Minute))
+	assert.False(t, sv.allow(now.Add(time.Minute+2*time.Second), 2, time.Minute))
+}
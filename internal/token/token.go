@@ -0,0 +1,265 @@
+// Package token implements signed bearer tokens that identify platform
+// services independently of the transport, e.g. through TLS terminating proxies.
+//
+// Tokens are JWTs signed with the private key of a service's TLS cert, which is
+// included in the x5c header. Verifiers only need the CA pool already used for
+// mTLS, and identities are derived from the cert in the same way as mTLS peers.
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/loshz/platform/internal/authz"
+)
+
+// Supported signing algorithms.
+const (
+	AlgEdDSA = "EdDSA"
+	AlgES256 = "ES256"
+)
+
+// Errors returned when verifying tokens.
+var (
+	ErrMalformed       = errors.New("malformed token")
+	ErrInvalidSigner   = errors.New("invalid token signer")
+	ErrInvalidSig      = errors.New("invalid token signature")
+	ErrExpired         = errors.New("token expired")
+	ErrInvalidAudience = errors.New("invalid token audience")
+	ErrInvalidSubject  = errors.New("token subject does not match signer")
+)
+
+// header is the JOSE header of a token.
+type header struct {
+	Alg string   `json:"alg"`
+	Typ string   `json:"typ"`
+	X5C []string `json:"x5c"`
+}
+
+// Claims are the registered JWT claims carried by a token.
+type Claims struct {
+	// Identity of the service instance that signed the token.
+	Subject string `json:"sub"`
+
+	// Name of the API the token is intended for, e.g. proto.v1.EventService.
+	Audience string `json:"aud"`
+
+	// Unix timestamps of when the token was issued and expires.
+	IssuedAt int64 `json:"iat"`
+	Expiry   int64 `json:"exp"`
+}
+
+// Sign creates a token for an audience that expires after ttl, signed with the
+// key of a TLS cert. The subject is the identity of the cert.
+func Sign(cert *tls.Certificate, audience string, ttl time.Duration) (string, error) {
+	if cert == nil || cert.Leaf == nil {
+		return "", errors.New("error signing token: no certificate")
+	}
+
+	alg, err := algorithm(cert.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	h := header{Alg: alg, Typ: "JWT"}
+	for _, der := range cert.Certificate {
+		h.X5C = append(h.X5C, base64.StdEncoding.EncodeToString(der))
+	}
+
+	now := time.Now()
+	c := Claims{
+		Subject:  authz.IdentityFromCert(cert.Leaf).String(),
+		Audience: audience,
+		IssuedAt: now.Unix(),
+		Expiry:   now.Add(ttl).Unix(),
+	}
+
+	hdr, err := encodeSegment(h)
+	if err != nil {
+		return "", err
+	}
+	claims, err := encodeSegment(c)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := hdr + "." + claims
+	sig, err := sign(cert.PrivateKey.(crypto.Signer), alg, []byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("error signing token: %w", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// Verify verifies a token's signer against a CA pool, its signature, expiry and
+// audience, returning the identity of the signer.
+func Verify(token string, roots *x509.CertPool, audience string) (authz.Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return authz.Identity{}, ErrMalformed
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return authz.Identity{}, err
+	}
+	var c Claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return authz.Identity{}, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return authz.Identity{}, ErrMalformed
+	}
+
+	leaf, err := verifySigner(h.X5C, roots)
+	if err != nil {
+		return authz.Identity{}, err
+	}
+
+	if err := verify(leaf.PublicKey, h.Alg, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return authz.Identity{}, err
+	}
+
+	if time.Now().Unix() >= c.Expiry {
+		return authz.Identity{}, ErrExpired
+	}
+
+	if c.Audience != audience {
+		return authz.Identity{}, ErrInvalidAudience
+	}
+
+	id := authz.IdentityFromCert(leaf)
+	if c.Subject != id.String() {
+		return authz.Identity{}, ErrInvalidSubject
+	}
+
+	return id, nil
+}
+
+// verifySigner parses the signer's cert chain and verifies it against a CA pool.
+func verifySigner(x5c []string, roots *x509.CertPool) (*x509.Certificate, error) {
+	if len(x5c) == 0 {
+		return nil, ErrInvalidSigner
+	}
+
+	var chain []*x509.Certificate
+	for _, enc := range x5c {
+		der, err := base64.StdEncoding.DecodeString(enc)
+		if err != nil {
+			return nil, ErrMalformed
+		}
+
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		chain = append(chain, cert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSigner, err)
+	}
+
+	return chain[0], nil
+}
+
+// algorithm returns the signing algorithm for a private key.
+func algorithm(key crypto.PrivateKey) (string, error) {
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		return AlgEdDSA, nil
+	case *ecdsa.PrivateKey:
+		if k.Curve.Params().BitSize == 256 {
+			return AlgES256, nil
+		}
+	}
+
+	return "", fmt.Errorf("error signing token: unsupported key type %T", key)
+}
+
+// sign signs data using an algorithm. ECDSA signatures are encoded as the
+// fixed size concatenation of r and s, as required by JWS.
+func sign(key crypto.Signer, alg string, data []byte) ([]byte, error) {
+	if alg == AlgEdDSA {
+		return key.Sign(rand.Reader, data, crypto.Hash(0))
+	}
+
+	digest := sha256.Sum256(data)
+	r, s, err := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	return sig, nil
+}
+
+// verify verifies a signature of data using an algorithm and public key.
+func verify(key crypto.PublicKey, alg string, data, sig []byte) error {
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		if alg == AlgEdDSA && ed25519.Verify(k, data, sig) {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		if alg == AlgES256 && len(sig) == 64 {
+			digest := sha256.Sum256(data)
+			r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+			if ecdsa.Verify(k, digest[:], r, s) {
+				return nil
+			}
+		}
+	}
+
+	return ErrInvalidSig
+}
+
+// encodeSegment JSON encodes a value as a base64url token segment.
+func encodeSegment(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeSegment decodes a base64url token segment into a value.
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrMalformed
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrMalformed
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/loshz/platform/internal/authz"
+)
+
+// testCert creates a CA pool and a cert signed by it for a SPIFFE ID.
+func testCert(t *testing.T, key crypto.Signer, spiffeID string) (*tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	uri, err := url.Parse(spiffeID)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		URIs:         []*url.URL{uri},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, key.Public(), caKey)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+func TestSignVerify(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	id := authz.Identity{Service: "trafficd", Instance: "trafficd-00000000-0000-0000-0000-000000000001"}
+	spiffeID := "spiffe://platform/" + id.Service + "/" + id.Instance
+
+	for name, key := range map[string]crypto.Signer{AlgEdDSA: edKey, AlgES256: ecKey} {
+		t.Run(name, func(t *testing.T) {
+			cert, pool := testCert(t, key, spiffeID)
+
+			tok, err := Sign(cert, "proto.v1.EventService", time.Minute)
+			require.NoError(t, err)
+
+			// Assert the signer's identity is returned.
+			got, err := Verify(tok, pool, "proto.v1.EventService")
+			require.NoError(t, err)
+			assert.Equal(t, id, got)
+		})
+	}
+
+	cert, pool := testCert(t, ecKey, spiffeID)
+	tok, err := Sign(cert, "proto.v1.EventService", time.Minute)
+	require.NoError(t, err)
+
+	t.Run("TestInvalidAudience", func(t *testing.T) {
+		_, err := Verify(tok, pool, "proto.v1.DiscoveryService")
+		assert.ErrorIs(t, err, ErrInvalidAudience)
+	})
+
+	t.Run("TestExpired", func(t *testing.T) {
+		tok, err := Sign(cert, "proto.v1.EventService", -time.Second)
+		require.NoError(t, err)
+
+		_, err = Verify(tok, pool, "proto.v1.EventService")
+		assert.ErrorIs(t, err, ErrExpired)
+	})
+
+	t.Run("TestUntrustedSigner", func(t *testing.T) {
+		_, other := testCert(t, ecKey, spiffeID)
+
+		_, err := Verify(tok, other, "proto.v1.EventService")
+		assert.ErrorIs(t, err, ErrInvalidSigner)
+	})
+
+	t.Run("TestTampered", func(t *testing.T) {
+		// Replace the claims with those of a token for another audience.
+		other, err := Sign(cert, "proto.v1.DiscoveryService", time.Minute)
+		require.NoError(t, err)
+		parts, otherParts := strings.Split(tok, "."), strings.Split(other, ".")
+		parts[1] = otherParts[1]
+
+		_, err = Verify(strings.Join(parts, "."), pool, "proto.v1.DiscoveryService")
+		assert.ErrorIs(t, err, ErrInvalidSig)
+	})
+
+	t.Run("TestMalformed", func(t *testing.T) {
+		_, err := Verify("invalid", pool, "proto.v1.EventService")
+		assert.ErrorIs(t, err, ErrMalformed)
+	})
+}
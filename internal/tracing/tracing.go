@@ -0,0 +1,125 @@
+// Package tracing configures distributed tracing using OpenTelemetry.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Supported span exporters.
+const (
+	// ExporterNone disables exporting, but trace context is still propagated.
+	ExporterNone = "none"
+	// ExporterOTLP exports spans to an OTLP collector over gRPC.
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans as JSON to stdout.
+	ExporterStdout = "stdout"
+	// ExporterFile writes spans as JSON to a file.
+	ExporterFile = "file"
+)
+
+// instrumentationName is the name of the tracer used by platform packages.
+const instrumentationName = "github.com/loshz/platform"
+
+// Exporters represents all supported span exporters.
+var Exporters = []string{ExporterNone, ExporterOTLP, ExporterStdout, ExporterFile}
+
+// Config represents the configuration of a tracer provider.
+type Config struct {
+	// One of Exporters.
+	Exporter string
+
+	// Address of the OTLP collector and whether to connect without TLS.
+	Endpoint string
+	Insecure bool
+
+	// Path of the file spans are written to.
+	File string
+
+	// Fraction of new traces that are sampled. Traces started by a sampled
+	// parent are always sampled.
+	SampleRatio float64
+}
+
+// ShutdownFunc flushes any remaining spans and stops the exporter.
+type ShutdownFunc func(context.Context) error
+
+// Start configures the global tracer provider and W3C trace context propagator
+// for a service.
+func Start(ctx context.Context, name, id, version string, conf Config) (ShutdownFunc, error) {
+	// Always propagate trace context, even if spans aren't exported.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var (
+		exporter sdktrace.SpanExporter
+		closer   func() error
+		err      error
+	)
+
+	switch conf.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(conf.Endpoint)}
+		if conf.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New()
+	case ExporterFile:
+		var f *os.File
+		f, err = os.OpenFile(conf.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("error opening trace file: %w", err)
+		}
+		closer = f.Close
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter '%s'", conf.Exporter)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating trace exporter: %w", err)
+	}
+
+	res := resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(name),
+		semconv.ServiceInstanceID(id),
+		semconv.ServiceVersion(version),
+	)
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer())
+		}
+
+		return err
+	}, nil
+}
+
+// Tracer returns the tracer used by platform packages.
+func Tracer() trace.Tracer { return otel.Tracer(instrumentationName) }
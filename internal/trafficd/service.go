@@ -0,0 +1,88 @@
+// Package trafficd implements the traffic service, which generates traffic by
+// sending events to other platform services.
+package trafficd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	apiv1 "github.com/loshz/platform/internal/api/v1"
+	"github.com/loshz/platform/internal/config"
+	"github.com/loshz/platform/internal/credentials"
+	pgrpc "github.com/loshz/platform/internal/grpc"
+	plog "github.com/loshz/platform/internal/log"
+	"github.com/loshz/platform/internal/service"
+)
+
+// Name of the traffic service.
+const Name = "trafficd"
+
+// Configure loads the credentials, config and dependencies required to run the
+// service. It must be called after config sources are loaded.
+func Configure(s *service.Service) {
+	s.LoadCredentials(credentials.GrpcClient, credentials.GrpcToken)
+	s.LoadTrafficConfig()
+
+	// Wait for eventd to be registered for discovery before running.
+	s.DependsOn("eventd", 1)
+}
+
+// Run sends events to eventd in the background.
+func Run(ctx context.Context, s *service.Service) error {
+	t := time.NewTicker(s.Config().Duration(config.KeyTrafficEventInterval))
+
+	// Apply changes to the event interval while running.
+	s.Config().Watch(config.KeyTrafficEventInterval, func(key string) {
+		t.Reset(s.Config().Duration(key))
+	})
+
+	// Send events in the background, restarting with a fresh eventd lookup on
+	// failure.
+	s.Go("traffic", func(ctx context.Context) error {
+		return sendEvents(ctx, s, t.C)
+	})
+
+	return nil
+}
+
+// sendEvents makes a request to an eventd instance on every tick until the
+// context is done.
+func sendEvents(ctx context.Context, s *service.Service, tick <-chan time.Time) error {
+	// Get eventd address.
+	svcs, err := s.Discovery().Lookup(ctx, "eventd")
+	if err != nil {
+		return fmt.Errorf("error getting eventd service details from discovery: %w", err)
+	}
+	if len(svcs) == 0 {
+		return errors.New("error: no eventd services registered for discovery")
+	}
+
+	eventd := fmt.Sprintf("%s:%d", svcs[0].Address, svcs[0].GrpcPort)
+	// Events are not idempotent, so calls are never repeated.
+	conn, err := s.DialGrpc(ctx, eventd, nil)
+	if err != nil {
+		return fmt.Errorf("error dialing eventd: %w", err)
+	}
+	defer conn.Close()
+	client := apiv1.NewEventServiceClient(conn)
+
+	for {
+		select {
+		case <-tick:
+			// Correlate each request with the eventd logs it causes.
+			reqCtx := plog.WithRequestID(ctx, pgrpc.NewRequestID())
+
+			res, err := client.Event(reqCtx, &apiv1.EventRequest{Hostname: "blah"})
+			if err != nil {
+				plog.Ctx(reqCtx).Error().Err(err).Msg("error making request to eventd")
+				continue
+			}
+
+			plog.Ctx(reqCtx).Info().Msgf("eventd response: %s", res.Uuid)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package uuid
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -22,6 +23,26 @@ func New(name string) UUID {
 	return UUID{strings.ToLower(name), guuid.New()}
 }
 
+// ErrInvalidUUID is returned when parsing a string that isn't a service UUID.
+var ErrInvalidUUID = errors.New("invalid service uuid")
+
+// Parse parses a service UUID including its name prefix.
+// E.g., service-xxxx-xxxx
+func Parse(s string) (UUID, error) {
+	// The name prefix is separated from the id by a single dash.
+	i := len(s) - 37
+	if i < 1 || s[i] != '-' {
+		return UUID{}, ErrInvalidUUID
+	}
+
+	id, err := guuid.Parse(s[i+1:])
+	if err != nil {
+		return UUID{}, ErrInvalidUUID
+	}
+
+	return UUID{strings.ToLower(s[:i]), id}, nil
+}
+
 func (u UUID) ID() string     { return u.id.String() }
 func (u UUID) Name() string   { return u.name }
 func (u UUID) String() string { return fmt.Sprintf("%s-%s", u.name, u.id) }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -13,3 +14,18 @@ func TestUUID(t *testing.T) {
 	expected := fmt.Sprintf("%s-%s", uuid.Name(), uuid.ID())
 	require.Equal(t, expected, uuid.String())
 }
+
+func TestParse(t *testing.T) {
+	uuid := New("test-service")
+
+	// Assert a valid uuid is parsed.
+	parsed, err := Parse(uuid.String())
+	require.NoError(t, err)
+	assert.Equal(t, uuid, parsed)
+
+	// Assert invalid uuids return an error.
+	for _, s := range []string{"", "test-service", uuid.ID(), "-" + uuid.ID(), "test-service-invalid"} {
+		_, err := Parse(s)
+		assert.ErrorIs(t, err, ErrInvalidUUID, s)
+	}
+}